@@ -12,8 +12,10 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"os"
+	"os/signal"
 	"regexp"
 	"strings"
+	"syscall"
 
 	//"path/filepath" // <-- 新增：用于获取文件扩展名
 	"strconv" // <-- 新增：用于将字符串转换为数字 (解析 UserID)
@@ -30,11 +32,18 @@ import (
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 
+	"github.com/shuind/language-learner/backend/internal/asr"
+	"github.com/shuind/language-learner/backend/internal/collab"
 	"github.com/shuind/language-learner/backend/internal/handler"
+	"github.com/shuind/language-learner/backend/internal/lang"
 	"github.com/shuind/language-learner/backend/internal/middleware"
 	"github.com/shuind/language-learner/backend/internal/model"
 	"github.com/shuind/language-learner/backend/internal/mq"
+	"github.com/shuind/language-learner/backend/internal/nodecontent"
+	"github.com/shuind/language-learner/backend/internal/nodetree"
 	"github.com/shuind/language-learner/backend/internal/scheduler"
+	"github.com/shuind/language-learner/backend/internal/search"
+	"github.com/shuind/language-learner/backend/internal/task"
 	"github.com/shuind/language-learner/backend/internal/utils"
 )
 
@@ -47,6 +56,7 @@ var (
 	thinkTagRegex = regexp.MustCompile(`(?s)<think>.*?</think>`)
 )
 var mqManager *mq.RabbitMQManager // 使用新的管理器
+var collabHub *collab.Hub         // 圈子节点协同编辑会话
 
 // --- 通用 DTO ---
 type AuthorResponse struct {
@@ -62,8 +72,18 @@ type RecordingWithLikeStatus struct {
 }
 
 // --- Comment 相关 DTO ---
+// CreateCommentInput 的 StartMs/EndMs 可以把评论定位到录音中的一段
 type CreateCommentInput struct {
-	Content string `json:"content" binding:"required,min=1"`
+	Content  string `json:"content" binding:"required,min=1"`
+	StartMs  *int   `json:"start_ms"`
+	EndMs    *int   `json:"end_ms"`
+	Category string `json:"category"`
+}
+
+// CreateDomainNodeCommentInput 可以指向节点中的一条共享标注，让讨论落到具体的文字上
+type CreateDomainNodeCommentInput struct {
+	Content      string `json:"content" binding:"required,min=1"`
+	AnnotationID *uint  `json:"annotation_id"`
 }
 
 type CommentResponse struct {
@@ -234,33 +254,6 @@ func AIChatHandler(c *gin.Context) {
 	})
 }
 
-func DomainOwnerMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		userID, _ := c.Get("userID")
-		domainIDStr := c.Param("id") // 有些路由是 :id，有些是 :domainID，需要统一或兼容
-		if domainIDStr == "" {
-			domainIDStr = c.Param("domainId")
-		}
-
-		domainID, err := strconv.ParseUint(domainIDStr, 10, 32)
-		if err != nil {
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid domain ID"})
-			return
-		}
-
-		var domain model.Domain
-		// 核心检查：圈子是否存在，且 owner_id 是当前登录用户
-		if err := DB.Where("id = ? AND owner_id = ?", domainID, userID).First(&domain).Error; err != nil {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Permission denied: you are not the owner of this domain"})
-			return
-		}
-
-		// 将圈子信息存入 context，方便后续使用
-		c.Set("domain", domain)
-		c.Next()
-	}
-}
-
 // 初始化数据库连接
 func initDB() {
 
@@ -293,12 +286,19 @@ func initDB() {
 	}
 
 	// 自动迁移模型，这部分保持不变
-	err = DB.AutoMigrate(&model.TaskItem{}, &model.User{}, &model.Text{}, &model.Recording{}, &model.Node{}, &model.Domain{}, &model.DomainMember{}, &model.DomainNode{}, &model.Like{}, &model.Follower{}, &model.Post{}, &model.Reply{}, &model.DomainNodeComment{}, &model.PostLike{}, &model.ReplyLike{}, &model.Message{}, &model.QuestionFollow{}, &model.Comment{})
+	err = DB.AutoMigrate(&model.TaskItem{}, &model.User{}, &model.Text{}, &model.Recording{}, &model.Node{}, &model.Domain{}, &model.DomainMember{}, &model.DomainNode{}, &model.Like{}, &model.Follower{}, &model.Post{}, &model.Reply{}, &model.DomainNodeComment{}, &model.PostLike{}, &model.ReplyLike{}, &model.Message{}, &model.QuestionFollow{}, &model.Comment{}, &model.NodeRevision{}, &model.SearchDocument{}, &model.Tag{}, &model.DomainTag{}, &model.SavedFilter{}, &model.NodeReview{}, &model.ShareLink{}, &model.Annotation{}, &model.SegmentTranslation{}, &model.WordBookEntry{}, &model.WordBookSource{}, &model.ReferenceAudio{}, &model.PracticeSession{}, &model.PracticeRecording{}, &model.DomainBan{}, &model.DomainInvitation{}, &model.DomainJoinRequest{}, &model.Assignment{}, &model.AssignmentAssignee{}, &model.Publication{}, &model.PublicationNode{}, &model.NodeFork{}, &model.Rubric{}, &model.RubricCriterion{}, &model.PeerReviewRound{}, &model.PeerReviewAssignment{}, &model.PeerReviewScore{}, &model.DomainActivity{}, &model.DomainAnnouncement{})
 	if err != nil {
 		log.Fatalf("Failed to auto migrate: %v", err)
 	}
 	seedTexts(DB)
 	seedNodes(DB)
+
+	// 为搜索功能上线前的已有数据补建索引
+	go func() {
+		if err := search.Backfill(DB); err != nil {
+			log.Printf("Search index backfill failed: %v", err)
+		}
+	}()
 }
 func seedNodes(db *gorm.DB) {
 	// 检查用户1是否存在，如果不存在则不植入数据
@@ -315,7 +315,7 @@ func seedNodes(db *gorm.DB) {
 		nodes := []model.Node{
 			{UserID: 1, NodeType: "folder", Title: "我的工作区"},
 			{UserID: 1, NodeType: "folder", Title: "个人笔记"},
-			{UserID: 1, NodeType: "text", Title: "快速便签", Content: "今天天气不错！"},
+			{UserID: 1, NodeType: "text", Title: "快速便签", Content: "今天天气不错！", Language: "zh"},
 		}
 		if err := db.Create(&nodes).Error; err != nil {
 			log.Fatalf("Could not seed nodes: %v", err)
@@ -326,25 +326,42 @@ func seedNodes(db *gorm.DB) {
 
 type CreateNodeInput struct {
 	ParentID *uint  `json:"parent_id"` // 使用指针以接受 null
-	NodeType string `json:"node_type" binding:"required,oneof=folder text"`
+	NodeType string `json:"node_type" binding:"required,oneof=folder text card qa"`
 	Title    string `json:"title" binding:"required,min=1,max=255"`
 	Content  string `json:"content"`
+	// Language 为空时沿用父文件夹的语言
+	Language string `json:"language"`
+	// card 的 front/back 与 qa 的 prompt/answer，也可以直接以 JSON 对象传入 content
+	nodecontent.Patch
 }
 
 func ListNodesHandler(c *gin.Context) {
 	userID, _ := c.Get("userID")
 	parentID := c.Query("parent_id") // 获取查询参数
 
-	query := DB.Where("user_id = ?", userID)
+	// 标签/复习筛选：tags=1,2&tag_mode=and|or&due=true
+	filter, err := handler.ParseNodeFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := DB.Where("user_id = ?", userID).Preload("Tags")
 
-	if parentID == "" || parentID == "null" {
+	if !filter.Empty() {
+		// 带筛选条件时，不指定 parent_id 表示在全部节点中筛选
+		query = query.Scopes(filter.Scope("id", userID.(uint)))
+		if parentID != "" && parentID != "null" {
+			query = query.Where("parent_id = ?", parentID)
+		}
+	} else if parentID == "" || parentID == "null" {
 		query = query.Where("parent_id IS NULL")
 	} else {
 		query = query.Where("parent_id = ?", parentID)
 	}
 
 	var nodes []model.Node
-	if err := query.Order("node_type DESC, title ASC").Find(&nodes).Error; err != nil { // 文件夹排前面
+	if err := query.Order(nodetree.Nodes.Order).Find(&nodes).Error; err != nil { // 按手动排序，未排序的旧数据文件夹排前面
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not retrieve nodes"})
 		return
 	}
@@ -369,6 +386,12 @@ func CreateNodeHandler(c *gin.Context) {
 		return
 	}
 
+	language, err := lang.Normalize(input.Language)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// 3. 安全验证：如果指定了 parent_id，必须验证该父节点存在且属于当前用户
 	if input.ParentID != nil {
 		var parentNode model.Node
@@ -386,6 +409,9 @@ func CreateNodeHandler(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot create a node under a text file"})
 			return
 		}
+		if language == "" {
+			language = parentNode.Language
+		}
 	}
 
 	// 4. 创建节点实例
@@ -395,15 +421,35 @@ func CreateNodeHandler(c *gin.Context) {
 		NodeType: input.NodeType,
 		Title:    input.Title,
 		Content:  input.Content,
+		Language: language,
 	}
 
-	// 如果是 folder，强制清空 content
-	if newNode.NodeType == "folder" {
-		newNode.Content = ""
+	// 按节点类型整理 content：folder 强制清空，card / qa 编码为结构化内容
+	content, err := nodecontent.Build(newNode.NodeType, input.Content, input.Patch)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
+	newNode.Content = content
 
-	// 5. 保存到数据库
-	if err := DB.Create(&newNode).Error; err != nil {
+	// 5. 保存到数据库，并记录第一个修订版本
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		// 新节点追加到同级末尾
+		pos, err := nodetree.NextPosition(tx, nodetree.Nodes, newNode.UserID, newNode.ParentID)
+		if err != nil {
+			return err
+		}
+		newNode.Position = pos
+		if err := tx.Create(&newNode).Error; err != nil {
+			return err
+		}
+		if err := search.IndexNode(tx, &newNode); err != nil {
+			return err
+		}
+		_, err = handler.RecordRevision(tx, model.RevisionKindNode, newNode.ID, newNode.UserID, newNode.Title, newNode.Content)
+		return err
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create node"})
 		return
 	}
@@ -413,8 +459,11 @@ func CreateNodeHandler(c *gin.Context) {
 }
 
 type UpdateNodeInput struct {
-	Title   *string `json:"title"`
-	Content *string `json:"content"`
+	Title    *string `json:"title"`
+	Content  *string `json:"content"`
+	Language *string `json:"language"`
+	// card / qa 节点可以只更新其中某个字段
+	nodecontent.Patch
 }
 
 // UpdateNodeHandler 更新一个节点 (标题或内容)
@@ -436,6 +485,11 @@ func UpdateNodeHandler(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found or permission denied"})
 		return
 	}
+	// If-Match 与当前版本不一致时拒绝覆盖，返回服务器端的最新内容
+	if !handler.IfMatch(c, node.Version) {
+		handler.RespondVersionConflict(c, node.Version, node)
+		return
+	}
 
 	// 4. 应用更新
 	// 检查 title 是否被传入
@@ -448,58 +502,104 @@ func UpdateNodeHandler(c *gin.Context) {
 		node.Title = *input.Title
 	}
 
-	// 检查 content 是否被传入
-	if input.Content != nil {
+	// 检查 language 是否被传入，传入空字符串表示清除
+	if input.Language != nil {
+		language, err := lang.Normalize(*input.Language)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		node.Language = language
+	}
+
+	// 检查 content 或 card / qa 的字段是否被传入
+	if input.Content != nil || !input.Patch.Empty() {
 		// 业务规则：文件夹不能有内容
 		if node.NodeType == "folder" {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot set content for a folder"})
 			return
 		}
-		node.Content = *input.Content
+		base := node.Content
+		if input.Content != nil {
+			base = *input.Content
+		}
+		content, err := nodecontent.Build(node.NodeType, base, input.Patch)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		node.Content = content
 	}
 
-	// 5. 保存更新
-	if err := DB.Save(&node).Error; err != nil {
+	// 5. 保存更新，同时追加一条修订记录（内容未变化时不会产生新版本）
+	// 只有读取之后版本未被他人修改时才会写入
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{"title": node.Title, "content": node.Content, "language": node.Language}
+		if err := handler.UpdateVersioned(tx, &model.Node{}, node.ID, node.Version, updates); err != nil {
+			return err
+		}
+		if err := tx.First(&node, node.ID).Error; err != nil {
+			return err
+		}
+		if err := search.IndexNode(tx, &node); err != nil {
+			return err
+		}
+		if err := handler.InvalidateReferenceAudio(tx, model.RevisionKindNode, node.ID, node.NodeType, node.Content, node.Language); err != nil {
+			return err
+		}
+		if err := handler.RealignTranslations(tx, model.RevisionKindNode, node.ID, node.NodeType, node.Content); err != nil {
+			return err
+		}
+		_, err := handler.RecordRevision(tx, model.RevisionKindNode, node.ID, userID.(uint), node.Title, node.Content)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, handler.ErrVersionConflict) {
+			DB.First(&node, node.ID)
+			handler.RespondVersionConflict(c, node.Version, node)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update node"})
 		return
 	}
 
 	// 6. 返回更新后的节点
+	handler.SetVersionETag(c, node.Version)
 	c.JSON(http.StatusOK, node)
 }
 
 // DeleteNodeHandler 删除一个节点
+// 删除是软删除：节点连同整棵子树和其下的录音一起进入回收站，可在 /trash 中恢复
 func DeleteNodeHandler(c *gin.Context) {
 	// 1. 获取用户ID和URL中的节点ID
 	userID, _ := c.Get("userID")
 	nodeID := c.Param("id")
 
-	// 2. 执行带权限检查的删除
-	// GORM 的 Where().Delete() 会返回一个结果对象
-	// 我们不需要先 Find() 再 Delete()，可以一步到位
-	result := DB.Where("id = ? AND user_id = ?", nodeID, userID).Delete(&model.Node{})
-
-	// 3. 检查是否有错误
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete node"})
+	// 2. 权限检查：节点必须存在且属于当前用户
+	var node model.Node
+	if err := DB.Where("id = ? AND user_id = ?", nodeID, userID).First(&node).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found or permission denied"})
 		return
 	}
 
-	// 4. 检查是否真的删除了记录
-	// 如果 RowsAffected == 0，说明没有找到匹配的记录（即节点不存在或不属于该用户）
-	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found or permission denied"})
+	// 3. 在一个事务中递归软删除整棵子树及录音
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		_, err := nodetree.SoftDeleteSubtree(tx, nodetree.Nodes, node.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete node"})
 		return
 	}
 
-	// 5. 返回成功响应
+	// 4. 返回成功响应
 	// HTTP 规范中，成功的 DELETE 操作通常返回 204 No Content
 	c.Status(http.StatusNoContent)
 }
 
 // SearchNodesHandler 搜索用户的节点
 func SearchNodesHandler(c *gin.Context) {
-	userID, _ := c.Get("userID")
+	userID := c.MustGet("userID").(uint)
 	query := c.Query("q") // 获取搜索关键词，例如: /nodes/search?q=开发计划
 
 	if strings.TrimSpace(query) == "" {
@@ -507,23 +607,41 @@ func SearchNodesHandler(c *gin.Context) {
 		return
 	}
 
-	var results []model.Node
-
-	// 使用 to_tsquery 进行查询，它会将用户输入也转换为查询向量
-	// @@ 是 "matches" 操作符
-	// 我们还可以在这里添加排序，比如按更新时间
-	err := DB.Where("user_id = ?", userID).
-		Where("tsv @@ to_tsquery('simple', ?)", query).
-		Order("updated_at DESC").
-		Find(&results).Error
-
+	// 复用统一搜索，只查个人节点；保持原来返回节点列表的格式
+	hits, _, err := search.Search(DB, search.Query{
+		UserID: userID,
+		Text:   query,
+		Types:  []string{model.SearchSourceNode},
+		Page:   1,
+		Limit:  50,
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to perform search"})
 		return
 	}
 
-	if results == nil {
-		results = make([]model.Node, 0)
+	ids := make([]uint, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	var nodes []model.Node
+	if len(ids) > 0 {
+		if err := DB.Where("id IN ? AND user_id = ?", ids, userID).Find(&nodes).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to perform search"})
+			return
+		}
+	}
+
+	// 按相关度排序返回
+	byID := make(map[uint]model.Node, len(nodes))
+	for _, n := range nodes {
+		byID[n.ID] = n
+	}
+	results := make([]model.Node, 0, len(nodes))
+	for _, id := range ids {
+		if n, ok := byID[id]; ok {
+			results = append(results, n)
+		}
 	}
 
 	c.JSON(http.StatusOK, results)
@@ -533,6 +651,14 @@ type MoveNodeInput struct {
 	NewParentID *uint `json:"new_parent_id"`
 }
 
+// sameParent 判断两个可为空的父节点 ID 是否指向同一位置
+func sameParent(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
 // MoveNodeHandler 移动一个节点
 func MoveNodeHandler(c *gin.Context) {
 	userID, _ := c.Get("userID")
@@ -568,12 +694,34 @@ func MoveNodeHandler(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Target must be a folder"})
 			return
 		}
-		// TODO: 还需要验证不能将父节点移动到其子节点下（防止循环）
+		// 验证不能将父节点移动到其子节点下（防止循环）
+		cyclic, err := nodetree.IsSelfOrDescendant(DB, nodetree.Nodes, nodeToMove.ID, targetParent.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate move"})
+			return
+		}
+		if cyclic {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot move a parent node into its own descendant"})
+			return
+		}
 	}
 
-	// 更新 ParentID
+	// 更新 ParentID，移入新文件夹的节点排到末尾
+	if !sameParent(nodeToMove.ParentID, input.NewParentID) {
+		pos, err := nodetree.NextPosition(DB, nodetree.Nodes, nodeToMove.UserID, input.NewParentID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move node"})
+			return
+		}
+		nodeToMove.Position = pos
+	}
 	nodeToMove.ParentID = input.NewParentID
-	DB.Save(&nodeToMove)
+	// 只写位置相关的列，不覆盖其他请求对标题、内容与版本号的并发修改
+	if err := DB.Model(&model.Node{}).Where("id = ?", nodeToMove.ID).
+		Updates(map[string]interface{}{"parent_id": nodeToMove.ParentID, "position": nodeToMove.Position}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move node"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Node moved successfully"})
 }
@@ -625,9 +773,9 @@ func seedTexts(db *gorm.DB) {
 	if count == 0 {
 		log.Println("No texts found, seeding database...")
 		texts := []model.Text{
-			{Title: "The North Wind and the Sun", Content: "The North Wind and the Sun were disputing which was the stronger, when a traveler came along wrapped in a warm cloak.", Difficulty: 1},
-			{Title: "A Fox and a Crane", Content: "A Fox invited a Crane to supper and provided nothing for his entertainment but some soup in a very shallow dish.", Difficulty: 2},
-			{Title: "The Ant and the Grasshopper", Content: "In a field one summer's day a Grasshopper was hopping about, chirping and singing to its heart's content.", Difficulty: 1},
+			{Title: "The North Wind and the Sun", Content: "The North Wind and the Sun were disputing which was the stronger, when a traveler came along wrapped in a warm cloak.", Difficulty: 1, Language: "en"},
+			{Title: "A Fox and a Crane", Content: "A Fox invited a Crane to supper and provided nothing for his entertainment but some soup in a very shallow dish.", Difficulty: 2, Language: "en"},
+			{Title: "The Ant and the Grasshopper", Content: "In a field one summer's day a Grasshopper was hopping about, chirping and singing to its heart's content.", Difficulty: 1, Language: "en"},
 		}
 		if err := db.Create(&texts).Error; err != nil {
 			log.Fatalf("Could not seed texts: %v", err)
@@ -736,10 +884,11 @@ func ListTextsHandler(c *gin.Context) {
 		ID         uint   `json:"id"`
 		Title      string `json:"title"`
 		Difficulty int    `json:"difficulty"`
+		Language   string `json:"language"`
 	}
 
 	// 只选择需要的字段
-	if err := DB.Model(&model.Text{}).Select("id, title, difficulty").Find(&texts).Error; err != nil {
+	if err := DB.Model(&model.Text{}).Select("id, title, difficulty, language").Find(&texts).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not retrieve texts"})
 		return
 	}
@@ -816,8 +965,23 @@ func UploadHandler(c *gin.Context) {
 		return
 	}
 
+	// 录音时长由客户端上报，可选，用于统计练习时间；写入前按文件大小截断
+	var durationMs int
+	if s := c.PostForm("duration_ms"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "duration_ms must be a non-negative integer"})
+			return
+		}
+		durationMs = v
+	}
+
 	// 使用指针类型，因为它们在模型中是可选的
 	var textID, nodeID, domainNodeID *uint
+	// 录音固定到朗读时的内容版本
+	var revision *model.NodeRevision
+	// 通过分享链接录音时记录来源链接
+	var shareLinkID *uint
 
 	// --- 情况A: 上传到公共文本 (text_id) ---
 	if textIDStr != "" {
@@ -838,16 +1002,39 @@ func UploadHandler(c *gin.Context) {
 		val := uint(id)
 
 		// 【安全检查】验证个人节点的所有权和类型
+		// 非本人节点只能通过允许录音的分享链接录音
 		var node model.Node
-		if err := DB.Where("id = ? AND user_id = ?", val, userID).First(&node).Error; err != nil {
+		if token := c.PostForm("share_token"); token != "" {
+			link, err := handler.ResolveShareLink(DB, token, c.PostForm("share_password"))
+			if err != nil {
+				handler.RespondShareError(c, err)
+				return
+			}
+			covered, err := handler.ShareCovers(DB, link, val)
+			if err != nil || !covered || !link.AllowRecording {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Recording is not allowed through this share link"})
+				return
+			}
+			if err := DB.Where("id = ? AND user_id = ?", val, link.UserID).First(&node).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Shared node not found"})
+				return
+			}
+			shareLinkID = &link.ID
+		} else if err := DB.Where("id = ? AND user_id = ?", val, userID).First(&node).Error; err != nil {
 			c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied or personal node not found"})
 			return
 		}
-		if node.NodeType != "text" {
+		if !nodecontent.Recitable(node.NodeType) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot record for a folder node"})
 			return
 		}
+		rev, err := handler.ResolveRevision(DB, model.RevisionKindNode, node.ID, node.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve node revision"})
+			return
+		}
 		nodeID = &val
+		revision = rev
 	} else if domainNodeIDStr != "" {
 		id, err := strconv.ParseUint(domainNodeIDStr, 10, 64)
 		if err != nil {
@@ -862,7 +1049,7 @@ func UploadHandler(c *gin.Context) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Domain node not found"})
 			return
 		}
-		if domainNode.NodeType != "text" {
+		if !nodecontent.Recitable(domainNode.NodeType) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot record for a folder node in a domain"})
 			return
 		}
@@ -871,7 +1058,19 @@ func UploadHandler(c *gin.Context) {
 			c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this domain"})
 			return
 		}
+		// 老节点可能还没有修订记录，此时以圈主的名义补建基线版本
+		var domain model.Domain
+		if err := DB.Select("id", "owner_id").First(&domain, domainNode.DomainID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Domain not found"})
+			return
+		}
+		rev, err := handler.ResolveRevision(DB, model.RevisionKindDomainNode, domainNode.ID, domain.OwnerID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve node revision"})
+			return
+		}
 		domainNodeID = &val
+		revision = rev
 	}
 
 	// 3. 获取音频文件 (逻辑不变)
@@ -894,9 +1093,14 @@ func UploadHandler(c *gin.Context) {
 		TextID:       textID,
 		NodeID:       nodeID,
 		DomainNodeID: domainNodeID, // 确保模型中有这个字段
+		ShareLinkID:  shareLinkID,
+		DurationMs:   handler.ClampDurationMs(durationMs, int64(len(fileBytes))),
 		Status:       "processing",
 		// Title 可以在转码后由 worker 根据关联的文本标题填充
 	}
+	if revision != nil {
+		newRecording.RevisionID = &revision.ID
+	}
 	if err := DB.Create(&newRecording).Error; err != nil {
 		log.Printf("Database create failed: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not save recording metadata"})
@@ -1103,12 +1307,19 @@ func CreateDomainHandler(c *gin.Context) {
 	if err := c.ShouldBindJSON(&input); err != nil { /* ... */
 	}
 
+	// 加入码须不可猜测，使用 crypto/rand 生成
+	joinCode, err := utils.GenerateSecureCode(8)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate join code"})
+		return
+	}
+
 	// 创建圈子
 	newDomain := model.Domain{
 		OwnerID:     userID.(uint),
 		Name:        input.Name,
 		Description: input.Description,
-		JoinCode:    utils.GenerateRandomString(8), // 生成唯一邀请码
+		JoinCode:    joinCode, // 生成唯一邀请码，可通过 POST /domains/:domainId/join-code 轮换
 	}
 	// TODO: 需要循环检查确保邀请码唯一性，虽然碰撞概率极低
 
@@ -1140,6 +1351,7 @@ func CreateDomainHandler(c *gin.Context) {
 type JoinDomainInput struct {
 	DomainID uint   `json:"domain_id" binding:"required"`
 	JoinCode string `json:"join_code" binding:"required"`
+	Message  string `json:"message" binding:"max=500"` // 圈子需要审核时附带的申请说明
 }
 
 // === Join Domain Handler ===
@@ -1160,22 +1372,24 @@ func JoinDomainHandler(c *gin.Context) {
 		return
 	}
 
-	// 检查用户是否已是成员 (逻辑不变)
-	var existingMember model.DomainMember
-	if err := DB.Where("domain_id = ? AND user_id = ?", domain.ID, userID).First(&existingMember).Error; err == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "你已经是该圈子成员"})
+	// 被封禁、已是成员时拒绝；圈子开启审核时提交申请，等待圈主或管理员处理
+	var result string
+	var request *model.DomainJoinRequest
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		result, request, err = handler.JoinDomain(tx, &domain, userID.(uint), nil, input.Message)
+		return err
+	})
+	if err != nil {
+		handler.RespondJoinError(c, err)
 		return
 	}
-
-	// 添加新成员 (逻辑不变)
-	newMember := model.DomainMember{
-		DomainID: domain.ID,
-		UserID:   userID.(uint),
-		Role:     "member",
+	if result == handler.JoinResultPending {
+		c.JSON(http.StatusAccepted, gin.H{"message": "已提交加入申请，等待审核", "result": result, "request": request, "domain": domain})
+		return
 	}
-	DB.Create(&newMember)
 
-	c.JSON(http.StatusOK, gin.H{"message": "成功加入圈子", "domain": domain})
+	c.JSON(http.StatusOK, gin.H{"message": "成功加入圈子", "result": result, "domain": domain})
 }
 
 // === List My Domains Handler ===
@@ -1193,25 +1407,23 @@ func ListMyDomainsHandler(c *gin.Context) {
 
 type PublishNodeInput struct {
 	SourceNodeID uint `json:"source_node_id" binding:"required"`
-	// MVP 版本先默认发布到圈子根目录，未来可扩展 target_parent_id
+	// TargetParentID 是圈子中的目标文件夹，为空表示发布到圈子根目录
+	TargetParentID *uint `json:"target_parent_id"`
 }
 
 // PublishNodeToDomainHandler 是 API 的入口
 func PublishNodeToDomainHandler(c *gin.Context) {
 	userID, _ := c.Get("userID")
-	domainID, _ := strconv.ParseUint(c.Param("domainId"), 10, 32)
+	domainID := c.MustGet("domain").(model.Domain).ID
 
 	var input PublishNodeInput
-	if err := c.ShouldBindJSON(&input); err != nil { /* ... */
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
 	// --- 安全性与权限检查 ---
-	// 1. 检查用户是否是该圈子的圈主 (只有圈主能发布内容)
-	var domain model.Domain
-	if err := DB.Where("id = ? AND owner_id = ?", domainID, userID).First(&domain).Error; err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied: you are not the owner of this domain"})
-		return
-	}
+	// 1. 只有圈主和管理员能发布内容，由 DomainRoleMiddleware 检查
 
 	// 2. 检查源节点是否存在且属于该用户
 	var sourceNode model.Node
@@ -1220,53 +1432,48 @@ func PublishNodeToDomainHandler(c *gin.Context) {
 		return
 	}
 
-	// --- 核心逻辑：使用事务执行递归复制 ---
+	// 3. 目标文件夹必须是该圈子中的文件夹
+	if input.TargetParentID != nil {
+		var parent model.DomainNode
+		if err := DB.Where("id = ? AND domain_id = ?", *input.TargetParentID, domainID).First(&parent).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Target folder not found in this domain"})
+			return
+		}
+		if parent.NodeType != "folder" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Target parent must be a folder"})
+			return
+		}
+	}
+
+	// --- 核心逻辑：使用事务执行递归复制，并记录源节点与副本的关联以便之后同步 ---
 	tx := DB.Begin()
-	err := recursiveCopyNode(tx, sourceNode.ID, uint(domainID), nil) // nil 表示发布到根目录
+	idMap := make(map[uint]uint)
+	copyRootID, _, err := nodetree.CopySubtree(tx, nodetree.Nodes, sourceNode.ID, nodetree.CopyTarget{
+		Kind:        nodetree.DomainNodes,
+		OwnerID:     domainID,
+		ParentID:    input.TargetParentID, // nil 表示发布到根目录
+		AfterCreate: handler.RevisionRecorder(model.RevisionKindDomainNode, userID.(uint)),
+		IDMap:       idMap,
+	})
 	if err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish content", "details": err.Error()})
 		return
 	}
-
-	tx.Commit()
-	c.JSON(http.StatusOK, gin.H{"message": "Content published successfully"})
-}
-
-// recursiveCopyNode 是真正的递归复制函数
-func recursiveCopyNode(tx *gorm.DB, sourceNodeID uint, domainID uint, targetParentID *uint) error {
-	// 1. 获取源节点信息
-	var sourceNode model.Node
-	if err := tx.First(&sourceNode, sourceNodeID).Error; err != nil {
-		return err
-	}
-
-	// 2. 创建一个新的 domain_node (副本)
-	newDomainNode := model.DomainNode{
-		DomainID: domainID,
-		ParentID: targetParentID,
-		NodeType: sourceNode.NodeType,
-		Title:    sourceNode.Title,
-		Content:  sourceNode.Content,
+	publication, err := handler.LinkPublication(tx, userID.(uint), domainID, sourceNode.ID, input.TargetParentID, idMap)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish content", "details": err.Error()})
+		return
 	}
-	if err := tx.Create(&newDomainNode).Error; err != nil {
-		return err
+	if err := handler.RecordDomainActivity(tx, domainID, userID.(uint), model.ActivityNodePublished, copyRootID, sourceNode.Title); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish content", "details": err.Error()})
+		return
 	}
 
-	// 3. 【递归部分】如果源节点是文件夹，则递归复制其所有子节点
-	if sourceNode.NodeType == "folder" {
-		var children []model.Node
-		tx.Where("parent_id = ?", sourceNode.ID).Find(&children)
-
-		for _, child := range children {
-			// 将新创建的 domain_node 的 ID 作为下一轮递归的 targetParentID
-			err := recursiveCopyNode(tx, child.ID, domainID, &newDomainNode.ID)
-			if err != nil {
-				return err // 如果任何一个子节点复制失败，整个事务都会回滚
-			}
-		}
-	}
-	return nil
+	tx.Commit()
+	c.JSON(http.StatusOK, gin.H{"message": "Content published successfully", "publication": publication})
 }
 
 func ListOwnedDomainsHandler(c *gin.Context) {
@@ -1375,7 +1582,7 @@ func ListDomainNodesHandler(c *gin.Context) {
 
 	// --- 4. 执行查询并返回结果 ---
 	var nodes []model.DomainNode
-	if err := query.Order("node_type, title").Find(&nodes).Error; err != nil {
+	if err := query.Order(nodetree.DomainNodes.Order).Find(&nodes).Error; err != nil {
 		log.Printf("Error finding domain nodes: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve domain content"})
 		return
@@ -1405,6 +1612,12 @@ func CreateDomainNodeHandler(c *gin.Context) {
 		return
 	}
 
+	language, err := lang.Normalize(input.Language)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// 如果指定了 parent_id，必须验证该父节点存在且属于当前圈子
 	if input.ParentID != nil {
 		var parentNode model.DomainNode
@@ -1422,6 +1635,9 @@ func CreateDomainNodeHandler(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot create a node under a text file"})
 			return
 		}
+		if language == "" {
+			language = parentNode.Language
+		}
 	}
 
 	// 创建新的 domain_node 实例
@@ -1431,15 +1647,38 @@ func CreateDomainNodeHandler(c *gin.Context) {
 		NodeType: input.NodeType,
 		Title:    input.Title,
 		Content:  input.Content,
+		Language: language,
 	}
 
-	// 如果是 folder，强制清空 content
-	if newDomainNode.NodeType == "folder" {
-		newDomainNode.Content = ""
+	// 按节点类型整理 content：folder 强制清空，card / qa 编码为结构化内容
+	content, err := nodecontent.Build(newDomainNode.NodeType, input.Content, input.Patch)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
+	newDomainNode.Content = content
 
-	// 保存到数据库
-	if err := DB.Create(&newDomainNode).Error; err != nil {
+	// 保存到数据库，并记录第一个修订版本
+	userID := c.MustGet("userID").(uint)
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		// 新节点追加到同级末尾
+		pos, err := nodetree.NextPosition(tx, nodetree.DomainNodes, domainID, newDomainNode.ParentID)
+		if err != nil {
+			return err
+		}
+		newDomainNode.Position = pos
+		if err := tx.Create(&newDomainNode).Error; err != nil {
+			return err
+		}
+		if err := search.IndexDomainNode(tx, &newDomainNode); err != nil {
+			return err
+		}
+		if _, err := handler.RecordRevision(tx, model.RevisionKindDomainNode, newDomainNode.ID, userID, newDomainNode.Title, newDomainNode.Content); err != nil {
+			return err
+		}
+		return handler.RecordDomainActivity(tx, domainID, userID, model.ActivityNodePublished, newDomainNode.ID, newDomainNode.Title)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create node in domain"})
 		return
 	}
@@ -1471,6 +1710,16 @@ func UpdateDomainNodeHandler(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error finding node"})
 		return
 	}
+	// 正在协同编辑的节点以协同会话为准；任何修改都会改变版本号，使会话的快照无法写入
+	if collabHub.Active(node.ID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Node is being edited collaboratively", "editors": collabHub.Presence(node.ID)})
+		return
+	}
+	// 圈主与管理员同时编辑时，后提交的一方需要先合并服务器端的版本
+	if !handler.IfMatch(c, node.Version) {
+		handler.RespondVersionConflict(c, node.Version, node)
+		return
+	}
 
 	// 应用更新
 	if input.Title != nil {
@@ -1481,20 +1730,90 @@ func UpdateDomainNodeHandler(c *gin.Context) {
 		node.Title = *input.Title
 	}
 
-	if input.Content != nil {
+	if input.Language != nil {
+		language, err := lang.Normalize(*input.Language)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		node.Language = language
+	}
+
+	if input.Content != nil || !input.Patch.Empty() {
 		if node.NodeType == "folder" {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot set content for a folder"})
 			return
 		}
-		node.Content = *input.Content
+		base := node.Content
+		if input.Content != nil {
+			base = *input.Content
+		}
+		content, err := nodecontent.Build(node.NodeType, base, input.Patch)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		node.Content = content
 	}
 
-	// 保存更新
-	if err := DB.Save(&node).Error; err != nil {
+	// 保存更新，同时追加一条修订记录
+	userID := c.MustGet("userID").(uint)
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{"title": node.Title, "content": node.Content, "language": node.Language}
+		if err := handler.UpdateVersioned(tx, &model.DomainNode{}, node.ID, node.Version, updates); err != nil {
+			return err
+		}
+		if err := tx.First(&node, node.ID).Error; err != nil {
+			return err
+		}
+		if err := search.IndexDomainNode(tx, &node); err != nil {
+			return err
+		}
+		if err := handler.InvalidateReferenceAudio(tx, model.RevisionKindDomainNode, node.ID, node.NodeType, node.Content, node.Language); err != nil {
+			return err
+		}
+		if err := handler.RealignTranslations(tx, model.RevisionKindDomainNode, node.ID, node.NodeType, node.Content); err != nil {
+			return err
+		}
+		if _, err := handler.RecordRevision(tx, model.RevisionKindDomainNode, node.ID, userID, node.Title, node.Content); err != nil {
+			return err
+		}
+		return handler.RecordDomainActivity(tx, domainID, userID, model.ActivityNodeUpdated, node.ID, node.Title)
+	})
+	if err != nil {
+		if errors.Is(err, handler.ErrVersionConflict) {
+			DB.First(&node, node.ID)
+			handler.RespondVersionConflict(c, node.Version, node)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update node"})
 		return
 	}
 
+	handler.SetVersionETag(c, node.Version)
+	c.JSON(http.StatusOK, node)
+}
+
+// === Get Domain Node Handler ===
+// GET /domains/:domainId/nodes/:nodeId
+// 返回单个圈子节点及其 ETag，编辑前用它获取最新版本
+func GetDomainNodeHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	domainID := c.Param("domainId")
+
+	var member model.DomainMember
+	if err := DB.Where("domain_id = ? AND user_id = ?", domainID, userID).First(&member).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: you are not a member of this domain"})
+		return
+	}
+
+	var node model.DomainNode
+	if err := DB.Where("id = ? AND domain_id = ?", c.Param("nodeId"), domainID).Preload("Tags").First(&node).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found in this domain"})
+		return
+	}
+
+	handler.SetVersionETag(c, node.Version)
 	c.JSON(http.StatusOK, node)
 }
 
@@ -1504,19 +1823,19 @@ func DeleteDomainNodeHandler(c *gin.Context) {
 	domainID := c.MustGet("domain").(model.Domain).ID
 	nodeID := c.Param("nodeId")
 
-	// GORM 的级联删除 (ON DELETE CASCADE) 会在数据库层面处理子节点的删除
-	// 我们只需要删除目标节点即可
-	result := DB.Where("id = ? AND domain_id = ?", nodeID, domainID).Delete(&model.DomainNode{})
-
-	// 检查是否有错误
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete node"})
+	// 数据库中并没有级联删除，子节点和录音需要我们自己递归软删除
+	var node model.DomainNode
+	if err := DB.Where("id = ? AND domain_id = ?", nodeID, domainID).First(&node).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found in this domain"})
 		return
 	}
 
-	// 检查是否真的删除了记录
-	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found in this domain"})
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		_, err := nodetree.SoftDeleteSubtree(tx, nodetree.DomainNodes, node.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete node"})
 		return
 	}
 
@@ -1562,24 +1881,31 @@ func MoveDomainNodeHandler(c *gin.Context) {
 		}
 
 		// 规则3：【重要】防止将父节点移动到其子孙节点下，避免形成循环
-		// 我们可以从 targetParent 向上遍历，检查其祖先链中是否包含 nodeToMove
-		currentParentID := targetParent.ParentID
-		for currentParentID != nil {
-			if *currentParentID == uint(nodeID) {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot move a parent node into its own descendant"})
-				return
-			}
-			var tempParent model.DomainNode
-			if err := DB.Select("parent_id").First(&tempParent, *currentParentID).Error; err != nil {
-				break // 到达根节点或出错
-			}
-			currentParentID = tempParent.ParentID
+		// 用递归 CTE 一次查出 targetParent 的祖先链，检查其中是否包含 nodeToMove
+		cyclic, err := nodetree.IsSelfOrDescendant(DB, nodetree.DomainNodes, nodeToMove.ID, targetParent.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate move"})
+			return
+		}
+		if cyclic {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot move a parent node into its own descendant"})
+			return
 		}
 	}
 
-	// 所有验证通过，更新 ParentID
+	// 所有验证通过，更新 ParentID，移入新文件夹的节点排到末尾
+	if !sameParent(nodeToMove.ParentID, input.NewParentID) {
+		pos, err := nodetree.NextPosition(DB, nodetree.DomainNodes, domainID, input.NewParentID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move node"})
+			return
+		}
+		nodeToMove.Position = pos
+	}
 	nodeToMove.ParentID = input.NewParentID
-	if err := DB.Save(&nodeToMove).Error; err != nil {
+	// 只写位置相关的列，不覆盖其他请求对标题、内容与版本号的并发修改
+	if err := DB.Model(&model.DomainNode{}).Where("id = ?", nodeToMove.ID).
+		Updates(map[string]interface{}{"parent_id": nodeToMove.ParentID, "position": nodeToMove.Position}).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move node"})
 		return
 	}
@@ -1640,7 +1966,7 @@ func GetNodeDetailsHandler(c *gin.Context) {
 	// 4. 执行数据库查询
 	// 【核心安全逻辑】查询条件必须同时包含 nodeID 和 userID
 	// 这可以防止用户通过猜测 ID 来获取不属于自己的节点信息
-	if err := DB.Where("id = ? AND user_id = ?", nodeID, userID).First(&node).Error; err != nil {
+	if err := DB.Where("id = ? AND user_id = ?", nodeID, userID).Preload("Tags").First(&node).Error; err != nil {
 		// 如果查询出错，判断错误类型
 		if err == gorm.ErrRecordNotFound {
 			// 这是最常见的情况：节点不存在，或者节点存在但不属于当前用户
@@ -1652,7 +1978,8 @@ func GetNodeDetailsHandler(c *gin.Context) {
 		return
 	}
 
-	// 5. 如果查询成功，返回找到的节点信息
+	// 5. 如果查询成功，返回找到的节点信息，ETag 供后续编辑时放入 If-Match
+	handler.SetVersionETag(c, node.Version)
 	c.JSON(http.StatusOK, node)
 }
 
@@ -1780,6 +2107,7 @@ func FeatureRecordingInDomainHandler(c *gin.Context) {
 	// 1. 找到这个录音，并确认它属于哪个圈子
 	var recording model.Recording
 	DB.First(&recording, recordingID)
+	wasFeatured := recording.IsDomainFeatured
 	if recording.DomainNodeID == nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "This recording does not belong to any domain"})
 		return
@@ -1794,8 +2122,20 @@ func FeatureRecordingInDomainHandler(c *gin.Context) {
 		return
 	}
 
-	// 3. 更新状态
-	DB.Model(&recording).Update("is_domain_featured", input.Feature)
+	// 3. 更新状态，新设为精选时记入圈子动态
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&recording).Update("is_domain_featured", input.Feature).Error; err != nil {
+			return err
+		}
+		if !input.Feature || wasFeatured {
+			return nil
+		}
+		return handler.RecordDomainActivity(tx, domainNode.DomainID, userID.(uint), model.ActivityRecordingFeatured, recording.ID, domainNode.Title)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update feature status"})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "Feature status updated"})
 }
 
@@ -1992,7 +2332,13 @@ func CreateCommentHandler(c *gin.Context) {
 	recordingID, _ := strconv.ParseUint(recordingID_str, 10, 32)
 
 	var input CreateCommentInput
-	if err := c.ShouldBindJSON(&input); err != nil { /* ... */
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !handler.ValidCommentCategory(input.Category) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category"})
+		return
 	}
 
 	// 检查录音是否存在
@@ -2001,11 +2347,18 @@ func CreateCommentHandler(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Recording not found"})
 		return
 	}
+	if err := handler.ValidateFeedbackRange(input.StartMs, input.EndMs, recording.DurationMs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	newComment := model.Comment{
 		RecordingID: uint(recordingID),
 		UserID:      userID.(uint),
 		Content:     input.Content,
+		StartMs:     input.StartMs,
+		EndMs:       input.EndMs,
+		Category:    input.Category,
 	}
 
 	tx := DB.Begin()
@@ -2397,7 +2750,7 @@ func CreateDomainNodeCommentHandler(c *gin.Context) {
 	}
 
 	// b. 绑定输入
-	var input CreateCommentInput // 复用之前的 CreateCommentInput 结构体
+	var input CreateDomainNodeCommentInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -2413,6 +2766,16 @@ func CreateDomainNodeCommentHandler(c *gin.Context) {
 		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 		return
 	}
+	// 只能引用同一节点上自己的或圈子内共享的标注
+	if input.AnnotationID != nil {
+		var annotation model.Annotation
+		if err := DB.Where("id = ? AND node_kind = ? AND node_id = ?", *input.AnnotationID, model.RevisionKindDomainNode, nodeID).
+			Where("(user_id = ? OR visibility = ?)", userID, model.AnnotationDomain).
+			First(&annotation).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Annotation not found on this node"})
+			return
+		}
+	}
 
 	// d. 创建评论并更新计数（使用事务）
 	var newComment model.DomainNodeComment
@@ -2422,6 +2785,7 @@ func CreateDomainNodeCommentHandler(c *gin.Context) {
 			DomainNodeID: uint(nodeID),
 			UserID:       userID,
 			Content:      input.Content,
+			AnnotationID: input.AnnotationID,
 		}
 		if err := tx.Create(&newComment).Error; err != nil {
 			return err
@@ -2441,7 +2805,7 @@ func CreateDomainNodeCommentHandler(c *gin.Context) {
 	}
 
 	// e. 返回新创建的评论（带作者信息）
-	DB.Preload("User").First(&newComment, newComment.ID)
+	DB.Preload("User").Preload("Annotation").First(&newComment, newComment.ID)
 
 	// 使用 DTO 组装安全的响应数据
 	type DomainNodeCommentResponse struct {
@@ -2484,6 +2848,7 @@ func ListDomainNodeCommentsHandler(c *gin.Context) {
 	var comments []model.DomainNodeComment
 	DB.Where("domain_node_id = ?", nodeID).
 		Preload("User").
+		Preload("Annotation").
 		Order("created_at ASC"). // 按时间正序排列，旧的在上面
 		Find(&comments)
 
@@ -2513,7 +2878,7 @@ func main() {
 	// 1. 初始化服务
 	initDB()
 	initMinIO()
-	mqManager = mq.NewRabbitMQManager("audio_processing")
+	mqManager = mq.NewRabbitMQManager("audio_processing", task.ReferenceAudioQueue)
 
 	// 启动调度器
 	stopCron := scheduler.Start(scheduler.Config{
@@ -2521,6 +2886,8 @@ func main() {
 		Logger:   log.Default(),
 		Env:      os.Getenv("APP_ENV"), // dev|prod
 		Timezone: os.Getenv("APP_TZ"),  // 默认 Asia/Shanghai
+		Minio:    minioClient,
+		Bucket:   minioBucket,
 	})
 	defer stopCron()
 
@@ -2539,7 +2906,8 @@ func main() {
 	config := cors.DefaultConfig()
 	config.AllowOrigins = []string{"http://localhost:5173"}
 	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
+	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "If-Match", "X-Share-Password"}
+	config.ExposeHeaders = []string{"ETag"}
 	config.MaxAge = 12 * time.Hour
 	r.Use(cors.New(config))
 
@@ -2555,6 +2923,33 @@ func main() {
 	uploadHandler := handler.NewUploadHandler(minioClient)
 	userHandler := handler.NewUserHandler(DB)       // <-- 新增
 	messageHandler := handler.NewMessageHandler(DB) // <-- 新增
+	trashHandler := handler.NewTrashHandler(DB, minioClient, minioBucket)
+	treeHandler := handler.NewTreeHandler(DB)
+	nodeBatchHandler := handler.NewNodeBatchHandler(DB)
+	positionHandler := handler.NewPositionHandler(DB)
+	searchHandler := handler.NewSearchHandler(DB)
+	tagHandler := handler.NewTagHandler(DB)
+	reviewHandler := handler.NewReviewHandler(DB)
+	shareHandler := handler.NewShareHandler(DB)
+	annotationHandler := handler.NewAnnotationHandler(DB)
+	translationHandler := handler.NewTranslationHandler(DB)
+	vocabHandler := handler.NewVocabHandler(DB)
+	domainMemberHandler := handler.NewDomainMemberHandler(DB)
+	invitationHandler := handler.NewDomainInvitationHandler(DB)
+	assignmentHandler := handler.NewAssignmentHandler(DB)
+	forkHandler := handler.NewForkHandler(DB)
+	peerReviewHandler := handler.NewPeerReviewHandler(DB)
+	feedbackHandler := handler.NewRecordingFeedbackHandler(DB, mqManager)
+	activityHandler := handler.NewDomainActivityHandler(DB)
+	analyticsHandler := handler.NewDomainAnalyticsHandler(DB)
+	referenceAudioHandler := handler.NewReferenceAudioHandler(DB, mqManager, minioClient, minioBucket)
+	practiceHandler := handler.NewPracticeHandler(DB, mqManager, minioClient, minioBucket, asr.FromEnv())
+	collabHandler := handler.NewCollabHandler(DB)
+	collabHub = collabHandler.Hub
+	revisionHandler := handler.NewRevisionHandler(DB, collabHub)
+	publicationHandler := handler.NewPublicationHandler(DB, collabHub)
+	// 定期把协同编辑的文档写回数据库，退出前在关闭 HTTP 服务之后再保存一次
+	go collabHub.Run(30 * time.Second)
 	// 4. 设置路由
 	apiV1 := r.Group("/api/v1")
 	apiV1.Use(middleware.AuthUserMiddleware())
@@ -2566,6 +2961,10 @@ func main() {
 		// 【修改】将论坛的公开路由指向新的 postHandler
 		apiV1.GET("/posts", postHandler.ListPosts)
 		apiV1.GET("/posts/:id", postHandler.GetPost)
+		// 分享链接无需登录即可查看
+		apiV1.GET("/shared/:token", shareHandler.GetSharedContent)
+		// WebSocket 无法携带 Authorization 请求头，允许用 access_token 查询参数认证
+		apiV1.GET("/domain-nodes/:id/collab", middleware.QueryTokenMiddleware(), middleware.AuthMiddleware(), collabHandler.EditDomainNode)
 		// --- 需要认证的路由组 ---
 		auth := apiV1.Group("/")
 		auth.Use(middleware.AuthMiddleware())
@@ -2613,11 +3012,81 @@ func main() {
 			auth.GET("/nodes", ListNodesHandler)
 			auth.POST("/nodes", CreateNodeHandler)
 			auth.GET("/nodes/search", SearchNodesHandler)
+			auth.GET("/search", searchHandler.Search)
+
+			auth.GET("/tags", tagHandler.ListTags)
+			auth.POST("/tags", tagHandler.CreateTag)
+			auth.PUT("/tags/:id", tagHandler.UpdateTag)
+			auth.DELETE("/tags/:id", tagHandler.DeleteTag)
+			auth.PUT("/nodes/:id/tags", tagHandler.SetNodeTags)
+			auth.GET("/saved-filters", tagHandler.ListSavedFilters)
+			auth.POST("/saved-filters", tagHandler.CreateSavedFilter)
+			auth.PUT("/saved-filters/:id", tagHandler.UpdateSavedFilter)
+			auth.DELETE("/saved-filters/:id", tagHandler.DeleteSavedFilter)
+			auth.GET("/saved-filters/:id/nodes", tagHandler.ListSavedFilterNodes)
+
+			auth.POST("/nodes/:id/review", reviewHandler.ReviewNode)
+			auth.GET("/reviews/due", reviewHandler.ListDueNodes)
+			auth.GET("/nodes/tree", treeHandler.GetMyTree)
+			auth.GET("/nodes/export", treeHandler.ExportMyTree)
 			auth.GET("/nodes/:id", GetNodeDetailsHandler)
 			auth.PUT("/nodes/:id", UpdateNodeHandler)
 			auth.DELETE("/nodes/:id", DeleteNodeHandler)
 			auth.PUT("/nodes/:id/move", MoveNodeHandler)
+			auth.PUT("/nodes/bulk-move", nodeBatchHandler.BulkMoveNodes)
+			auth.PUT("/nodes/reorder", positionHandler.ReorderNodes)
+			auth.PUT("/nodes/:id/position", positionHandler.PlaceNode)
+			auth.POST("/nodes/:id/duplicate", nodeBatchHandler.DuplicateNode)
+			auth.POST("/nodes/:id/copy", nodeBatchHandler.CopyNode)
 			auth.GET("/nodes/:id/recordings", ListRecordingsForNodeHandler)
+			auth.GET("/nodes/:id/path", treeHandler.GetNodePath)
+			// 分享链接
+			auth.POST("/nodes/:id/share-links", shareHandler.CreateShareLink)
+			auth.GET("/nodes/:id/share-links", shareHandler.ListShareLinks)
+			auth.DELETE("/share-links/:id", shareHandler.RevokeShareLink)
+			auth.POST("/shared/:token/clone", shareHandler.CloneSharedContent)
+			// 回收站
+			auth.GET("/trash", trashHandler.ListMyTrash)
+			auth.POST("/trash/nodes/:id/restore", trashHandler.RestoreMyNode)
+			auth.DELETE("/trash/nodes/:id", trashHandler.PurgeMyNode)
+			// 修订历史
+			auth.GET("/nodes/:id/annotations", annotationHandler.ListNodeAnnotations)
+			auth.POST("/nodes/:id/annotations", annotationHandler.CreateNodeAnnotation)
+			auth.PUT("/annotations/:id", annotationHandler.UpdateAnnotation)
+			auth.DELETE("/annotations/:id", annotationHandler.DeleteAnnotation)
+			auth.GET("/nodes/:id/segments", translationHandler.GetNodeSegments)
+			auth.PUT("/nodes/:id/translations", translationHandler.PutNodeTranslations)
+			auth.GET("/nodes/:id/vocabulary", vocabHandler.GetNodeVocabulary)
+			auth.GET("/nodes/:id/reference-audio", referenceAudioHandler.ListNodeReferenceAudio)
+			auth.POST("/nodes/:id/reference-audio", referenceAudioHandler.CreateNodeReferenceAudio)
+			auth.POST("/nodes/:id/reference-audio/upload", referenceAudioHandler.UploadNodeReferenceAudio)
+			auth.POST("/nodes/:id/practice-sessions", practiceHandler.CreateNodePracticeSession)
+
+			// 发布关联：预览差异并把个人空间中的修改同步到圈子副本
+			auth.GET("/publications", publicationHandler.ListPublications)
+			auth.GET("/publications/:id/sync", publicationHandler.PreviewPublicationSync)
+			auth.POST("/publications/:id/sync", publicationHandler.SyncPublication)
+			auth.DELETE("/publications/:id", publicationHandler.UnlinkPublication)
+			auth.POST("/nodes/:id/publications/sync", publicationHandler.SyncNodePublications)
+			auth.GET("/nodes/:id/attribution", forkHandler.GetNodeAttribution)
+			auth.GET("/forks", forkHandler.ListMyForks)
+			auth.GET("/recordings/:id/peer-reviews", peerReviewHandler.GetRecordingPeerReviews)
+			auth.GET("/practice-sessions", practiceHandler.ListPracticeSessions)
+			auth.GET("/practice-sessions/:id", practiceHandler.GetPracticeSession)
+			auth.POST("/practice-sessions/:id/segments/:index/attempts", practiceHandler.CreateAttempt)
+			auth.POST("/practice-sessions/:id/finish", practiceHandler.FinishPracticeSession)
+			auth.GET("/texts/:id/vocabulary", vocabHandler.GetTextVocabulary)
+			auth.GET("/dictionary", vocabHandler.LookupWord)
+			auth.GET("/word-book", vocabHandler.ListWordBook)
+			auth.GET("/word-book/due", vocabHandler.ListDueWords)
+			auth.POST("/word-book", vocabHandler.AddWord)
+			auth.PUT("/word-book/:id", vocabHandler.UpdateWord)
+			auth.DELETE("/word-book/:id", vocabHandler.DeleteWord)
+			auth.POST("/word-book/:id/review", vocabHandler.ReviewWord)
+			auth.GET("/nodes/:id/revisions", revisionHandler.ListNodeRevisions)
+			auth.GET("/nodes/:id/revisions/diff", revisionHandler.DiffNodeRevisions)
+			auth.GET("/nodes/:id/revisions/:version", revisionHandler.GetNodeRevision)
+			auth.POST("/nodes/:id/revisions/:version/restore", revisionHandler.RestoreNodeRevision)
 
 			// --- 个人录音 (Recordings) (你的现有逻辑，保持不变) ---
 			auth.GET("/recordings", ListMyRecordingsHandler)
@@ -2629,6 +3098,8 @@ func main() {
 			auth.DELETE("/recordings/:id/like", UnlikeRecordingHandler)
 			auth.POST("/recordings/:id/comments", CreateCommentHandler)
 			auth.GET("/recordings/:id/comments", ListCommentsHandler)
+			auth.POST("/recordings/:id/voice-feedback", feedbackHandler.CreateVoiceFeedback)
+			auth.GET("/recordings/:id/feedback", feedbackHandler.GetRecordingFeedback)
 			auth.POST("/recordings/:id/feature-in-domain", FeatureRecordingInDomainHandler)
 
 			// --- AI 助手 (你的现有逻辑，保持不变) ---
@@ -2638,31 +3109,139 @@ func main() {
 			auth.POST("/domains", CreateDomainHandler)
 			auth.POST("/domains/join", JoinDomainHandler)
 			auth.GET("/domains/my", ListMyDomainsHandler)
+			auth.GET("/invitations/:token", invitationHandler.PreviewInvitation)
+			auth.POST("/invitations/:token/accept", invitationHandler.AcceptInvitation)
+			auth.GET("/domain-join-requests", invitationHandler.ListMyJoinRequests)
+			auth.GET("/assignments", assignmentHandler.ListMyAssignments)
+			auth.DELETE("/domain-join-requests/:id", invitationHandler.CancelJoinRequest)
 			auth.GET("/domain-nodes/:id/recordings", ListRecordingsForDomainNodeHandler)
 			auth.POST("/domain-nodes/:id/comments", CreateDomainNodeCommentHandler)
 			auth.GET("/domain-nodes/:id/comments", ListDomainNodeCommentsHandler)
+			auth.GET("/domain-nodes/:id/collab/presence", collabHandler.GetDomainNodePresence)
+			auth.GET("/domain-nodes/:id/annotations", annotationHandler.ListDomainNodeAnnotations)
+			auth.POST("/domain-nodes/:id/annotations", annotationHandler.CreateDomainNodeAnnotation)
+			auth.GET("/domain-nodes/:id/segments", translationHandler.GetDomainNodeSegments)
+			auth.PUT("/domain-nodes/:id/translations", translationHandler.PutDomainNodeTranslations)
+			auth.GET("/domain-nodes/:id/vocabulary", vocabHandler.GetDomainNodeVocabulary)
+			auth.GET("/domain-nodes/:id/reference-audio", referenceAudioHandler.ListDomainNodeReferenceAudio)
+			auth.POST("/domain-nodes/:id/reference-audio", referenceAudioHandler.CreateDomainNodeReferenceAudio)
+			auth.POST("/domain-nodes/:id/reference-audio/upload", referenceAudioHandler.UploadDomainNodeReferenceAudio)
+			auth.POST("/domain-nodes/:id/practice-sessions", practiceHandler.CreateDomainNodePracticeSession)
+			auth.GET("/domain-nodes/:id/revisions", revisionHandler.ListDomainNodeRevisions)
+			auth.GET("/domain-nodes/:id/revisions/diff", revisionHandler.DiffDomainNodeRevisions)
+			auth.GET("/domain-nodes/:id/revisions/:version", revisionHandler.GetDomainNodeRevision)
+			auth.POST("/domain-nodes/:id/revisions/:version/restore", revisionHandler.RestoreDomainNodeRevision)
 
 			domainSpecific := auth.Group("/domains/:domainId")
 			{
 				domainSpecific.GET("/details", GetDomainDetailsHandler)
 				domainSpecific.GET("/nodes", ListDomainNodesHandler)
+				domainSpecific.GET("/tree", treeHandler.GetDomainTree)
+				domainSpecific.GET("/export", treeHandler.ExportDomainTree)
+				domainSpecific.GET("/nodes/:nodeId", GetDomainNodeHandler)
+				domainSpecific.GET("/nodes/:nodeId/path", treeHandler.GetDomainNodePath)
 				domainSpecific.GET("/featured-recordings", ListDomainFeaturedRecordingsHandler)
 				domainSpecific.GET("/nodes/:nodeId/featured-recordings", ListFeaturedRecordingsForNode)
 				domainSpecific.GET("/nodes/:nodeId/all-recordings", ListAllRecordingsForNodeInDomainHandler)
-				domainSpecific.POST("/publish", PublishNodeToDomainHandler)
-
+				domainSpecific.POST("/publish", middleware.DomainRoleMiddleware(DB, model.DomainRoleOwner, model.DomainRoleAdmin), PublishNodeToDomainHandler)
+
+				// 成员管理
+				anyMember := middleware.DomainRoleMiddleware(DB)
+				managers := middleware.DomainRoleMiddleware(DB, model.DomainRoleOwner, model.DomainRoleAdmin)
+				ownerOnly := middleware.DomainRoleMiddleware(DB, model.DomainRoleOwner)
+				domainSpecific.GET("/members", anyMember, domainMemberHandler.ListMembers)
+				domainSpecific.PUT("/members/:userId/role", ownerOnly, domainMemberHandler.UpdateMemberRole)
+				domainSpecific.DELETE("/members/:userId", managers, domainMemberHandler.RemoveMember)
+				domainSpecific.POST("/leave", anyMember, domainMemberHandler.LeaveDomain)
+				domainSpecific.POST("/nodes/:nodeId/fork", anyMember, forkHandler.ForkDomainNode)
+				domainSpecific.GET("/bans", managers, domainMemberHandler.ListBans)
+				domainSpecific.POST("/bans", managers, domainMemberHandler.BanMember)
+				domainSpecific.DELETE("/bans/:userId", managers, domainMemberHandler.UnbanMember)
+				domainSpecific.POST("/transfer", ownerOnly, domainMemberHandler.TransferOwnership)
+
+				// 邀请与加入审核
+				domainSpecific.POST("/invitations", managers, invitationHandler.CreateInvitation)
+				domainSpecific.GET("/invitations", managers, invitationHandler.ListInvitations)
+				domainSpecific.DELETE("/invitations/:invitationId", managers, invitationHandler.RevokeInvitation)
+				domainSpecific.POST("/join-code", managers, invitationHandler.RotateJoinCode)
+				domainSpecific.PATCH("/settings", managers, invitationHandler.UpdateSettings)
+				domainSpecific.GET("/join-requests", managers, invitationHandler.ListJoinRequests)
+				domainSpecific.POST("/join-requests/:requestId/approve", managers, invitationHandler.ApproveJoinRequest)
+				domainSpecific.POST("/join-requests/:requestId/reject", managers, invitationHandler.RejectJoinRequest)
+
+				// 作业
+				domainSpecific.GET("/assignments", anyMember, assignmentHandler.ListDomainAssignments)
+				domainSpecific.POST("/assignments", managers, assignmentHandler.CreateAssignment)
+				domainSpecific.GET("/assignments/:assignmentId", anyMember, assignmentHandler.GetAssignment)
+				domainSpecific.PATCH("/assignments/:assignmentId", managers, assignmentHandler.UpdateAssignment)
+				domainSpecific.DELETE("/assignments/:assignmentId", managers, assignmentHandler.DeleteAssignment)
+				domainSpecific.GET("/assignments/:assignmentId/roster", managers, assignmentHandler.GetAssignmentRoster)
+
+				// 学习统计与排行榜
+				domainSpecific.GET("/analytics", managers, analyticsHandler.GetDomainAnalytics)
+				domainSpecific.GET("/leaderboard", anyMember, analyticsHandler.GetLeaderboard)
+				domainSpecific.PUT("/leaderboard/opt-in", anyMember, analyticsHandler.SetLeaderboardOptIn)
+
+				// 评分量表与互评
+				domainSpecific.GET("/rubrics", anyMember, peerReviewHandler.ListRubrics)
+				domainSpecific.POST("/rubrics", managers, peerReviewHandler.CreateRubric)
+				domainSpecific.PUT("/rubrics/:rubricId", managers, peerReviewHandler.UpdateRubric)
+				domainSpecific.DELETE("/rubrics/:rubricId", managers, peerReviewHandler.DeleteRubric)
+				domainSpecific.GET("/peer-reviews", anyMember, peerReviewHandler.ListPeerReviewRounds)
+				domainSpecific.POST("/peer-reviews", managers, peerReviewHandler.CreatePeerReviewRound)
+				domainSpecific.GET("/peer-reviews/:roundId", managers, peerReviewHandler.GetPeerReviewRound)
+				domainSpecific.POST("/peer-reviews/:roundId/close", managers, peerReviewHandler.ClosePeerReviewRound)
+				domainSpecific.GET("/peer-reviews/:roundId/my-reviews", anyMember, peerReviewHandler.ListMyPeerReviews)
+				domainSpecific.PUT("/peer-reviews/:roundId/reviews/:assignmentId", anyMember, peerReviewHandler.SubmitPeerReview)
+
+				// 动态与公告
+				domainSpecific.GET("/activity", anyMember, activityHandler.ListActivity)
+				domainSpecific.GET("/announcements", anyMember, activityHandler.ListAnnouncements)
+				domainSpecific.POST("/announcements", managers, activityHandler.CreateAnnouncement)
+				domainSpecific.PATCH("/announcements/:announcementId", managers, activityHandler.UpdateAnnouncement)
+				domainSpecific.DELETE("/announcements/:announcementId", managers, activityHandler.DeleteAnnouncement)
+
+				// 圈主和管理员可以编辑内容
 				domainContent := domainSpecific.Group("/nodes")
-				domainContent.Use(DomainOwnerMiddleware())
+				domainContent.Use(managers)
 				{
 					domainContent.POST("", CreateDomainNodeHandler)
 					domainContent.PUT("/:nodeId", UpdateDomainNodeHandler)
 					domainContent.DELETE("/:nodeId", DeleteDomainNodeHandler)
 					domainContent.PUT("/:nodeId/move", MoveDomainNodeHandler)
+					domainContent.PUT("/reorder", positionHandler.ReorderDomainNodes)
+					domainContent.PUT("/:nodeId/position", positionHandler.PlaceDomainNode)
+				}
+
+				domainTrash := domainSpecific.Group("/trash")
+				domainTrash.Use(managers)
+				{
+					domainTrash.GET("", trashHandler.ListDomainTrash)
+					domainTrash.POST("/:nodeId/restore", trashHandler.RestoreDomainNode)
+					domainTrash.DELETE("/:nodeId", trashHandler.PurgeDomainNode)
 				}
 			}
 		}
 	}
 
-	// 5. 启动服务
-	r.Run(":8080")
+	// 5. 启动服务，收到 SIGINT / SIGTERM 后停止接收新请求，等待进行中的请求结束
+	srv := &http.Server{Addr: ":8080", Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("HTTP server failed: %v", err)
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	log.Println("Shutting down server...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("ERROR: HTTP server shutdown: %v", err)
+	}
+	// WebSocket 连接不受 Shutdown 管理，进程退出前把协同编辑的文档写回数据库
+	collabHub.Flush()
 }
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/shuind/language-learner/backend/internal/model"
+	"github.com/shuind/language-learner/backend/internal/testdb"
+	"github.com/shuind/language-learner/backend/internal/tts"
+)
+
+func TestUpdateNodeInvalidatesReferenceAudio(t *testing.T) {
+	DB = testdb.Open(t, &model.User{}, &model.Tag{}, &model.Node{}, &model.NodeRevision{}, &model.SearchDocument{},
+		&model.Annotation{}, &model.ReferenceAudio{}, &model.SegmentTranslation{})
+	gin.SetMode(gin.TestMode)
+
+	node := model.Node{UserID: 1, NodeType: "text", Title: "Fox", Content: "The fox runs. The dog sleeps.", Language: "en"}
+	if err := DB.Create(&node).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 用 tts.Fake 的结果填充已合成的示范音频：整段与两句
+	synth := &tts.Fake{}
+	for i, text := range map[int]string{model.ReferenceAudioWholeNode: node.Content, 0: "The fox runs.", 1: "The dog sleeps."} {
+		result, err := synth.Synthesize(t.Context(), text, "en", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		hash := tts.CacheKey(text, "en", "")
+		if err := DB.Create(&model.ReferenceAudio{
+			NodeKind: model.RevisionKindNode, NodeID: node.ID, SegmentIndex: i, Source: model.ReferenceAudioSourceTTS,
+			ContentHash: hash, Text: text, Language: "en", Status: model.ReferenceAudioCompleted,
+			ObjectName: "tts/" + hash + ".wav", ContentType: result.ContentType, DurationMs: int(result.Duration.Milliseconds()),
+			RequestedBy: 1,
+		}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := gin.New()
+	r.PUT("/nodes/:id", func(c *gin.Context) { c.Set("userID", uint(1)) }, UpdateNodeHandler)
+	req := httptest.NewRequest(http.MethodPut, "/nodes/"+strconv.FormatUint(uint64(node.ID), 10), strings.NewReader(`{"content":"The fox runs. The cat sleeps."}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("update status = %d: %s", w.Code, w.Body.String())
+	}
+
+	var left []model.ReferenceAudio
+	DB.Where("node_kind = ? AND node_id = ?", model.RevisionKindNode, node.ID).Find(&left)
+	if len(left) != 1 || left[0].SegmentIndex != 0 {
+		t.Fatalf("remaining reference audio %+v, want only the unchanged first sentence", left)
+	}
+
+	// 只改标题不影响示范音频
+	req = httptest.NewRequest(http.MethodPut, "/nodes/"+strconv.FormatUint(uint64(node.ID), 10), strings.NewReader(`{"title":"Fox and cat"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("rename status = %d: %s", w.Code, w.Body.String())
+	}
+	var count int64
+	DB.Model(&model.ReferenceAudio{}).Where("node_id = ?", node.ID).Count(&count)
+	if count != 1 {
+		t.Fatalf("rename left %d reference audio records, want 1", count)
+	}
+}
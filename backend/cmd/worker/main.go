@@ -4,26 +4,32 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
-	"mime/multipart"
-	"net/http"
 	"os"
 	"time"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/shuind/language-learner/backend/internal/asr"
 	"github.com/shuind/language-learner/backend/internal/model" // !!! 确保这是你正确的模块路径
+	"github.com/shuind/language-learner/backend/internal/nodecontent"
+	"github.com/shuind/language-learner/backend/internal/review"
+	"github.com/shuind/language-learner/backend/internal/scoring"
+	"github.com/shuind/language-learner/backend/internal/task"
+	"github.com/shuind/language-learner/backend/internal/tts"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-// Task 定义了从消息队列接收的任务结构
+// Task 定义了从消息队列接收的任务结构。CommentID 不为 0 时是一条语音反馈，见 task.VoiceFeedbackTask
 type Task struct {
 	RecordingID uint   `json:"recording_id"`
+	CommentID   uint   `json:"comment_id"`
 	FileContent []byte `json:"file_content"`
+	ContentType string `json:"content_type"`
 }
 
 // 全局变量
@@ -31,6 +37,8 @@ var (
 	DB          *gorm.DB
 	minioClient *minio.Client
 	minioBucket = "recordings"
+	transcriber asr.Transcriber
+	synthesizer tts.Synthesizer
 )
 
 // initDB 初始化数据库连接（带重试机制）
@@ -88,6 +96,25 @@ func initMinio() {
 	}
 }
 
+// initTranscriber 初始化语音识别客户端
+func initTranscriber() {
+	transcriber = asr.FromEnv()
+}
+
+// initSynthesizer 初始化语音合成客户端，见 tts.FromEnv
+func initSynthesizer() {
+	synthesizer = tts.FromEnv()
+}
+
+// publicURL 返回对象在 MinIO 中的公开访问地址
+func publicURL(objectName string) string {
+	minioPublicEndpoint := os.Getenv("MINIO_PUBLIC_ENDPOINT")
+	if minioPublicEndpoint == "" {
+		minioPublicEndpoint = "http://localhost:9000"
+	}
+	return fmt.Sprintf("%s/%s/%s", minioPublicEndpoint, minioBucket, objectName)
+}
+
 // ===================================================================
 // =================== 核心修改在这里 ================================
 // ===================================================================
@@ -109,11 +136,7 @@ func processTask(task Task) error {
 		return fmt.Errorf("minio upload failed for RecordingID %d: %w", task.RecordingID, err)
 	}
 
-	minioPublicEndpoint := os.Getenv("MINIO_PUBLIC_ENDPOINT")
-	if minioPublicEndpoint == "" {
-		minioPublicEndpoint = "http://localhost:9000"
-	}
-	audioURL := fmt.Sprintf("%s/%s/%s", minioPublicEndpoint, minioBucket, objectName)
+	audioURL := publicURL(objectName)
 	log.Printf("RecordingID %d uploaded to MinIO: %s", task.RecordingID, audioURL)
 
 	// 【修改】将 status, audio_url 和初始 ai_status 一起更新
@@ -127,13 +150,19 @@ func processTask(task Task) error {
 	log.Printf("RecordingID %d: Calling AI service for transcription...", task.RecordingID)
 	DB.Model(&model.Recording{}).Where("id = ?", task.RecordingID).Update("ai_status", "processing")
 
-	asrApiUrl := os.Getenv("ASR_API_URL")
-	if asrApiUrl == "" {
-		//【建议】使用 host.docker.internal 可能不稳定，可以直接指向宿主机 IP 或另一个服务名
-		asrApiUrl = "http://host.docker.internal:8000/transcribe"
+	// 把朗读内容的语言作为识别提示；找不到朗读内容时交由识别服务自动检测
+	var rec model.Recording
+	var recited *recitedContent
+	contentErr := DB.First(&rec, task.RecordingID).Error
+	if contentErr == nil {
+		recited, contentErr = loadRecitedContent(&rec)
+	}
+	language := ""
+	if recited != nil {
+		language = recited.Language
 	}
 
-	recognizedText, err := callAsrApi(asrApiUrl, task.FileContent)
+	recognizedText, err := transcriber.Transcribe(context.Background(), task.FileContent, language)
 	if err != nil {
 		// AI 识别失败，只更新 AI 相关状态
 		log.Printf("WARN: AI processing failed for RecordingID %d: %v", task.RecordingID, err)
@@ -152,47 +181,165 @@ func processTask(task Task) error {
 		"recognized_text": recognizedText,
 	})
 
+	// --- 步骤 4: 与朗读内容对比评分 ---
+	if contentErr != nil {
+		log.Printf("WARN: Skipping scoring for RecordingID %d: %v", task.RecordingID, contentErr)
+	} else if err := scoreRecording(&rec, recited, recognizedText); err != nil {
+		log.Printf("WARN: Scoring failed for RecordingID %d: %v", task.RecordingID, err)
+	}
+
 	log.Printf("Successfully processed task for RecordingID: %d. AI part completed.", task.RecordingID)
 	return nil
 }
-func callAsrApi(url string, fileContent []byte) (string, error) {
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-	part, err := writer.CreateFormFile("file", "audio.webm")
-	if err != nil {
-		return "", fmt.Errorf("failed to create form file: %w", err)
+
+// processVoiceFeedback 把圈主或管理员的语音反馈上传到 MinIO 并更新对应的评论
+func processVoiceFeedback(t Task) error {
+	var comment model.Comment
+	if err := DB.Select("id", "recording_id").First(&comment, t.CommentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// 评论或其录音已被删除，不再上传
+			log.Printf("Comment %d no longer exists, skipping voice feedback", t.CommentID)
+			return nil
+		}
+		return err
 	}
-	if _, err := io.Copy(part, bytes.NewReader(fileContent)); err != nil {
-		return "", fmt.Errorf("failed to copy file content to form: %w", err)
+
+	// 对象名由评论 ID 决定，消息重投时覆盖同一个文件
+	objectName := fmt.Sprintf("feedback/recording-%d/comment-%d.%s", comment.RecordingID, comment.ID, tts.Extension(t.ContentType))
+	if _, err := minioClient.PutObject(context.Background(), minioBucket, objectName, bytes.NewReader(t.FileContent), int64(len(t.FileContent)), minio.PutObjectOptions{
+		ContentType: t.ContentType,
+	}); err != nil {
+		return fmt.Errorf("minio upload failed for comment %d: %w", comment.ID, err)
 	}
-	writer.Close()
 
-	req, err := http.NewRequest("POST", url, body)
-	if err != nil {
-		return "", fmt.Errorf("failed to create http request: %w", err)
+	res := DB.Model(&model.Comment{}).Where("id = ?", comment.ID).Updates(map[string]interface{}{
+		"audio_url":         publicURL(objectName),
+		"audio_object_name": objectName,
+		"audio_status":      model.CommentAudioCompleted,
+	})
+	if res.Error != nil {
+		return res.Error
 	}
-	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if res.RowsAffected == 0 {
+		// 上传期间评论被删除
+		minioClient.RemoveObject(context.Background(), minioBucket, objectName, minio.RemoveObjectOptions{})
+	}
+	log.Printf("Voice feedback for comment %d uploaded to MinIO: %s", comment.ID, objectName)
+	return nil
+}
 
-	// 模型处理可能较慢，设置一个合理的超时时间
-	client := &http.Client{Timeout: 90 * time.Second}
-	resp, err := client.Do(req)
+// recitedContent 是一条录音所朗读的内容
+type recitedContent struct {
+	NodeType string
+	Content  string
+	Language string
+}
+
+// scoreRecording 把识别结果与录音时朗读的内容对比，写入准确率；
+// 为自己的 card / qa 节点录音时，同时按准确率自动记一次复习
+func scoreRecording(rec *model.Recording, recited *recitedContent, recognizedText string) error {
+	reference := nodecontent.Reference(recited.NodeType, recited.Content)
+	result := scoring.Score(recited.Language, reference, recognizedText)
+
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Recording{}).Where("id = ?", rec.ID).Update("accuracy", result.Accuracy).Error; err != nil {
+			return err
+		}
+		// 通过分享链接为他人节点录音时不影响任何人的复习计划
+		if rec.NodeID == nil || rec.ShareLinkID != nil || (recited.NodeType != nodecontent.TypeCard && recited.NodeType != nodecontent.TypeQA) {
+			return nil
+		}
+		_, err := review.RecordNode(tx, rec.UserID, *rec.NodeID, result.Quality(), time.Now())
+		return err
+	})
+}
+
+// loadRecitedContent 读取录音所朗读的内容，正文优先使用录音时的修订版本
+func loadRecitedContent(rec *model.Recording) (*recitedContent, error) {
+	var recited recitedContent
+	var err error
+	switch {
+	case rec.NodeID != nil:
+		var node model.Node
+		err = DB.Unscoped().Select("id", "node_type", "content", "language").First(&node, *rec.NodeID).Error
+		recited = recitedContent{NodeType: node.NodeType, Content: node.Content, Language: node.Language}
+	case rec.DomainNodeID != nil:
+		var node model.DomainNode
+		err = DB.Unscoped().Select("id", "node_type", "content", "language").First(&node, *rec.DomainNodeID).Error
+		recited = recitedContent{NodeType: node.NodeType, Content: node.Content, Language: node.Language}
+	case rec.TextID != nil:
+		var text model.Text
+		err = DB.Unscoped().Select("id", "content", "language").First(&text, *rec.TextID).Error
+		recited = recitedContent{NodeType: nodecontent.TypeText, Content: text.Content, Language: text.Language}
+	default:
+		return nil, fmt.Errorf("recording %d is not linked to any content", rec.ID)
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to call AI service: %w", err)
+		return nil, err
+	}
+	if rec.RevisionID != nil {
+		var revision model.NodeRevision
+		if err := DB.Select("id", "content").First(&revision, *rec.RevisionID).Error; err != nil {
+			return nil, err
+		}
+		recited.Content = revision.Content
 	}
-	defer resp.Body.Close()
+	return &recited, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("AI service returned an error. Status: %d, Body: %s", resp.StatusCode, string(bodyBytes))
+// processReferenceAudio 为一条示范音频记录合成音频并上传到 MinIO。
+// 对象名由内容哈希决定，相同内容已合成过时直接复用。
+func processReferenceAudio(t task.ReferenceAudioTask) error {
+	var audio model.ReferenceAudio
+	if err := DB.First(&audio, t.ReferenceAudioID).Error; err != nil {
+		// 记录已因内容修改而失效
+		log.Printf("Reference audio %d no longer exists, skipping: %v", t.ReferenceAudioID, err)
+		return nil
+	}
+	if audio.Status == model.ReferenceAudioCompleted {
+		return nil
 	}
 
-	var aiResult struct {
-		Text string `json:"text"`
+	var cached model.ReferenceAudio
+	if DB.Where("content_hash = ? AND source = ? AND status = ?", audio.ContentHash, model.ReferenceAudioSourceTTS, model.ReferenceAudioCompleted).First(&cached).Error == nil {
+		log.Printf("Reference audio %d: reusing cached audio %s", audio.ID, cached.ObjectName)
+		return DB.Model(&audio).Updates(map[string]interface{}{
+			"status":       model.ReferenceAudioCompleted,
+			"audio_url":    cached.AudioURL,
+			"object_name":  cached.ObjectName,
+			"content_type": cached.ContentType,
+			"duration_ms":  cached.DurationMs,
+			"error":        "",
+		}).Error
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&aiResult); err != nil {
-		return "", fmt.Errorf("failed to decode AI service response: %w", err)
+
+	DB.Model(&audio).Update("status", model.ReferenceAudioProcessing)
+	// --- 步骤 1: 合成 ---
+	result, err := synthesizer.Synthesize(context.Background(), audio.Text, audio.Language, audio.Voice)
+	if err != nil {
+		return fmt.Errorf("synthesis failed for reference audio %d: %w", audio.ID, err)
 	}
-	return aiResult.Text, nil
+
+	// --- 步骤 2: 上传到 MinIO ---
+	objectName := fmt.Sprintf("tts/%s.%s", audio.ContentHash, tts.Extension(result.ContentType))
+	_, err = minioClient.PutObject(context.Background(), minioBucket, objectName, bytes.NewReader(result.Data), int64(len(result.Data)), minio.PutObjectOptions{
+		ContentType: result.ContentType,
+	})
+	if err != nil {
+		return fmt.Errorf("minio upload failed for reference audio %d: %w", audio.ID, err)
+	}
+
+	// --- 步骤 3: 更新记录 ---
+	audioURL := publicURL(objectName)
+	log.Printf("Reference audio %d uploaded to MinIO: %s", audio.ID, audioURL)
+	return DB.Model(&audio).Updates(map[string]interface{}{
+		"status":       model.ReferenceAudioCompleted,
+		"audio_url":    audioURL,
+		"object_name":  objectName,
+		"content_type": result.ContentType,
+		"duration_ms":  result.Duration.Milliseconds(),
+		"error":        "",
+	}).Error
 }
 
 // failTask 标记任务为失败
@@ -209,6 +356,8 @@ func main() {
 
 	initDB()
 	initMinio()
+	initTranscriber()
+	initSynthesizer()
 
 	var conn *amqp.Connection
 	var err error
@@ -260,6 +409,15 @@ func main() {
 				continue
 			}
 
+			if task.CommentID != 0 {
+				if err := processVoiceFeedback(task); err != nil {
+					log.Printf("ERROR: %v", err)
+					DB.Model(&model.Comment{}).Where("id = ?", task.CommentID).Update("audio_status", model.CommentAudioFailed)
+				}
+				d.Ack(false)
+				continue
+			}
+
 			// 【修改】简化错误处理逻辑
 			if err := processTask(task); err != nil {
 				// 只有当 processTask 返回错误时，才意味着是致命错误
@@ -277,6 +435,33 @@ func main() {
 		}
 	}()
 
+	// 示范音频的合成任务
+	if _, err := ch.QueueDeclare(task.ReferenceAudioQueue, true, false, false, false, nil); err != nil {
+		log.Fatalf("Failed to declare a queue: %v", err)
+	}
+	ttsMsgs, err := ch.Consume(task.ReferenceAudioQueue, "", false, false, false, false, nil)
+	if err != nil {
+		log.Fatalf("Failed to register a consumer: %v", err)
+	}
+	go func() {
+		for d := range ttsMsgs {
+			var t task.ReferenceAudioTask
+			if err := json.Unmarshal(d.Body, &t); err != nil {
+				log.Printf("ERROR: Failed to unmarshal message: %v. Discarding message.", err)
+				d.Ack(false)
+				continue
+			}
+			if err := processReferenceAudio(t); err != nil {
+				log.Printf("ERROR: %v", err)
+				DB.Model(&model.ReferenceAudio{}).Where("id = ?", t.ReferenceAudioID).Updates(map[string]interface{}{
+					"status": model.ReferenceAudioFailed,
+					"error":  err.Error(),
+				})
+			}
+			d.Ack(false)
+		}
+	}()
+
 	log.Println("Worker is waiting for messages. To exit press CTRL+C")
 	<-forever
 }
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/shuind/language-learner/backend/internal/model"
+	"github.com/shuind/language-learner/backend/internal/task"
+	"github.com/shuind/language-learner/backend/internal/testdb"
+	"github.com/shuind/language-learner/backend/internal/tts"
+)
+
+func TestProcessReferenceAudioReusesCachedSynthesis(t *testing.T) {
+	DB = testdb.Open(t, &model.ReferenceAudio{})
+	fake := &tts.Fake{}
+	synthesizer = fake
+
+	const text = "Good morning."
+	hash := tts.CacheKey(text, "en", "")
+	cached := model.ReferenceAudio{
+		NodeKind: model.RevisionKindNode, NodeID: 1, SegmentIndex: model.ReferenceAudioWholeNode, Source: model.ReferenceAudioSourceTTS,
+		ContentHash: hash, Text: text, Language: "en", Status: model.ReferenceAudioCompleted,
+		AudioURL: "http://minio/recordings/tts/" + hash + ".wav", ObjectName: "tts/" + hash + ".wav",
+		ContentType: "audio/wav", DurationMs: 1300, RequestedBy: 1,
+	}
+	pending := model.ReferenceAudio{
+		NodeKind: model.RevisionKindDomainNode, NodeID: 9, SegmentIndex: model.ReferenceAudioWholeNode, Source: model.ReferenceAudioSourceTTS,
+		ContentHash: hash, Text: text, Language: "en", Status: model.ReferenceAudioPending, RequestedBy: 2,
+	}
+	if err := DB.Create(&cached).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := DB.Create(&pending).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := processReferenceAudio(task.ReferenceAudioTask{ReferenceAudioID: pending.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(fake.Requests()); n != 0 {
+		t.Fatalf("synthesized %d times, want a cache hit", n)
+	}
+	var got model.ReferenceAudio
+	DB.First(&got, pending.ID)
+	if got.Status != model.ReferenceAudioCompleted || got.ObjectName != cached.ObjectName || got.DurationMs != cached.DurationMs {
+		t.Fatalf("pending audio after processing = %+v, want the cached object", got)
+	}
+}
+
+func TestProcessReferenceAudioSkipsInvalidatedRecords(t *testing.T) {
+	DB = testdb.Open(t, &model.ReferenceAudio{})
+	fake := &tts.Fake{}
+	synthesizer = fake
+
+	// 记录已因节点内容修改被删除
+	if err := processReferenceAudio(task.ReferenceAudioTask{ReferenceAudioID: 42}); err != nil {
+		t.Fatalf("err = %v, want nil so the task is acknowledged", err)
+	}
+	if n := len(fake.Requests()); n != 0 {
+		t.Fatalf("synthesized %d times for a deleted record", n)
+	}
+}
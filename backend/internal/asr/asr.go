@@ -0,0 +1,85 @@
+// Package asr 封装语音识别服务。
+package asr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Transcriber 把一段录音转写为文字
+// language 是朗读内容的 BCP 47 语言标签，为空时由识别服务自动检测。
+type Transcriber interface {
+	Transcribe(ctx context.Context, audio []byte, language string) (string, error)
+}
+
+// FromEnv 返回调用 ASR_API_URL 指向的识别服务的客户端
+func FromEnv() Transcriber {
+	url := os.Getenv("ASR_API_URL")
+	if url == "" {
+		//【建议】使用 host.docker.internal 可能不稳定，可以直接指向宿主机 IP 或另一个服务名
+		url = "http://host.docker.internal:8000/transcribe"
+	}
+	return NewHTTPTranscriber(url)
+}
+
+// HTTPTranscriber 调用以 multipart 表单接收音频的识别服务：
+// 字段 file 为音频，可选字段 language 为语言提示，返回 {"text": "..."}
+type HTTPTranscriber struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewHTTPTranscriber 创建一个 HTTP 识别客户端，模型处理可能较慢，超时设为 90 秒
+func NewHTTPTranscriber(url string) *HTTPTranscriber {
+	return &HTTPTranscriber{URL: url, Client: &http.Client{Timeout: 90 * time.Second}}
+}
+
+func (t *HTTPTranscriber) Transcribe(ctx context.Context, audio []byte, language string) (string, error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", "audio.webm")
+	if err != nil {
+		return "", fmt.Errorf("failed to create form file: %w", err)
+	}
+	if _, err := io.Copy(part, bytes.NewReader(audio)); err != nil {
+		return "", fmt.Errorf("failed to copy file content to form: %w", err)
+	}
+	if language != "" {
+		if err := writer.WriteField("language", language); err != nil {
+			return "", fmt.Errorf("failed to write language field: %w", err)
+		}
+	}
+	writer.Close()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.URL, body)
+	if err != nil {
+		return "", fmt.Errorf("failed to create http request: %w", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call AI service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("AI service returned an error. Status: %d, Body: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var result struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode AI service response: %w", err)
+	}
+	return result.Text, nil
+}
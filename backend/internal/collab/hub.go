@@ -0,0 +1,405 @@
+package collab
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// MaxDocumentRunes 限制协同文档的长度
+	MaxDocumentRunes = 200000
+	// maxHistory 是服务器为落后的客户端保留的历史操作数，更早的修订号需要重新同步
+	maxHistory = 1000
+	// sendBuffer 是每个连接的待发送消息数，写不过来的连接会被断开
+	sendBuffer = 256
+)
+
+var (
+	// ErrStaleRevision 表示客户端的修订号过旧或不存在，需要重新连接以同步全文
+	ErrStaleRevision = errors.New("revision is no longer available, please resync")
+	// ErrDocumentTooLarge 表示应用操作后文档超出长度限制
+	ErrDocumentTooLarge = errors.New("document is too large")
+	// ErrNotJoined 表示连接不在该文档的会话中
+	ErrNotJoined = errors.New("client has not joined this document")
+	// ErrSnapshotConflict 由 SaveFunc 返回，表示节点在会话之外被修改过，快照没有写入
+	ErrSnapshotConflict = errors.New("document was modified outside the collaborative session")
+)
+
+// 消息类型
+const (
+	MsgInit     = "init"     // 服务器 -> 客户端：加入时的全文与在线成员
+	MsgOp       = "op"       // 双向：编辑操作
+	MsgAck      = "ack"      // 服务器 -> 客户端：自己的操作已被接受
+	MsgCursor   = "cursor"   // 双向：光标或选区变化
+	MsgPresence = "presence" // 服务器 -> 客户端：在线成员变化
+	MsgError    = "error"    // 服务器 -> 客户端：操作被拒绝
+)
+
+// Cursor 是光标或选区，Anchor == Head 时为光标
+type Cursor struct {
+	Anchor int `json:"anchor"`
+	Head   int `json:"head"`
+}
+
+// Presence 是一位正在编辑的成员
+type Presence struct {
+	ClientID string  `json:"client_id"`
+	UserID   uint    `json:"user_id"`
+	Username string  `json:"username"`
+	Cursor   *Cursor `json:"cursor,omitempty"`
+}
+
+// Message 是 WebSocket 上传输的消息。
+// 客户端提交操作或光标时，Rev 是该操作所基于的服务器修订号；
+// 服务器下发时，Rev 是应用该操作之后的修订号。
+type Message struct {
+	Type     string     `json:"type"`
+	Rev      int        `json:"rev"`
+	Op       Operation  `json:"op,omitempty"`
+	Content  string     `json:"content,omitempty"`
+	ClientID string     `json:"client_id,omitempty"`
+	UserID   uint       `json:"user_id,omitempty"`
+	Cursor   *Cursor    `json:"cursor,omitempty"`
+	Presence []Presence `json:"presence,omitempty"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// Client 是一个编辑连接，同一用户可以打开多个连接
+type Client struct {
+	ID       string
+	UserID   uint
+	Username string
+	cursor   *Cursor
+	send     chan Message
+}
+
+// Messages 返回待发送给该连接的消息，连接被移出会话时通道关闭
+func (c *Client) Messages() <-chan Message {
+	return c.send
+}
+
+// LoadFunc 读取节点当前保存的内容及其版本号，用于开启会话
+type LoadFunc func(nodeID uint) (content string, version int, err error)
+
+// SaveFunc 把文档快照写回节点，仅当节点仍是 version 时写入，返回写入后的版本号；
+// 节点已被其他途径修改时返回 ErrSnapshotConflict。authorID 是快照前最后一位编辑者
+type SaveFunc func(nodeID uint, content string, authorID uint, version int) (int, error)
+
+// session 是一篇文档的协同会话，所有连接离开后销毁
+type session struct {
+	mu      sync.Mutex
+	doc     []rune
+	rev     int         // 当前修订号
+	base    int         // history[0] 对应的修订号
+	history []Operation // history[i] 把修订号 base+i 变为 base+i+1
+	clients map[string]*Client
+	author  uint // 最后一位编辑者
+	// closing 表示最后一个连接已离开、正在保存最终快照；仅在持有 Hub.mu 时访问。
+	// 保存期间会话仍留在 Hub 中，新连接加入时直接复用内存中的文档
+	closing bool
+
+	saveMu   sync.Mutex // 保证快照按修订顺序写入
+	savedRev int        // 已写回数据库的修订号，仅在持有 saveMu 时访问
+	version  int        // 节点在数据库中的版本号，快照以此为条件写入；仅在持有 saveMu 时访问
+}
+
+// Hub 管理所有正在协同编辑的文档
+type Hub struct {
+	mu       sync.Mutex
+	sessions map[uint]*session
+	load     LoadFunc
+	save     SaveFunc
+	nextID   atomic.Uint64
+}
+
+func NewHub(load LoadFunc, save SaveFunc) *Hub {
+	return &Hub{sessions: make(map[uint]*session), load: load, save: save}
+}
+
+// NewClient 创建一个连接，ID 在进程内唯一
+func (h *Hub) NewClient(userID uint, username string) *Client {
+	id := strconv.FormatUint(h.nextID.Add(1), 10)
+	return &Client{ID: id, UserID: userID, Username: username, send: make(chan Message, sendBuffer)}
+}
+
+// Join 把连接加入文档的会话（必要时从数据库载入），并向其发送全文
+func (h *Hub) Join(nodeID uint, c *Client) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.sessions[nodeID]
+	if !ok {
+		content, version, err := h.load(nodeID)
+		if err != nil {
+			return err
+		}
+		s = &session{doc: []rune(content), clients: make(map[string]*Client), version: version}
+		h.sessions[nodeID] = s
+	}
+	s.closing = false
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// init 先于其他消息进入通道，客户端收到的第一条消息一定是全文
+	c.send <- Message{Type: MsgInit, Rev: s.rev, Content: string(s.doc), ClientID: c.ID, Presence: s.presenceLocked()}
+	s.clients[c.ID] = c
+	s.broadcastLocked(Message{Type: MsgPresence, Rev: s.rev, Presence: s.presenceLocked()}, c.ID)
+	return nil
+}
+
+// Leave 把连接移出会话；最后一个连接离开时立即保存快照并销毁会话。
+// 快照在释放 h.mu 之后写入，保存期间其他文档的 Join / Active 不会被阻塞。
+// 快照保存失败时保留会话（节点仍视为正在编辑），由 Run 定期重试，成功后再销毁
+func (h *Hub) Leave(nodeID uint, c *Client) {
+	h.mu.Lock()
+	s, ok := h.sessions[nodeID]
+	if !ok {
+		h.mu.Unlock()
+		return
+	}
+	s.mu.Lock()
+	if _, joined := s.clients[c.ID]; joined {
+		s.removeLocked(c)
+	}
+	empty := len(s.clients) == 0
+	if empty {
+		s.closing = true
+	} else {
+		s.broadcastLocked(Message{Type: MsgPresence, Rev: s.rev, Presence: s.presenceLocked()}, "")
+	}
+	s.mu.Unlock()
+	h.mu.Unlock()
+	if !empty {
+		return
+	}
+
+	if h.snapshot(nodeID, s, true) {
+		h.remove(nodeID, s)
+	}
+}
+
+// remove 销毁已保存的会话；保存期间有新连接加入时 Join 已清除 closing，会话继续使用
+func (h *Hub) remove(nodeID uint, s *session) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closing && len(s.clients) == 0 && h.sessions[nodeID] == s {
+		delete(h.sessions, nodeID)
+	}
+}
+
+// Submit 接收客户端基于修订号 rev 的操作：先对其之后的历史操作做变换，再应用并广播。
+// 操作被拒绝时会向该连接发送 error 消息，并返回原因。
+func (h *Hub) Submit(nodeID uint, c *Client, rev int, op Operation) error {
+	s := h.session(nodeID)
+	if s == nil {
+		return ErrNotJoined
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, joined := s.clients[c.ID]; !joined {
+		return ErrNotJoined
+	}
+	doc, err := s.applyLocked(rev, &op)
+	if err != nil {
+		s.sendLocked(c, Message{Type: MsgError, Rev: s.rev, Error: err.Error()})
+		return err
+	}
+	if len(doc) > MaxDocumentRunes {
+		s.sendLocked(c, Message{Type: MsgError, Rev: s.rev, Error: ErrDocumentTooLarge.Error()})
+		return ErrDocumentTooLarge
+	}
+
+	s.doc = doc
+	s.history = append(s.history, op)
+	s.rev++
+	s.author = c.UserID
+	if len(s.history) > maxHistory {
+		drop := len(s.history) - maxHistory
+		s.history = append([]Operation(nil), s.history[drop:]...)
+		s.base += drop
+	}
+	// 其他人的光标随文档一起移动
+	for _, other := range s.clients {
+		if other.cursor != nil {
+			other.cursor = &Cursor{Anchor: op.TransformIndex(other.cursor.Anchor), Head: op.TransformIndex(other.cursor.Head)}
+		}
+	}
+
+	s.sendLocked(c, Message{Type: MsgAck, Rev: s.rev})
+	s.broadcastLocked(Message{Type: MsgOp, Rev: s.rev, Op: op, ClientID: c.ID, UserID: c.UserID}, c.ID)
+	return nil
+}
+
+// MoveCursor 更新连接基于修订号 rev 的光标位置，并通知其他人
+func (h *Hub) MoveCursor(nodeID uint, c *Client, rev int, cursor Cursor) error {
+	s := h.session(nodeID)
+	if s == nil {
+		return ErrNotJoined
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, joined := s.clients[c.ID]; !joined {
+		return ErrNotJoined
+	}
+	if rev < s.base || rev > s.rev {
+		return ErrStaleRevision
+	}
+	for _, op := range s.history[rev-s.base:] {
+		cursor = Cursor{Anchor: op.TransformIndex(cursor.Anchor), Head: op.TransformIndex(cursor.Head)}
+	}
+	cursor.Anchor = clamp(cursor.Anchor, len(s.doc))
+	cursor.Head = clamp(cursor.Head, len(s.doc))
+	c.cursor = &cursor
+	s.broadcastLocked(Message{Type: MsgCursor, Rev: s.rev, ClientID: c.ID, UserID: c.UserID, Cursor: &cursor}, c.ID)
+	return nil
+}
+
+// Active 判断节点是否正在被协同编辑
+func (h *Hub) Active(nodeID uint) bool {
+	return h.session(nodeID) != nil
+}
+
+// Presence 返回正在编辑该节点的成员
+func (h *Hub) Presence(nodeID uint) []Presence {
+	s := h.session(nodeID)
+	if s == nil {
+		return []Presence{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.presenceLocked()
+}
+
+// Run 每隔 interval 保存一次所有有改动的文档，应在单独的 goroutine 中运行
+func (h *Hub) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		h.Flush()
+	}
+}
+
+// Flush 立即保存所有有改动的文档，并销毁之前最终快照保存失败、现已保存的会话
+func (h *Hub) Flush() {
+	h.mu.Lock()
+	sessions := make(map[uint]*session, len(h.sessions))
+	closing := make(map[uint]bool, len(h.sessions))
+	for id, s := range h.sessions {
+		sessions[id] = s
+		closing[id] = s.closing
+	}
+	h.mu.Unlock()
+
+	for id, s := range sessions {
+		if h.snapshot(id, s, closing[id]) && closing[id] {
+			h.remove(id, s)
+		}
+	}
+}
+
+func (h *Hub) session(nodeID uint) *session {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.sessions[nodeID]
+}
+
+// snapshot 在文档自上次保存后有改动时写回数据库，返回会话是否可以销毁。
+// final 表示已没有在线的编辑者：此时遇到冲突以节点的最新版本重试，
+// 会话之外的修改仍保留在修订历史中；节点已不存在时放弃保存
+func (h *Hub) snapshot(nodeID uint, s *session, final bool) bool {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	s.mu.Lock()
+	rev, content, author := s.rev, string(s.doc), s.author
+	s.mu.Unlock()
+	if rev == s.savedRev {
+		return true
+	}
+	version, err := h.save(nodeID, content, author, s.version)
+	if errors.Is(err, ErrSnapshotConflict) && final {
+		_, current, loadErr := h.load(nodeID)
+		if loadErr != nil {
+			log.Printf("ERROR discarding collaborative snapshot for domain node %d: %v", nodeID, loadErr)
+			return true
+		}
+		version, err = h.save(nodeID, content, author, current)
+	}
+	if err != nil {
+		log.Printf("ERROR saving collaborative snapshot for domain node %d: %v", nodeID, err)
+		if errors.Is(err, ErrSnapshotConflict) {
+			// 不覆盖会话之外的修改，提示在线的编辑者
+			s.mu.Lock()
+			s.broadcastLocked(Message{Type: MsgError, Rev: s.rev, Error: err.Error()}, "")
+			s.mu.Unlock()
+		}
+		return false
+	}
+	s.savedRev, s.version = rev, version
+	return true
+}
+
+// applyLocked 把基于 rev 的操作变换到当前修订并应用，op 被替换为变换后的操作
+func (s *session) applyLocked(rev int, op *Operation) ([]rune, error) {
+	if rev < s.base || rev > s.rev {
+		return nil, ErrStaleRevision
+	}
+	for _, past := range s.history[rev-s.base:] {
+		transformed, _, err := Transform(*op, past)
+		if err != nil {
+			return nil, err
+		}
+		*op = transformed
+	}
+	return op.Apply(s.doc)
+}
+
+func (s *session) presenceLocked() []Presence {
+	list := make([]Presence, 0, len(s.clients))
+	for _, c := range s.clients {
+		list = append(list, Presence{ClientID: c.ID, UserID: c.UserID, Username: c.Username, Cursor: c.cursor})
+	}
+	return list
+}
+
+// broadcastLocked 向除 exceptID 以外的所有连接发送消息
+func (s *session) broadcastLocked(msg Message, exceptID string) {
+	for id, c := range s.clients {
+		if id != exceptID {
+			s.sendLocked(c, msg)
+		}
+	}
+}
+
+// sendLocked 非阻塞地发送消息；通道已满说明连接跟不上，直接移出会话
+func (s *session) sendLocked(c *Client, msg Message) {
+	select {
+	case c.send <- msg:
+	default:
+		s.removeLocked(c)
+	}
+}
+
+// removeLocked 移出连接并关闭其通道，写协程随之退出并关闭 WebSocket
+func (s *session) removeLocked(c *Client) {
+	delete(s.clients, c.ID)
+	close(c.send)
+}
+
+func clamp(v, n int) int {
+	if v < 0 {
+		return 0
+	}
+	if v > n {
+		return n
+	}
+	return v
+}
@@ -0,0 +1,181 @@
+package collab
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type savedSnapshot struct {
+	content string
+	author  uint
+	version int
+}
+
+func TestHubSnapshotUsesLoadedVersion(t *testing.T) {
+	var saved []savedSnapshot
+	h := NewHub(
+		func(nodeID uint) (string, int, error) { return "hello", 7, nil },
+		func(nodeID uint, content string, authorID uint, version int) (int, error) {
+			saved = append(saved, savedSnapshot{content, authorID, version})
+			return version + 1, nil
+		},
+	)
+	c := h.NewClient(1, "alice")
+	if err := h.Join(1, c); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Submit(1, c, 0, op(5, " world")); err != nil {
+		t.Fatal(err)
+	}
+	h.Flush()
+	if err := h.Submit(1, c, 1, op(11, "!")); err != nil {
+		t.Fatal(err)
+	}
+	h.Leave(1, c)
+
+	want := []savedSnapshot{{"hello world", 1, 7}, {"hello world!", 1, 8}}
+	if len(saved) != len(want) {
+		t.Fatalf("saved %v, want %v", saved, want)
+	}
+	for i := range want {
+		if saved[i] != want[i] {
+			t.Fatalf("save %d = %+v, want %+v", i, saved[i], want[i])
+		}
+	}
+	if h.Active(1) {
+		t.Fatal("session should be closed after the last client leaves")
+	}
+}
+
+func TestHubSnapshotConflictNotifiesEditors(t *testing.T) {
+	h := NewHub(
+		func(nodeID uint) (string, int, error) { return "hello", 1, nil },
+		func(nodeID uint, content string, authorID uint, version int) (int, error) {
+			return 0, ErrSnapshotConflict
+		},
+	)
+	c := h.NewClient(1, "alice")
+	if err := h.Join(1, c); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Submit(1, c, 0, op(-5)); err != nil {
+		t.Fatal(err)
+	}
+	h.Flush()
+
+	for {
+		select {
+		case msg := <-c.Messages():
+			if msg.Type == MsgError {
+				if msg.Error != ErrSnapshotConflict.Error() {
+					t.Fatalf("error = %q", msg.Error)
+				}
+				return
+			}
+		default:
+			t.Fatal("editors were not told about the conflicting snapshot")
+		}
+	}
+}
+
+func TestHubLeaveSavesWithoutHoldingHubLock(t *testing.T) {
+	var h *Hub
+	h = NewHub(
+		func(nodeID uint) (string, int, error) { return "", 1, nil },
+		func(nodeID uint, content string, authorID uint, version int) (int, error) {
+			// 保存期间其他文档仍可访问 Hub
+			h.Active(2)
+			return version + 1, nil
+		},
+	)
+	c := h.NewClient(1, "alice")
+	if err := h.Join(1, c); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Submit(1, c, 0, op("x")); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		h.Leave(1, c)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Leave blocked while saving the final snapshot")
+	}
+}
+
+func TestHubFinalSnapshotRetriesWithCurrentVersion(t *testing.T) {
+	var saved []savedSnapshot
+	version := 1
+	h := NewHub(
+		func(nodeID uint) (string, int, error) { return "hello", version, nil },
+		func(nodeID uint, content string, authorID uint, v int) (int, error) {
+			if v != version {
+				return 0, ErrSnapshotConflict
+			}
+			saved = append(saved, savedSnapshot{content, authorID, v})
+			version++
+			return version, nil
+		},
+	)
+	c := h.NewClient(1, "alice")
+	if err := h.Join(1, c); err != nil {
+		t.Fatal(err)
+	}
+	// 会话之外修改了节点的标题
+	version = 2
+	if err := h.Submit(1, c, 0, op(5, "!")); err != nil {
+		t.Fatal(err)
+	}
+	h.Flush()
+	if len(saved) != 0 {
+		t.Fatalf("saved %v while editors were online, want a conflict", saved)
+	}
+	h.Leave(1, c)
+	if len(saved) != 1 || saved[0] != (savedSnapshot{"hello!", 1, 2}) {
+		t.Fatalf("saved %v, want the final snapshot on version 2", saved)
+	}
+	if h.Active(1) {
+		t.Fatal("session should be closed after the final snapshot is saved")
+	}
+}
+
+func TestHubKeepsSessionWhenFinalSnapshotFails(t *testing.T) {
+	fail := true
+	var saved []string
+	h := NewHub(
+		func(nodeID uint) (string, int, error) { return "hello", 1, nil },
+		func(nodeID uint, content string, authorID uint, version int) (int, error) {
+			if fail {
+				return 0, errors.New("database is unavailable")
+			}
+			saved = append(saved, content)
+			return version + 1, nil
+		},
+	)
+	c := h.NewClient(1, "alice")
+	if err := h.Join(1, c); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Submit(1, c, 0, op(5, "!")); err != nil {
+		t.Fatal(err)
+	}
+	h.Leave(1, c)
+	if !h.Active(1) {
+		t.Fatal("session with an unsaved snapshot was discarded")
+	}
+
+	fail = false
+	h.Flush()
+	if len(saved) != 1 || saved[0] != "hello!" {
+		t.Fatalf("saved %q, want the retried snapshot", saved)
+	}
+	if h.Active(1) {
+		t.Fatal("session should be closed once the retried snapshot is saved")
+	}
+}
@@ -0,0 +1,268 @@
+// Package collab 实现圈子节点的多人实时协同编辑：
+// 基于操作变换（OT）的纯文本编辑、在线成员与光标、以及定期把文档快照写回数据库。
+//
+// 操作的 JSON 格式与 ot.js 相同：正整数表示保留，字符串表示插入，负整数表示删除，
+// 例如 [3, "abc", -2] 表示“保留 3 个字符，插入 abc，删除 2 个字符”。
+// 与 ot.js 不同的是，所有长度都按 Unicode 码点（rune）计算，而不是 UTF-16 代码单元。
+package collab
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+var (
+	// ErrBaseLength 表示操作的基准长度与文档长度不一致
+	ErrBaseLength = errors.New("operation base length does not match document length")
+	// ErrIncompatible 表示两个操作不是基于同一文档，无法变换
+	ErrIncompatible = errors.New("operations are not based on the same document")
+)
+
+// Component 是操作中的一步，三个字段中只有一个有效
+type Component struct {
+	Retain int    // 保留的字符数
+	Insert string // 插入的文本
+	Delete int    // 删除的字符数
+}
+
+func (c Component) isRetain() bool { return c.Retain > 0 }
+func (c Component) isInsert() bool { return c.Insert != "" }
+func (c Component) isDelete() bool { return c.Delete > 0 }
+
+// Operation 是对整篇文档的一次编辑，必须覆盖文档的全部字符
+type Operation []Component
+
+// Retain 追加保留步骤，并与前一个保留步骤合并
+func (o Operation) Retain(n int) Operation {
+	if n <= 0 {
+		return o
+	}
+	if l := len(o); l > 0 && o[l-1].isRetain() {
+		o[l-1].Retain += n
+		return o
+	}
+	return append(o, Component{Retain: n})
+}
+
+// Insert 追加插入步骤；紧跟在删除之后的插入会被放到删除之前，使等价的操作有相同的表示
+func (o Operation) Insert(s string) Operation {
+	if s == "" {
+		return o
+	}
+	l := len(o)
+	if l > 0 && o[l-1].isInsert() {
+		o[l-1].Insert += s
+		return o
+	}
+	if l > 0 && o[l-1].isDelete() {
+		if l > 1 && o[l-2].isInsert() {
+			o[l-2].Insert += s
+			return o
+		}
+		o = append(o, o[l-1])
+		o[l-1] = Component{Insert: s}
+		return o
+	}
+	return append(o, Component{Insert: s})
+}
+
+// Delete 追加删除步骤，并与前一个删除步骤合并
+func (o Operation) Delete(n int) Operation {
+	if n <= 0 {
+		return o
+	}
+	if l := len(o); l > 0 && o[l-1].isDelete() {
+		o[l-1].Delete += n
+		return o
+	}
+	return append(o, Component{Delete: n})
+}
+
+// BaseLen 是操作要求的原文档长度
+func (o Operation) BaseLen() int {
+	n := 0
+	for _, c := range o {
+		n += c.Retain + c.Delete
+	}
+	return n
+}
+
+// TargetLen 是应用操作之后的文档长度
+func (o Operation) TargetLen() int {
+	n := 0
+	for _, c := range o {
+		n += c.Retain + utf8.RuneCountInString(c.Insert)
+	}
+	return n
+}
+
+// IsNoop 判断操作是否不改变文档
+func (o Operation) IsNoop() bool {
+	return len(o) == 0 || (len(o) == 1 && o[0].isRetain())
+}
+
+// Apply 把操作应用到文档上，返回新文档
+func (o Operation) Apply(doc []rune) ([]rune, error) {
+	if o.BaseLen() != len(doc) {
+		return nil, ErrBaseLength
+	}
+	out := make([]rune, 0, o.TargetLen())
+	pos := 0
+	for _, c := range o {
+		switch {
+		case c.isRetain():
+			out = append(out, doc[pos:pos+c.Retain]...)
+			pos += c.Retain
+		case c.isInsert():
+			out = append(out, []rune(c.Insert)...)
+		case c.isDelete():
+			pos += c.Delete
+		}
+	}
+	return out, nil
+}
+
+// TransformIndex 把操作前文档中的位置映射到操作后的文档中，用于移动其他人的光标
+func (o Operation) TransformIndex(index int) int {
+	pos, result := 0, index
+	for _, c := range o {
+		if pos > index {
+			break
+		}
+		switch {
+		case c.isRetain():
+			pos += c.Retain
+		case c.isInsert():
+			result += utf8.RuneCountInString(c.Insert)
+		case c.isDelete():
+			if index-pos < c.Delete {
+				result -= index - pos
+			} else {
+				result -= c.Delete
+			}
+			pos += c.Delete
+		}
+	}
+	return result
+}
+
+// Transform 把基于同一文档的并发操作 a、b 变换为 a'、b'，
+// 使 apply(apply(doc, a), b') == apply(apply(doc, b), a')。
+// 两边在同一位置插入时，a 的插入排在前面。
+func Transform(a, b Operation) (Operation, Operation, error) {
+	if a.BaseLen() != b.BaseLen() {
+		return nil, nil, ErrIncompatible
+	}
+	var a2, b2 Operation
+	i, j := 0, 0
+	var ca, cb *Component
+	next := func(ops Operation, k *int) *Component {
+		if *k >= len(ops) {
+			return nil
+		}
+		c := ops[*k]
+		*k++
+		return &c
+	}
+	ca, cb = next(a, &i), next(b, &j)
+
+	for ca != nil || cb != nil {
+		// 插入不消耗原文档字符，直接放入结果
+		if ca != nil && ca.isInsert() {
+			a2 = a2.Insert(ca.Insert)
+			b2 = b2.Retain(utf8.RuneCountInString(ca.Insert))
+			ca = next(a, &i)
+			continue
+		}
+		if cb != nil && cb.isInsert() {
+			a2 = a2.Retain(utf8.RuneCountInString(cb.Insert))
+			b2 = b2.Insert(cb.Insert)
+			cb = next(b, &j)
+			continue
+		}
+		if ca == nil || cb == nil {
+			return nil, nil, ErrIncompatible
+		}
+
+		n := min(ca.Retain+ca.Delete, cb.Retain+cb.Delete)
+		switch {
+		case ca.isRetain() && cb.isRetain():
+			a2, b2 = a2.Retain(n), b2.Retain(n)
+		case ca.isDelete() && cb.isRetain():
+			a2 = a2.Delete(n)
+		case ca.isRetain() && cb.isDelete():
+			b2 = b2.Delete(n)
+		}
+		// 双方都删除的部分在结果中不再出现
+
+		ca = consume(ca, n)
+		if ca == nil {
+			ca = next(a, &i)
+		}
+		cb = consume(cb, n)
+		if cb == nil {
+			cb = next(b, &j)
+		}
+	}
+	return a2, b2, nil
+}
+
+// consume 从保留或删除步骤中扣除 n 个字符，用完时返回 nil
+func consume(c *Component, n int) *Component {
+	if c.isRetain() {
+		c.Retain -= n
+	} else {
+		c.Delete -= n
+	}
+	if c.Retain == 0 && c.Delete == 0 {
+		return nil
+	}
+	return c
+}
+
+// MarshalJSON 输出 ot.js 格式的数组
+func (o Operation) MarshalJSON() ([]byte, error) {
+	items := make([]interface{}, len(o))
+	for i, c := range o {
+		switch {
+		case c.isRetain():
+			items[i] = c.Retain
+		case c.isInsert():
+			items[i] = c.Insert
+		default:
+			items[i] = -c.Delete
+		}
+	}
+	return json.Marshal(items)
+}
+
+// UnmarshalJSON 解析 ot.js 格式的数组，并合并相邻的同类步骤
+func (o *Operation) UnmarshalJSON(data []byte) error {
+	var items []interface{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	var op Operation
+	for _, item := range items {
+		switch v := item.(type) {
+		case string:
+			op = op.Insert(v)
+		case float64:
+			n := int(v)
+			if float64(n) != v || n == 0 {
+				return fmt.Errorf("invalid operation component: %v", v)
+			}
+			if n > 0 {
+				op = op.Retain(n)
+			} else {
+				op = op.Delete(-n)
+			}
+		default:
+			return fmt.Errorf("invalid operation component: %v", item)
+		}
+	}
+	*o = op
+	return nil
+}
@@ -0,0 +1,171 @@
+package collab
+
+import (
+	"math/rand"
+	"testing"
+	"unicode/utf8"
+)
+
+// op 用 ot.js 的写法构造操作：正整数保留，字符串插入，负整数删除
+func op(items ...interface{}) Operation {
+	var o Operation
+	for _, item := range items {
+		switch v := item.(type) {
+		case int:
+			if v > 0 {
+				o = o.Retain(v)
+			} else {
+				o = o.Delete(-v)
+			}
+		case string:
+			o = o.Insert(v)
+		}
+	}
+	return o
+}
+
+// assertConverges 检查 apply(apply(doc, a), b') == apply(apply(doc, b), a')，并返回收敛后的文档
+func assertConverges(t *testing.T, doc string, a, b Operation) string {
+	t.Helper()
+	a2, b2, err := Transform(a, b)
+	if err != nil {
+		t.Fatalf("Transform(%v, %v): %v", a, b, err)
+	}
+	da, err := a.Apply([]rune(doc))
+	if err != nil {
+		t.Fatalf("apply a: %v", err)
+	}
+	left, err := b2.Apply(da)
+	if err != nil {
+		t.Fatalf("apply b': %v", err)
+	}
+	db, err := b.Apply([]rune(doc))
+	if err != nil {
+		t.Fatalf("apply b: %v", err)
+	}
+	right, err := a2.Apply(db)
+	if err != nil {
+		t.Fatalf("apply a': %v", err)
+	}
+	if string(left) != string(right) {
+		t.Fatalf("diverged on %q: a then b' = %q, b then a' = %q", doc, string(left), string(right))
+	}
+	return string(left)
+}
+
+func TestTransformConcurrentPairs(t *testing.T) {
+	const doc = "abcdef"
+	tests := []struct {
+		name string
+		a, b Operation
+		want string
+	}{
+		{"insert vs insert at same position, a first", op(2, "X", 4), op(2, "Y", 4), "abXYcdef"},
+		{"insert vs insert at different positions", op(1, "X", 5), op(4, "Y", 2), "aXbcdYef"},
+		{"insert at start vs insert at end", op("X", 6), op(6, "Y"), "XabcdefY"},
+		{"insert inside a deleted range", op(3, "X", 3), op(1, -4, 1), "aXf"},
+		{"delete vs insert inside the deleted range", op(1, -4, 1), op(3, "X", 3), "aXf"},
+		{"insert before a delete", op(1, "X", 5), op(3, -2, 1), "aXbcf"},
+		{"overlapping deletes", op(1, -3, 2), op(2, -3, 1), "af"},
+		{"identical deletes", op(2, -2, 2), op(2, -2, 2), "abef"},
+		{"disjoint deletes", op(-2, 4), op(4, -2), "cd"},
+		{"delete everything vs insert", op(-6), op(3, "X", 3), "X"},
+		{"replace vs replace same range", op(1, "X", -2, 3), op(1, "Y", -2, 3), "aXYdef"},
+		{"noop vs delete", op(6), op(-1, 5), "bcdef"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := assertConverges(t, doc, tt.a, tt.b); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransformCountsRunes(t *testing.T) {
+	// 长度按码点计算：中文与 emoji 都算一个字符
+	doc := "你好😀世界"
+	got := assertConverges(t, doc, op(2, "，", 3), op(3, -1, 1))
+	if want := "你好，😀界"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestTransformIncompatible(t *testing.T) {
+	if _, _, err := Transform(op(3), op(4)); err != ErrIncompatible {
+		t.Fatalf("err = %v, want ErrIncompatible", err)
+	}
+}
+
+func TestTransformRandomConverges(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		doc := randomText(rng, rng.Intn(12))
+		a, b := randomOp(rng, doc), randomOp(rng, doc)
+		assertConverges(t, doc, a, b)
+	}
+}
+
+func TestTransformIndex(t *testing.T) {
+	o := op(2, "XY", -2, 2) // "abcdef" -> "abXYef"
+	tests := []struct{ in, want int }{{0, 0}, {2, 4}, {3, 4}, {4, 4}, {6, 6}}
+	for _, tt := range tests {
+		if got := o.TransformIndex(tt.in); got != tt.want {
+			t.Errorf("TransformIndex(%d) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestOperationJSONRoundTrip(t *testing.T) {
+	o := op(3, "héllo", -2, 1)
+	data, err := o.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `[3,"héllo",-2,1]` {
+		t.Fatalf("MarshalJSON = %s", data)
+	}
+	var back Operation
+	if err := back.UnmarshalJSON(data); err != nil {
+		t.Fatal(err)
+	}
+	if len(back) != len(o) || back.BaseLen() != o.BaseLen() || back.TargetLen() != o.TargetLen() {
+		t.Fatalf("round trip = %v, want %v", back, o)
+	}
+	if err := back.UnmarshalJSON([]byte(`[0]`)); err == nil {
+		t.Fatal("zero component should be rejected")
+	}
+}
+
+func randomText(rng *rand.Rand, n int) string {
+	const alphabet = "abc你😀"
+	runes := []rune(alphabet)
+	out := make([]rune, n)
+	for i := range out {
+		out[i] = runes[rng.Intn(len(runes))]
+	}
+	return string(out)
+}
+
+// randomOp 生成覆盖整篇 doc 的随机操作
+func randomOp(rng *rand.Rand, doc string) Operation {
+	var o Operation
+	remaining := utf8.RuneCountInString(doc)
+	for remaining > 0 {
+		n := 1 + rng.Intn(remaining)
+		switch rng.Intn(3) {
+		case 0:
+			o = o.Retain(n)
+			remaining -= n
+		case 1:
+			o = o.Delete(n)
+			remaining -= n
+		default:
+			o = o.Insert(randomText(rng, 1+rng.Intn(3)))
+		}
+	}
+	if rng.Intn(2) == 0 {
+		o = o.Insert(randomText(rng, 1+rng.Intn(3)))
+	}
+	return o
+}
@@ -0,0 +1,368 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/shuind/language-learner/backend/internal/model"
+	"github.com/shuind/language-learner/backend/internal/textdiff"
+)
+
+// AnnotationHandler 处理节点内容上的文字标注
+type AnnotationHandler struct {
+	DB *gorm.DB
+}
+
+func NewAnnotationHandler(db *gorm.DB) *AnnotationHandler {
+	return &AnnotationHandler{DB: db}
+}
+
+// errAnchorLost 表示客户端基于旧修订选中的文字在最新内容中已不存在
+var errAnchorLost = errors.New("annotated text no longer exists in the latest content")
+
+type CreateAnnotationInput struct {
+	Start      *int   `json:"start" binding:"required,min=0"`
+	End        *int   `json:"end" binding:"required,min=1"`
+	Revision   *int   `json:"revision"` // 选中文字时看到的修订版本号，为空表示最新版本
+	Note       string `json:"note" binding:"max=2000"`
+	Color      string `json:"color" binding:"omitempty,hexcolor"`
+	Visibility string `json:"visibility" binding:"omitempty,oneof=private domain"`
+}
+
+type UpdateAnnotationInput struct {
+	Note       *string `json:"note" binding:"omitempty,max=2000"`
+	Color      *string `json:"color" binding:"omitempty,hexcolor"`
+	Visibility *string `json:"visibility" binding:"omitempty,oneof=private domain"`
+}
+
+// AnnotationResponse 附带标注作者信息
+type AnnotationResponse struct {
+	model.Annotation
+	Author AuthorResponse `json:"author"`
+}
+
+// annotationTarget 是被标注的节点
+type annotationTarget struct {
+	kind     string
+	nodeID   uint
+	domainID *uint
+	baseline uint // 节点尚无修订时，补建基线版本所记的作者
+	title    string
+	content  string
+}
+
+// ---------------------- 个人节点 ----------------------
+
+// ListNodeAnnotations GET /nodes/:id/annotations
+func (h *AnnotationHandler) ListNodeAnnotations(c *gin.Context) {
+	if t, ok := h.nodeTarget(c); ok {
+		h.list(c, t)
+	}
+}
+
+// CreateNodeAnnotation POST /nodes/:id/annotations
+func (h *AnnotationHandler) CreateNodeAnnotation(c *gin.Context) {
+	if t, ok := h.nodeTarget(c); ok {
+		h.create(c, t)
+	}
+}
+
+func (h *AnnotationHandler) nodeTarget(c *gin.Context) (*annotationTarget, bool) {
+	userID := c.MustGet("userID").(uint)
+	var node model.Node
+	if err := h.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&node).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found or permission denied"})
+		return nil, false
+	}
+	if node.NodeType != "text" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot annotate a folder node"})
+		return nil, false
+	}
+	return &annotationTarget{kind: model.RevisionKindNode, nodeID: node.ID, baseline: node.UserID, title: node.Title, content: node.Content}, true
+}
+
+// ---------------------- 圈子节点 (成员可见) ----------------------
+
+// ListDomainNodeAnnotations GET /domain-nodes/:id/annotations
+// 返回自己的标注以及圈子内共享的标注
+func (h *AnnotationHandler) ListDomainNodeAnnotations(c *gin.Context) {
+	if t, ok := h.domainNodeTarget(c); ok {
+		h.list(c, t)
+	}
+}
+
+// CreateDomainNodeAnnotation POST /domain-nodes/:id/annotations
+func (h *AnnotationHandler) CreateDomainNodeAnnotation(c *gin.Context) {
+	if t, ok := h.domainNodeTarget(c); ok {
+		h.create(c, t)
+	}
+}
+
+func (h *AnnotationHandler) domainNodeTarget(c *gin.Context) (*annotationTarget, bool) {
+	userID := c.MustGet("userID").(uint)
+	var node model.DomainNode
+	if err := h.DB.First(&node, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Domain node not found"})
+		return nil, false
+	}
+	if node.NodeType != "text" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot annotate a folder node"})
+		return nil, false
+	}
+	var member model.DomainMember
+	if err := h.DB.Where("domain_id = ? AND user_id = ?", node.DomainID, userID).First(&member).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: you are not a member of this domain"})
+		return nil, false
+	}
+	// 老节点可能还没有修订记录，此时以圈主的名义补建基线版本
+	var domain model.Domain
+	if err := h.DB.Select("id", "owner_id").First(&domain, node.DomainID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Domain not found"})
+		return nil, false
+	}
+	domainID := node.DomainID
+	return &annotationTarget{kind: model.RevisionKindDomainNode, nodeID: node.ID, domainID: &domainID, baseline: domain.OwnerID, title: node.Title, content: node.Content}, true
+}
+
+// ---------------------- 单条标注 ----------------------
+
+// UpdateAnnotation PUT /annotations/:id
+// 只能修改笔记、颜色和可见范围，锚点由内容变化自动维护
+func (h *AnnotationHandler) UpdateAnnotation(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	var input UpdateAnnotationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var a model.Annotation
+	if err := h.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&a).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Annotation not found or permission denied"})
+		return
+	}
+	if input.Note != nil {
+		a.Note = strings.TrimSpace(*input.Note)
+	}
+	if input.Color != nil && *input.Color != "" {
+		a.Color = *input.Color
+	}
+	if input.Visibility != nil {
+		if *input.Visibility == model.AnnotationDomain && a.DomainID == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Only annotations on domain nodes can be shared"})
+			return
+		}
+		a.Visibility = *input.Visibility
+	}
+	if err := h.DB.Save(&a).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update annotation"})
+		return
+	}
+	h.DB.Preload("User").First(&a, a.ID)
+	c.JSON(http.StatusOK, annotationResponse(a))
+}
+
+// DeleteAnnotation DELETE /annotations/:id
+// 作者可以删除自己的标注；圈主和管理员可以删除圈子内共享的标注
+func (h *AnnotationHandler) DeleteAnnotation(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	var a model.Annotation
+	if err := h.DB.First(&a, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Annotation not found"})
+		return
+	}
+	if a.UserID != userID {
+		var member model.DomainMember
+		if a.DomainID == nil || a.Visibility != model.AnnotationDomain ||
+			h.DB.Where("domain_id = ? AND user_id = ? AND role IN ?", *a.DomainID, userID, []string{"owner", "admin"}).First(&member).Error != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+			return
+		}
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		// 引用该标注的评论保留，只是不再指向具体文字
+		if err := tx.Model(&model.DomainNodeComment{}).Where("annotation_id = ?", a.ID).Update("annotation_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&a).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete annotation"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ---------------------- 通用实现 ----------------------
+
+func (h *AnnotationHandler) list(c *gin.Context, t *annotationTarget) {
+	userID := c.MustGet("userID").(uint)
+	query := h.DB.Where("node_kind = ? AND node_id = ?", t.kind, t.nodeID)
+	if t.domainID != nil {
+		query = query.Where("(user_id = ? OR visibility = ?)", userID, model.AnnotationDomain)
+	} else {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var annotations []model.Annotation
+	if err := query.Preload("User").Order("orphaned, start_offset, id").Find(&annotations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list annotations"})
+		return
+	}
+	response := make([]AnnotationResponse, len(annotations))
+	for i, a := range annotations {
+		response[i] = annotationResponse(a)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *AnnotationHandler) create(c *gin.Context, t *annotationTarget) {
+	userID := c.MustGet("userID").(uint)
+	var input CreateAnnotationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	visibility := model.AnnotationPrivate
+	if input.Visibility != "" {
+		visibility = input.Visibility
+	}
+	if visibility == model.AnnotationDomain && t.domainID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only annotations on domain nodes can be shared"})
+		return
+	}
+
+	a := model.Annotation{
+		UserID:     userID,
+		NodeKind:   t.kind,
+		NodeID:     t.nodeID,
+		DomainID:   t.domainID,
+		Note:       strings.TrimSpace(input.Note),
+		Color:      input.Color,
+		Visibility: visibility,
+	}
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		latest, err := RecordRevision(tx, t.kind, t.nodeID, t.baseline, t.title, t.content)
+		if err != nil {
+			return err
+		}
+		start, end := *input.Start, *input.End
+		// 基于旧修订选中的区间先映射到最新修订
+		if input.Revision != nil && *input.Revision != latest.Version {
+			var old model.NodeRevision
+			if err := tx.Where("node_kind = ? AND node_id = ? AND version = ?", t.kind, t.nodeID, *input.Revision).First(&old).Error; err != nil {
+				return err
+			}
+			if end > len([]rune(old.Content)) {
+				return errAnchorRange
+			}
+			var ok bool
+			if start, end, ok = textdiff.NewOffsetMap(old.Content, latest.Content).Range(start, end); !ok {
+				return errAnchorLost
+			}
+		}
+
+		content := []rune(latest.Content)
+		if start >= end || end > len(content) {
+			return errAnchorRange
+		}
+		a.RevisionID, a.Start, a.End, a.Quote = latest.ID, start, end, string(content[start:end])
+		return tx.Create(&a).Error
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, errAnchorRange):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid annotation range"})
+		case errors.Is(err, errAnchorLost):
+			c.JSON(http.StatusConflict, gin.H{"error": "The selected text no longer exists in the latest content"})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create annotation"})
+		}
+		return
+	}
+
+	h.DB.Preload("User").First(&a, a.ID)
+	c.JSON(http.StatusCreated, annotationResponse(a))
+}
+
+// errAnchorRange 表示区间越界或为空
+var errAnchorRange = errors.New("invalid annotation range")
+
+func annotationResponse(a model.Annotation) AnnotationResponse {
+	return AnnotationResponse{
+		Annotation: a,
+		Author:     AuthorResponse{ID: a.User.ID, Username: a.User.Username, AvatarURL: a.User.AvatarURL},
+	}
+}
+
+// remapAnnotations 在节点内容产生新修订后，把旧修订上的标注锚点重新定位到新修订。
+// 区间内仍有文字保留时沿用映射后的区间；全部被删除时按原文就近查找，找不到则标记为失效。
+func remapAnnotations(tx *gorm.DB, kind string, nodeID uint, oldContent, newContent string, revisionID uint) error {
+	if oldContent == newContent {
+		return tx.Model(&model.Annotation{}).
+			Where("node_kind = ? AND node_id = ? AND orphaned = ?", kind, nodeID, false).
+			Update("revision_id", revisionID).Error
+	}
+
+	var annotations []model.Annotation
+	if err := tx.Where("node_kind = ? AND node_id = ? AND orphaned = ?", kind, nodeID, false).Find(&annotations).Error; err != nil {
+		return err
+	}
+	if len(annotations) == 0 {
+		return nil
+	}
+
+	m := textdiff.NewOffsetMap(oldContent, newContent)
+	content := []rune(newContent)
+	for _, a := range annotations {
+		updates := map[string]interface{}{"revision_id": revisionID}
+		if start, end, ok := m.Range(a.Start, a.End); ok {
+			updates["start_offset"], updates["end_offset"], updates["quote"] = start, end, string(content[start:end])
+		} else if start, ok := findNearest(content, []rune(a.Quote), a.Start); ok {
+			updates["start_offset"], updates["end_offset"] = start, start+len([]rune(a.Quote))
+		} else {
+			updates["orphaned"] = true
+		}
+		if err := tx.Model(&model.Annotation{}).Where("id = ?", a.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findNearest 在 text 中查找离 near 最近的 quote
+func findNearest(text, quote []rune, near int) (int, bool) {
+	best, found := 0, false
+	for i := 0; i+len(quote) <= len(text) && len(quote) > 0; i++ {
+		if !hasPrefixAt(text, quote, i) {
+			continue
+		}
+		if !found || abs(i-near) < abs(best-near) {
+			best, found = i, true
+		}
+	}
+	return best, found
+}
+
+func hasPrefixAt(s, prefix []rune, at int) bool {
+	for j, r := range prefix {
+		if s[at+j] != r {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
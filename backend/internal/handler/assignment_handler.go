@@ -0,0 +1,598 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/shuind/language-learner/backend/internal/model"
+	"github.com/shuind/language-learner/backend/internal/nodecontent"
+)
+
+// AssignmentHandler 处理圈子作业：布置、修改、成员完成情况与个人待办。
+// 圈子内的路由需挂在 middleware.DomainRoleMiddleware 之后。
+type AssignmentHandler struct {
+	DB *gorm.DB
+}
+
+func NewAssignmentHandler(db *gorm.DB) *AssignmentHandler {
+	return &AssignmentHandler{DB: db}
+}
+
+// maxAssignmentNodes 限制一次作业包含的节点数
+const maxAssignmentNodes = 50
+
+// maxAssignmentLimit 是作业列表每页的最大条数
+const maxAssignmentLimit = 100
+
+type CreateAssignmentInput struct {
+	Title       string    `json:"title" binding:"required,max=255"`
+	Description string    `json:"description"`
+	DueAt       time.Time `json:"due_at" binding:"required"`
+	MinAccuracy *float64  `json:"min_accuracy" binding:"omitempty,min=0,max=1"`
+	NodeIDs     []uint    `json:"node_ids" binding:"required,min=1,max=50"`
+	UserIDs     []uint    `json:"user_ids"` // 为空表示面向全体成员
+}
+
+// UpdateAssignmentInput 中为空的字段保持不变；min_accuracy 为 0 表示取消准确率要求，
+// user_ids 为空数组表示改为面向全体成员
+type UpdateAssignmentInput struct {
+	Title       *string    `json:"title" binding:"omitempty,max=255"`
+	Description *string    `json:"description"`
+	DueAt       *time.Time `json:"due_at"`
+	MinAccuracy *float64   `json:"min_accuracy" binding:"omitempty,min=0,max=1"`
+	NodeIDs     []uint     `json:"node_ids" binding:"omitempty,max=50"`
+	UserIDs     *[]uint    `json:"user_ids"`
+}
+
+// AssignmentProgress 是一名成员在作业中的完成情况
+type AssignmentProgress struct {
+	Status         string     `json:"status"`
+	CompletedNodes int        `json:"completed_nodes"`
+	TotalNodes     int        `json:"total_nodes"`
+	CompletedAt    *time.Time `json:"completed_at"` // 最后一个节点完成的时间
+}
+
+// AssignmentSummary 统计各状态的成员数
+type AssignmentSummary struct {
+	Assignees int `json:"assignees"`
+	Done      int `json:"done"`
+	Late      int `json:"late"`
+	Missing   int `json:"missing"`
+	Pending   int `json:"pending"`
+}
+
+type AssignmentResponse struct {
+	model.Assignment
+	UserIDs    []uint              `json:"user_ids,omitempty"`
+	DomainName string              `json:"domain_name,omitempty"`
+	MyProgress *AssignmentProgress `json:"my_progress,omitempty"` // 当前用户是作业对象时返回
+	Summary    *AssignmentSummary  `json:"summary,omitempty"`     // 仅圈主与管理员可见
+}
+
+type AssignmentRosterEntry struct {
+	User AuthorResponse `json:"user"`
+	AssignmentProgress
+}
+
+// nodeCompletion 是某成员在某节点上第一次达标的录音
+type nodeCompletion struct {
+	UserID       uint
+	DomainNodeID uint
+	FirstAt      time.Time
+}
+
+// ---------------------- 圈主与管理员 ----------------------
+
+// CreateAssignment POST /domains/:domainId/assignments
+func (h *AssignmentHandler) CreateAssignment(c *gin.Context) {
+	domain := c.MustGet("domain").(model.Domain)
+	userID := c.MustGet("userID").(uint)
+	var input CreateAssignmentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !input.DueAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "due_at must be in the future"})
+		return
+	}
+
+	assignment := model.Assignment{
+		DomainID:    domain.ID,
+		CreatedBy:   userID,
+		Title:       input.Title,
+		Description: input.Description,
+		DueAt:       input.DueAt,
+		MinAccuracy: input.MinAccuracy,
+		AllMembers:  len(input.UserIDs) == 0,
+	}
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		nodes, err := loadAssignmentNodes(tx, domain.ID, input.NodeIDs)
+		if err != nil {
+			return err
+		}
+		if err := tx.Omit("Nodes").Create(&assignment).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&assignment).Association("Nodes").Replace(nodes); err != nil {
+			return err
+		}
+		if err := setAssignees(tx, &assignment, input.UserIDs); err != nil {
+			return err
+		}
+		return RecordDomainActivity(tx, domain.ID, userID, model.ActivityAssignmentCreated, assignment.ID, assignment.Title)
+	})
+	if err != nil {
+		respondAssignmentError(c, err, "Failed to create assignment")
+		return
+	}
+	c.JSON(http.StatusCreated, AssignmentResponse{Assignment: assignment, UserIDs: input.UserIDs})
+}
+
+// UpdateAssignment PATCH /domains/:domainId/assignments/:assignmentId
+func (h *AssignmentHandler) UpdateAssignment(c *gin.Context) {
+	assignment, ok := h.findAssignment(c)
+	if !ok {
+		return
+	}
+	var input UpdateAssignmentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if input.Title != nil {
+		updates["title"] = *input.Title
+	}
+	if input.Description != nil {
+		updates["description"] = *input.Description
+	}
+	if input.DueAt != nil {
+		if !input.DueAt.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "due_at must be in the future"})
+			return
+		}
+		updates["due_at"] = *input.DueAt
+	}
+	if input.MinAccuracy != nil {
+		if *input.MinAccuracy == 0 {
+			updates["min_accuracy"] = nil
+		} else {
+			updates["min_accuracy"] = *input.MinAccuracy
+		}
+	}
+	if input.UserIDs != nil {
+		updates["all_members"] = len(*input.UserIDs) == 0
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			if err := tx.Model(assignment).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		if len(input.NodeIDs) > 0 {
+			nodes, err := loadAssignmentNodes(tx, assignment.DomainID, input.NodeIDs)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(assignment).Association("Nodes").Replace(nodes); err != nil {
+				return err
+			}
+		}
+		if input.UserIDs != nil {
+			return setAssignees(tx, assignment, *input.UserIDs)
+		}
+		return nil
+	})
+	if err != nil {
+		respondAssignmentError(c, err, "Failed to update assignment")
+		return
+	}
+	h.GetAssignment(c)
+}
+
+// DeleteAssignment DELETE /domains/:domainId/assignments/:assignmentId
+func (h *AssignmentHandler) DeleteAssignment(c *gin.Context) {
+	assignment, ok := h.findAssignment(c)
+	if !ok {
+		return
+	}
+	if err := h.DB.Delete(assignment).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete assignment"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetAssignmentRoster GET /domains/:domainId/assignments/:assignmentId/roster?status=
+// 列出每名作业对象的完成情况，按用户名排序，可按状态过滤
+func (h *AssignmentHandler) GetAssignmentRoster(c *gin.Context) {
+	assignment, ok := h.findAssignment(c)
+	if !ok {
+		return
+	}
+	userIDs, err := assignmentAssignees(h.DB, assignment)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load roster"})
+		return
+	}
+	progress, err := assignmentProgress(h.DB, assignment, userIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load roster"})
+		return
+	}
+
+	var users []model.User
+	if len(userIDs) > 0 {
+		if err := h.DB.Where("id IN ?", userIDs).Order("username").Find(&users).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load roster"})
+			return
+		}
+	}
+	status := c.Query("status")
+	roster := make([]AssignmentRosterEntry, 0, len(users))
+	for _, u := range users {
+		p := progress[u.ID]
+		if status != "" && p.Status != status {
+			continue
+		}
+		roster = append(roster, AssignmentRosterEntry{
+			User:               AuthorResponse{ID: u.ID, Username: u.Username, AvatarURL: u.AvatarURL},
+			AssignmentProgress: *p,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"assignment": assignment,
+		"summary":    summarize(progress),
+		"roster":     roster,
+	})
+}
+
+// ---------------------- 成员 ----------------------
+
+// ListDomainAssignments GET /domains/:domainId/assignments?page=&limit=
+// 圈主与管理员看到全部作业及统计，普通成员只看到布置给自己的作业
+func (h *AssignmentHandler) ListDomainAssignments(c *gin.Context) {
+	domain := c.MustGet("domain").(model.Domain)
+	member := c.MustGet("domainMember").(model.DomainMember)
+	page, limit := assignmentPage(c)
+
+	query := h.DB.Model(&model.Assignment{}).Where("domain_id = ?", domain.ID)
+	manager := roleRank(member.Role) > roleRank(model.DomainRoleMember)
+	if !manager {
+		query = scopeAssignedTo(query, member.UserID)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list assignments"})
+		return
+	}
+	var assignments []model.Assignment
+	if err := query.Preload("Nodes").Order("due_at DESC").
+		Offset((page - 1) * limit).Limit(limit).Find(&assignments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list assignments"})
+		return
+	}
+
+	resp := make([]AssignmentResponse, 0, len(assignments))
+	for i := range assignments {
+		item, err := h.describe(&assignments[i], member.UserID, manager)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list assignments"})
+			return
+		}
+		resp = append(resp, *item)
+	}
+	c.JSON(http.StatusOK, gin.H{"total": total, "page": page, "assignments": resp})
+}
+
+// GetAssignment GET /domains/:domainId/assignments/:assignmentId
+func (h *AssignmentHandler) GetAssignment(c *gin.Context) {
+	assignment, ok := h.findAssignment(c)
+	if !ok {
+		return
+	}
+	member := c.MustGet("domainMember").(model.DomainMember)
+	manager := roleRank(member.Role) > roleRank(model.DomainRoleMember)
+	resp, err := h.describe(assignment, member.UserID, manager)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load assignment"})
+		return
+	}
+	if !manager && resp.MyProgress == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Assignment not found"})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ListMyAssignments GET /assignments?status=open|all&page=&limit=
+// 当前用户在所有圈子中的作业待办，默认只返回尚未完成的，按截止时间排序
+func (h *AssignmentHandler) ListMyAssignments(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	page, limit := assignmentPage(c)
+
+	memberships := h.DB.Model(&model.DomainMember{}).Select("domain_id").Where("user_id = ?", userID)
+	query := scopeAssignedTo(h.DB.Model(&model.Assignment{}).Where("domain_id IN (?)", memberships), userID)
+	if c.DefaultQuery("status", "open") == "open" {
+		query = scopeOpenFor(query, userID)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list assignments"})
+		return
+	}
+	var assignments []model.Assignment
+	if err := query.Preload("Nodes").Order("due_at, id").
+		Offset((page - 1) * limit).Limit(limit).Find(&assignments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list assignments"})
+		return
+	}
+
+	domainNames := map[uint]string{}
+	if len(assignments) > 0 {
+		var domains []model.Domain
+		ids := make([]uint, len(assignments))
+		for i, a := range assignments {
+			ids[i] = a.DomainID
+		}
+		h.DB.Select("id", "name").Where("id IN ?", ids).Find(&domains)
+		for _, d := range domains {
+			domainNames[d.ID] = d.Name
+		}
+	}
+
+	items := make([]AssignmentResponse, 0, len(assignments))
+	for i := range assignments {
+		item, err := h.describe(&assignments[i], userID, false)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list assignments"})
+			return
+		}
+		item.DomainName = domainNames[item.DomainID]
+		items = append(items, *item)
+	}
+	c.JSON(http.StatusOK, gin.H{"total": total, "page": page, "assignments": items})
+}
+
+// ---------------------- 内部方法 ----------------------
+
+var (
+	errAssignmentNodes     = errors.New("assignment nodes must be recitable nodes of this domain")
+	errAssignmentAssignees = errors.New("assignees must be members of this domain")
+)
+
+func respondAssignmentError(c *gin.Context, err error, message string) {
+	if errors.Is(err, errAssignmentNodes) || errors.Is(err, errAssignmentAssignees) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
+
+func assignmentPage(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > maxAssignmentLimit {
+		limit = 20
+	}
+	return page, limit
+}
+
+// scopeAssignedTo 限定为布置给 userID 的作业：面向全体成员且不是本人布置的，或指定了本人的
+func scopeAssignedTo(query *gorm.DB, userID uint) *gorm.DB {
+	assigned := query.Session(&gorm.Session{NewDB: true}).Model(&model.AssignmentAssignee{}).
+		Select("assignment_id").Where("user_id = ?", userID)
+	return query.Where("(all_members AND created_by <> ?) OR id IN (?)", userID, assigned)
+}
+
+// scopeOpenFor 限定为 userID 尚未完成的作业：还有节点没有达标的录音，或作业已没有节点。
+// 完成条件与 assignmentProgress 一致，用于在数据库中过滤和分页
+func scopeOpenFor(query *gorm.DB, userID uint) *gorm.DB {
+	return query.Where(`EXISTS (
+			SELECT 1 FROM assignment_nodes an
+			JOIN domain_nodes dn ON dn.id = an.domain_node_id AND dn.deleted_at IS NULL
+			WHERE an.assignment_id = assignments.id AND NOT EXISTS (
+				SELECT 1 FROM recordings r
+				WHERE r.domain_node_id = an.domain_node_id AND r.user_id = ?
+				  AND r.status = 'completed' AND r.deleted_at IS NULL
+				  AND r.created_at >= assignments.created_at
+				  AND (assignments.min_accuracy IS NULL OR r.accuracy >= assignments.min_accuracy)))
+		OR NOT EXISTS (
+			SELECT 1 FROM assignment_nodes an
+			JOIN domain_nodes dn ON dn.id = an.domain_node_id AND dn.deleted_at IS NULL
+			WHERE an.assignment_id = assignments.id)`, userID)
+}
+
+// loadAssignmentNodes 校验节点都属于该圈子且可以朗读
+func loadAssignmentNodes(tx *gorm.DB, domainID uint, ids []uint) ([]model.DomainNode, error) {
+	unique := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		unique[id] = true
+	}
+	if len(unique) > maxAssignmentNodes {
+		return nil, errAssignmentNodes
+	}
+	var nodes []model.DomainNode
+	if err := tx.Where("id IN ? AND domain_id = ?", ids, domainID).Find(&nodes).Error; err != nil {
+		return nil, err
+	}
+	if len(nodes) != len(unique) {
+		return nil, errAssignmentNodes
+	}
+	for _, n := range nodes {
+		if !nodecontent.Recitable(n.NodeType) {
+			return nil, errAssignmentNodes
+		}
+	}
+	return nodes, nil
+}
+
+// setAssignees 覆盖作业的指定成员；userIDs 为空时面向全体成员，不保留名单
+func setAssignees(tx *gorm.DB, assignment *model.Assignment, userIDs []uint) error {
+	if err := tx.Where("assignment_id = ?", assignment.ID).Delete(&model.AssignmentAssignee{}).Error; err != nil {
+		return err
+	}
+	if len(userIDs) == 0 {
+		return nil
+	}
+	unique := make(map[uint]bool, len(userIDs))
+	for _, id := range userIDs {
+		unique[id] = true
+	}
+	var count int64
+	if err := tx.Model(&model.DomainMember{}).
+		Where("domain_id = ? AND user_id IN ?", assignment.DomainID, userIDs).Count(&count).Error; err != nil {
+		return err
+	}
+	if int(count) != len(unique) {
+		return errAssignmentAssignees
+	}
+	rows := make([]model.AssignmentAssignee, 0, len(unique))
+	for id := range unique {
+		rows = append(rows, model.AssignmentAssignee{AssignmentID: assignment.ID, UserID: id})
+	}
+	return tx.Create(&rows).Error
+}
+
+func (h *AssignmentHandler) findAssignment(c *gin.Context) (*model.Assignment, bool) {
+	domain := c.MustGet("domain").(model.Domain)
+	var assignment model.Assignment
+	if err := h.DB.Preload("Nodes").
+		Where("id = ? AND domain_id = ?", c.Param("assignmentId"), domain.ID).
+		First(&assignment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Assignment not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load assignment"})
+		}
+		return nil, false
+	}
+	return &assignment, true
+}
+
+// assignmentAssignees 返回作业当前的对象；已退出圈子的成员不再计入
+func assignmentAssignees(db *gorm.DB, a *model.Assignment) ([]uint, error) {
+	query := db.Model(&model.DomainMember{}).Where("domain_id = ?", a.DomainID)
+	if a.AllMembers {
+		query = query.Where("user_id <> ?", a.CreatedBy)
+	} else {
+		query = query.Where("user_id IN (?)",
+			db.Model(&model.AssignmentAssignee{}).Select("user_id").Where("assignment_id = ?", a.ID))
+	}
+	var ids []uint
+	err := query.Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+// assignmentProgress 根据录音推导每名成员的完成情况：布置之后为作业节点录音、处理完成（且准确率达标）即完成该节点，
+// 全部节点完成的时间与截止时间比较得出按时或迟交
+func assignmentProgress(db *gorm.DB, a *model.Assignment, userIDs []uint) (map[uint]*AssignmentProgress, error) {
+	result := make(map[uint]*AssignmentProgress, len(userIDs))
+	for _, id := range userIDs {
+		result[id] = &AssignmentProgress{TotalNodes: len(a.Nodes)}
+	}
+
+	if len(userIDs) > 0 && len(a.Nodes) > 0 {
+		nodeIDs := make([]uint, len(a.Nodes))
+		for i, n := range a.Nodes {
+			nodeIDs[i] = n.ID
+		}
+		query := db.Model(&model.Recording{}).
+			Select("user_id, domain_node_id, MIN(created_at) AS first_at").
+			Where("domain_node_id IN ? AND user_id IN ? AND status = 'completed' AND created_at >= ?", nodeIDs, userIDs, a.CreatedAt)
+		if a.MinAccuracy != nil {
+			query = query.Where("accuracy >= ?", *a.MinAccuracy)
+		}
+		var completions []nodeCompletion
+		if err := query.Group("user_id, domain_node_id").Scan(&completions).Error; err != nil {
+			return nil, err
+		}
+		for _, comp := range completions {
+			p := result[comp.UserID]
+			p.CompletedNodes++
+			if p.CompletedAt == nil || comp.FirstAt.After(*p.CompletedAt) {
+				at := comp.FirstAt
+				p.CompletedAt = &at
+			}
+		}
+	}
+
+	now := time.Now()
+	for _, p := range result {
+		switch {
+		case p.CompletedNodes < p.TotalNodes || p.TotalNodes == 0:
+			p.CompletedAt = nil
+			if now.After(a.DueAt) {
+				p.Status = model.AssignmentStatusMissing
+			} else {
+				p.Status = model.AssignmentStatusPending
+			}
+		case p.CompletedAt.After(a.DueAt):
+			p.Status = model.AssignmentStatusLate
+		default:
+			p.Status = model.AssignmentStatusDone
+		}
+	}
+	return result, nil
+}
+
+func summarize(progress map[uint]*AssignmentProgress) AssignmentSummary {
+	s := AssignmentSummary{Assignees: len(progress)}
+	for _, p := range progress {
+		switch p.Status {
+		case model.AssignmentStatusDone:
+			s.Done++
+		case model.AssignmentStatusLate:
+			s.Late++
+		case model.AssignmentStatusMissing:
+			s.Missing++
+		default:
+			s.Pending++
+		}
+	}
+	return s
+}
+
+// describe 组装作业详情：userID 是作业对象时附带其完成情况，manager 为 true 时附带指定名单与统计
+func (h *AssignmentHandler) describe(a *model.Assignment, userID uint, manager bool) (*AssignmentResponse, error) {
+	ids, err := assignmentAssignees(h.DB, a)
+	if err != nil {
+		return nil, err
+	}
+	var subjects []uint
+	if manager {
+		subjects = ids
+	} else {
+		for _, id := range ids {
+			if id == userID {
+				subjects = []uint{userID}
+				break
+			}
+		}
+	}
+	progress, err := assignmentProgress(h.DB, a, subjects)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &AssignmentResponse{Assignment: *a, MyProgress: progress[userID]}
+	if manager {
+		summary := summarize(progress)
+		resp.Summary = &summary
+		if !a.AllMembers {
+			resp.UserIDs = ids
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+	"gorm.io/gorm"
+
+	"github.com/shuind/language-learner/backend/internal/collab"
+	"github.com/shuind/language-learner/backend/internal/model"
+	"github.com/shuind/language-learner/backend/internal/search"
+)
+
+// CollabHandler 提供圈子节点的多人实时协同编辑
+type CollabHandler struct {
+	DB  *gorm.DB
+	Hub *collab.Hub
+}
+
+func NewCollabHandler(db *gorm.DB) *CollabHandler {
+	h := &CollabHandler{DB: db}
+	h.Hub = collab.NewHub(h.load, h.save)
+	return h
+}
+
+// maxCollabMessageBytes 限制单条 WebSocket 消息的大小
+const maxCollabMessageBytes = 1 << 20
+
+// EditDomainNode GET /domain-nodes/:id/collab （WebSocket）
+// 只有圈主和管理员可以加入；浏览器可通过 access_token 查询参数传递登录令牌。
+// 协议见 collab 包：连接后先收到 init，之后提交 op / cursor 消息。
+func (h *CollabHandler) EditDomainNode(c *gin.Context) {
+	node, ok := h.loadEditableNode(c)
+	if !ok {
+		return
+	}
+	userID := c.MustGet("userID").(uint)
+	var user model.User
+	h.DB.Select("id", "username").First(&user, userID)
+
+	client := h.Hub.NewClient(userID, user.Username)
+	server := websocket.Server{Handler: func(ws *websocket.Conn) {
+		h.serve(ws, node.ID, client)
+	}}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// GetDomainNodePresence GET /domain-nodes/:id/collab/presence
+// 不建立连接也能查看谁正在编辑
+func (h *CollabHandler) GetDomainNodePresence(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	var node model.DomainNode
+	if err := h.DB.Select("id", "domain_id").First(&node, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Domain node not found"})
+		return
+	}
+	var member model.DomainMember
+	if err := h.DB.Where("domain_id = ? AND user_id = ?", node.DomainID, userID).First(&member).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: you are not a member of this domain"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"active": h.Hub.Active(node.ID), "editors": h.Hub.Presence(node.ID)})
+}
+
+// loadEditableNode 校验节点是文本且当前用户是圈主或管理员
+func (h *CollabHandler) loadEditableNode(c *gin.Context) (*model.DomainNode, bool) {
+	userID := c.MustGet("userID").(uint)
+	var node model.DomainNode
+	if err := h.DB.Select("id", "domain_id", "node_type").First(&node, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Domain node not found"})
+		return nil, false
+	}
+	if node.NodeType != "text" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only text nodes can be edited collaboratively"})
+		return nil, false
+	}
+	var member model.DomainMember
+	if err := h.DB.Where("domain_id = ? AND user_id = ? AND role IN ?", node.DomainID, userID, []string{"owner", "admin"}).
+		First(&member).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return nil, false
+	}
+	return &node, true
+}
+
+// serve 处理一个连接：写协程转发 Hub 的消息，当前协程读取客户端的消息
+func (h *CollabHandler) serve(ws *websocket.Conn, nodeID uint, client *collab.Client) {
+	defer ws.Close()
+	ws.MaxPayloadBytes = maxCollabMessageBytes
+
+	if err := h.Hub.Join(nodeID, client); err != nil {
+		log.Printf("ERROR joining collaborative session for domain node %d: %v", nodeID, err)
+		websocket.JSON.Send(ws, collab.Message{Type: collab.MsgError, Error: "Failed to load document"})
+		return
+	}
+	defer h.Hub.Leave(nodeID, client)
+
+	go func() {
+		for msg := range client.Messages() {
+			if err := websocket.JSON.Send(ws, msg); err != nil {
+				break
+			}
+		}
+		// 通道关闭（被移出会话）或写失败时关闭连接，读循环随之结束
+		ws.Close()
+	}()
+
+	for {
+		var msg collab.Message
+		if err := websocket.JSON.Receive(ws, &msg); err != nil {
+			return
+		}
+		switch msg.Type {
+		case collab.MsgOp:
+			// 被拒绝的操作已由 Hub 通知客户端，这里无需处理
+			h.Hub.Submit(nodeID, client, msg.Rev, msg.Op)
+		case collab.MsgCursor:
+			if msg.Cursor != nil {
+				h.Hub.MoveCursor(nodeID, client, msg.Rev, *msg.Cursor)
+			}
+		}
+	}
+}
+
+// load 是会话开启时读取节点内容的回调
+func (h *CollabHandler) load(nodeID uint) (string, int, error) {
+	var node model.DomainNode
+	if err := h.DB.Select("id", "content", "version").First(&node, nodeID).Error; err != nil {
+		return "", 0, err
+	}
+	return node.Content, node.Version, nil
+}
+
+// save 把协同文档的快照写回节点，并为每个快照追加一条修订记录与圈子动态（连续保存会合并）。
+// 只有节点仍是会话载入（或上次保存）时的版本才写入，避免覆盖会话之外的修改
+func (h *CollabHandler) save(nodeID uint, content string, authorID uint, version int) (int, error) {
+	var node model.DomainNode
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := UpdateVersioned(tx, &model.DomainNode{}, nodeID, version, map[string]interface{}{"content": content}); err != nil {
+			if errors.Is(err, ErrVersionConflict) {
+				return collab.ErrSnapshotConflict
+			}
+			return err
+		}
+		if err := tx.First(&node, nodeID).Error; err != nil {
+			return err
+		}
+		if err := search.IndexDomainNode(tx, &node); err != nil {
+			return err
+		}
+		if err := InvalidateReferenceAudio(tx, model.RevisionKindDomainNode, node.ID, node.NodeType, node.Content, node.Language); err != nil {
+			return err
+		}
+		if err := RealignTranslations(tx, model.RevisionKindDomainNode, node.ID, node.NodeType, node.Content); err != nil {
+			return err
+		}
+		if _, err := RecordRevision(tx, model.RevisionKindDomainNode, node.ID, authorID, node.Title, node.Content); err != nil {
+			return err
+		}
+		return RecordDomainActivity(tx, node.DomainID, authorID, model.ActivityNodeUpdated, node.ID, node.Title)
+	})
+	return node.Version, err
+}
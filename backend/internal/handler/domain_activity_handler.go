@@ -0,0 +1,269 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/shuind/language-learner/backend/internal/model"
+)
+
+// DomainActivityHandler 处理圈子动态流与公告。
+// 路由需挂在 middleware.DomainRoleMiddleware 之后。
+type DomainActivityHandler struct {
+	DB *gorm.DB
+}
+
+func NewDomainActivityHandler(db *gorm.DB) *DomainActivityHandler {
+	return &DomainActivityHandler{DB: db}
+}
+
+// activityCoalesceWindow 内同一人对同一节点的连续编辑只记一条动态
+const activityCoalesceWindow = 10 * time.Minute
+
+var activityTypes = map[string]bool{
+	model.ActivityNodePublished:     true,
+	model.ActivityNodeUpdated:       true,
+	model.ActivityAssignmentCreated: true,
+	model.ActivityRecordingFeatured: true,
+	model.ActivityMemberJoined:      true,
+	model.ActivityAnnouncement:      true,
+}
+
+// RecordDomainActivity 在 tx 中追加一条圈子动态。
+// 内容更新类的动态在 activityCoalesceWindow 内合并，连续保存不会刷屏
+func RecordDomainActivity(tx *gorm.DB, domainID, actorID uint, kind string, subjectID uint, title string) error {
+	if kind == model.ActivityNodeUpdated {
+		var recent int64
+		if err := tx.Model(&model.DomainActivity{}).
+			Where("domain_id = ? AND actor_id = ? AND type = ? AND subject_id = ? AND created_at > ?",
+				domainID, actorID, kind, subjectID, time.Now().Add(-activityCoalesceWindow)).
+			Count(&recent).Error; err != nil {
+			return err
+		}
+		if recent > 0 {
+			return nil
+		}
+	}
+	return tx.Create(&model.DomainActivity{
+		DomainID:  domainID,
+		ActorID:   actorID,
+		Type:      kind,
+		SubjectID: subjectID,
+		Title:     title,
+	}).Error
+}
+
+// ActivityResponse 是动态流中的一条记录
+type ActivityResponse struct {
+	model.DomainActivity
+	Actor AuthorResponse `json:"actor"`
+}
+
+// AnnouncementResponse 是一条公告
+type AnnouncementResponse struct {
+	model.DomainAnnouncement
+	Author AuthorResponse `json:"author"`
+}
+
+type AnnouncementInput struct {
+	Title   string `json:"title" binding:"required,max=255"`
+	Content string `json:"content" binding:"required"`
+	Pinned  bool   `json:"pinned"`
+}
+
+type UpdateAnnouncementInput struct {
+	Title   *string `json:"title" binding:"omitempty,min=1,max=255"`
+	Content *string `json:"content" binding:"omitempty,min=1"`
+	Pinned  *bool   `json:"pinned"`
+}
+
+// ListActivity GET /domains/:domainId/activity?type=&cursor=&limit=
+// 按时间倒序返回动态；type 可用逗号分隔多个类型。普通成员只看到布置给自己的作业动态。
+// cursor 为上一页返回的 next_cursor，next_cursor 为空表示没有更多
+func (h *DomainActivityHandler) ListActivity(c *gin.Context) {
+	domain := c.MustGet("domain").(model.Domain)
+	member := c.MustGet("domainMember").(model.DomainMember)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	query := h.DB.Preload("Actor").Where("domain_id = ?", domain.ID)
+	if roleRank(member.Role) <= roleRank(model.DomainRoleMember) {
+		visible := scopeAssignedTo(h.DB.Model(&model.Assignment{}).Select("id").Where("domain_id = ?", domain.ID), member.UserID)
+		query = query.Where("type <> ? OR subject_id IN (?)", model.ActivityAssignmentCreated, visible)
+	}
+	if s := c.Query("cursor"); s != "" {
+		cursor, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		query = query.Where("id < ?", cursor)
+	}
+	if s := c.Query("type"); s != "" {
+		kinds := strings.Split(s, ",")
+		for _, k := range kinds {
+			if !activityTypes[k] {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid activity type: " + k})
+				return
+			}
+		}
+		query = query.Where("type IN ?", kinds)
+	}
+
+	// 多取一条用来判断是否还有下一页
+	var rows []model.DomainActivity
+	if err := query.Order("id DESC").Limit(limit + 1).Find(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load activity"})
+		return
+	}
+	var next *uint
+	if len(rows) > limit {
+		rows = rows[:limit]
+		next = &rows[limit-1].ID
+	}
+	items := make([]ActivityResponse, len(rows))
+	for i, a := range rows {
+		items[i] = ActivityResponse{
+			DomainActivity: a,
+			Actor:          AuthorResponse{ID: a.Actor.ID, Username: a.Actor.Username, AvatarURL: a.Actor.AvatarURL},
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "next_cursor": next})
+}
+
+// ListAnnouncements GET /domains/:domainId/announcements?page=&limit=
+// 置顶公告在前（按置顶时间倒序），其余按发布时间倒序
+func (h *DomainActivityHandler) ListAnnouncements(c *gin.Context) {
+	domain := c.MustGet("domain").(model.Domain)
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	var total int64
+	h.DB.Model(&model.DomainAnnouncement{}).Where("domain_id = ?", domain.ID).Count(&total)
+	var rows []model.DomainAnnouncement
+	if err := h.DB.Preload("Author").Where("domain_id = ?", domain.ID).
+		Order("pinned DESC, pinned_at DESC, created_at DESC").
+		Offset((page - 1) * limit).Limit(limit).Find(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load announcements"})
+		return
+	}
+	items := make([]AnnouncementResponse, len(rows))
+	for i, a := range rows {
+		items[i] = announcementResponse(a)
+	}
+	c.JSON(http.StatusOK, gin.H{"total": total, "page": page, "announcements": items})
+}
+
+// CreateAnnouncement POST /domains/:domainId/announcements
+func (h *DomainActivityHandler) CreateAnnouncement(c *gin.Context) {
+	domain := c.MustGet("domain").(model.Domain)
+	userID := c.MustGet("userID").(uint)
+	var input AnnouncementInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	announcement := model.DomainAnnouncement{
+		DomainID: domain.ID,
+		AuthorID: userID,
+		Title:    input.Title,
+		Content:  input.Content,
+		Pinned:   input.Pinned,
+	}
+	if input.Pinned {
+		now := time.Now()
+		announcement.PinnedAt = &now
+	}
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&announcement).Error; err != nil {
+			return err
+		}
+		return RecordDomainActivity(tx, domain.ID, userID, model.ActivityAnnouncement, announcement.ID, announcement.Title)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to post announcement"})
+		return
+	}
+	h.DB.Preload("Author").First(&announcement, announcement.ID)
+	c.JSON(http.StatusCreated, announcementResponse(announcement))
+}
+
+// UpdateAnnouncement PATCH /domains/:domainId/announcements/:announcementId
+// 修改标题、内容或置顶状态
+func (h *DomainActivityHandler) UpdateAnnouncement(c *gin.Context) {
+	announcement, ok := h.findAnnouncement(c)
+	if !ok {
+		return
+	}
+	var input UpdateAnnouncementInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	updates := map[string]interface{}{}
+	if input.Title != nil {
+		updates["title"] = *input.Title
+	}
+	if input.Content != nil {
+		updates["content"] = *input.Content
+	}
+	if input.Pinned != nil && *input.Pinned != announcement.Pinned {
+		updates["pinned"] = *input.Pinned
+		if *input.Pinned {
+			updates["pinned_at"] = time.Now()
+		} else {
+			updates["pinned_at"] = nil
+		}
+	}
+	if len(updates) > 0 {
+		if err := h.DB.Model(announcement).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update announcement"})
+			return
+		}
+	}
+	h.DB.Preload("Author").First(announcement, announcement.ID)
+	c.JSON(http.StatusOK, announcementResponse(*announcement))
+}
+
+// DeleteAnnouncement DELETE /domains/:domainId/announcements/:announcementId
+func (h *DomainActivityHandler) DeleteAnnouncement(c *gin.Context) {
+	announcement, ok := h.findAnnouncement(c)
+	if !ok {
+		return
+	}
+	if err := h.DB.Delete(announcement).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete announcement"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *DomainActivityHandler) findAnnouncement(c *gin.Context) (*model.DomainAnnouncement, bool) {
+	domain := c.MustGet("domain").(model.Domain)
+	var announcement model.DomainAnnouncement
+	if err := h.DB.Where("id = ? AND domain_id = ?", c.Param("announcementId"), domain.ID).First(&announcement).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Announcement not found"})
+		return nil, false
+	}
+	return &announcement, true
+}
+
+func announcementResponse(a model.DomainAnnouncement) AnnouncementResponse {
+	return AnnouncementResponse{
+		DomainAnnouncement: a,
+		Author:             AuthorResponse{ID: a.Author.ID, Username: a.Author.Username, AvatarURL: a.Author.AvatarURL},
+	}
+}
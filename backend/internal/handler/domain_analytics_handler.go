@@ -0,0 +1,424 @@
+package handler
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/shuind/language-learner/backend/internal/model"
+	"github.com/shuind/language-learner/backend/internal/utils"
+)
+
+// DomainAnalyticsHandler 提供圈子的学习统计（圈主与管理员）与成员自愿参与的排行榜。
+// 路由需挂在 middleware.DomainRoleMiddleware 之后。
+type DomainAnalyticsHandler struct {
+	DB *gorm.DB
+}
+
+func NewDomainAnalyticsHandler(db *gorm.DB) *DomainAnalyticsHandler {
+	return &DomainAnalyticsHandler{DB: db}
+}
+
+// masteryAccuracy 是节点计为“已掌握”所需的录音准确率
+const masteryAccuracy = 0.9
+
+// analyticsAssignmentLimit 是统计中展示的最近作业数
+const analyticsAssignmentLimit = 20
+
+// maxLeaderboardLimit 是排行榜返回的最大人数
+const maxLeaderboardLimit = 100
+
+type MemberActivity struct {
+	User           AuthorResponse `json:"user"`
+	Role           string         `json:"role"`
+	Recordings     int64          `json:"recordings"`
+	PracticeMs     int64          `json:"practice_ms"`
+	AvgAccuracy    *float64       `json:"avg_accuracy"`
+	LastRecordedAt *time.Time     `json:"last_recorded_at"`
+}
+
+type NodeAccuracy struct {
+	NodeID      uint     `json:"node_id"`
+	Title       string   `json:"title"`
+	Recordings  int64    `json:"recordings"`
+	Members     int64    `json:"members"` // 录过音的成员数
+	AvgAccuracy *float64 `json:"avg_accuracy"`
+}
+
+type AssignmentCompletion struct {
+	ID             uint              `json:"id"`
+	Title          string            `json:"title"`
+	DueAt          time.Time         `json:"due_at"`
+	Summary        AssignmentSummary `json:"summary"`
+	CompletionRate float64           `json:"completion_rate"` // (按时 + 迟交) / 作业对象数
+}
+
+type ActivePoint struct {
+	Date    string `json:"date"`
+	Members int64  `json:"members"`
+}
+
+type LeaderboardEntry struct {
+	Rank  int            `json:"rank"`
+	User  AuthorResponse `json:"user"`
+	Value int64          `json:"value"`
+}
+
+type LeaderboardOptInInput struct {
+	OptIn *bool `json:"opt_in" binding:"required"`
+}
+
+// maxRecordingDurationMs 是一条录音计入练习时长的上限
+const maxRecordingDurationMs = 10 * 60 * 1000
+
+// minAudioBytesPerSecond 按 6 kbps 估计录音格式（Opus、MP3 等）的最低码率，用于由文件大小推出时长上限
+const minAudioBytesPerSecond = 6000 / 8
+
+// ClampDurationMs 把客户端上报的录音时长限制在文件大小与 maxRecordingDurationMs 允许的范围内，
+// 避免伪造的时长抬高练习统计与排行榜
+func ClampDurationMs(reported int, size int64) int {
+	limit := int64(maxRecordingDurationMs)
+	if bySize := size * 1000 / minAudioBytesPerSecond; bySize < limit {
+		limit = bySize
+	}
+	return int(min(int64(reported), limit))
+}
+
+// domainPracticeSQL 是圈子内所有练习活动的子查询：圈子节点上的录音与逐句练习录音，
+// 列为 user_id、node_id、duration_ms、accuracy、created_at。
+// 时长在写入时已由 ClampDurationMs 限制，这里再按 maxRecordingDurationMs 截断此前写入的数据
+const domainPracticeSQL = `
+    SELECT r.user_id, r.domain_node_id AS node_id, LEAST(r.duration_ms, 600000) AS duration_ms, r.accuracy, r.created_at
+    FROM recordings r
+    JOIN domain_nodes dn ON dn.id = r.domain_node_id
+    WHERE dn.domain_id = @domain AND dn.deleted_at IS NULL AND r.deleted_at IS NULL
+    UNION ALL
+    SELECT s.user_id, s.node_id, LEAST(pr.duration_ms, 600000) AS duration_ms, pr.accuracy, pr.created_at
+    FROM practice_recordings pr
+    JOIN practice_sessions s ON s.id = pr.session_id
+    JOIN domain_nodes dn ON dn.id = s.node_id
+    WHERE s.node_kind = @kind AND dn.domain_id = @domain AND dn.deleted_at IS NULL
+`
+
+// GetDomainAnalytics GET /domains/:domainId/analytics?period=day|week|month
+// 返回成员录音统计、节点平均准确率、最近作业的完成率，以及各周期的活跃成员数
+func (h *DomainAnalyticsHandler) GetDomainAnalytics(c *gin.Context) {
+	domain := c.MustGet("domain").(model.Domain)
+	params := map[string]interface{}{"domain": domain.ID, "kind": model.RevisionKindDomainNode}
+
+	members, err := h.memberActivity(params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	nodes, err := h.nodeAccuracy(params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	assignments, err := h.assignmentCompletion(domain.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+	active, err := h.activeMembers(c.DefaultQuery("period", "day"), params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"members":        members,
+		"nodes":          nodes,
+		"assignments":    assignments,
+		"active_members": active,
+	})
+}
+
+// memberActivity 统计每名成员的录音数、练习时长与平均准确率，没有练习的成员也会列出
+func (h *DomainAnalyticsHandler) memberActivity(params map[string]interface{}) ([]MemberActivity, error) {
+	type row struct {
+		UserID         uint
+		Username       string
+		AvatarURL      string
+		Role           string
+		Recordings     int64
+		PracticeMs     int64
+		AvgAccuracy    *float64
+		LastRecordedAt *time.Time
+	}
+	var rows []row
+	sql := `
+        SELECT m.user_id, u.username, u.avatar_url, m.role,
+               COUNT(p.user_id) AS recordings,
+               COALESCE(SUM(p.duration_ms), 0) AS practice_ms,
+               AVG(p.accuracy) AS avg_accuracy,
+               MAX(p.created_at) AS last_recorded_at
+        FROM domain_members m
+        JOIN users u ON u.id = m.user_id
+        LEFT JOIN (` + domainPracticeSQL + `) p ON p.user_id = m.user_id
+        WHERE m.domain_id = @domain
+        GROUP BY m.user_id, u.username, u.avatar_url, m.role
+        ORDER BY recordings DESC, u.username
+    `
+	if err := h.DB.Raw(sql, params).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make([]MemberActivity, len(rows))
+	for i, r := range rows {
+		out[i] = MemberActivity{
+			User:           AuthorResponse{ID: r.UserID, Username: r.Username, AvatarURL: r.AvatarURL},
+			Role:           r.Role,
+			Recordings:     r.Recordings,
+			PracticeMs:     r.PracticeMs,
+			AvgAccuracy:    r.AvgAccuracy,
+			LastRecordedAt: r.LastRecordedAt,
+		}
+	}
+	return out, nil
+}
+
+// nodeAccuracy 统计有练习记录的节点，平均准确率低的排在前面，便于发现难点
+func (h *DomainAnalyticsHandler) nodeAccuracy(params map[string]interface{}) ([]NodeAccuracy, error) {
+	var rows []NodeAccuracy
+	sql := `
+        SELECT p.node_id, dn.title,
+               COUNT(*) AS recordings,
+               COUNT(DISTINCT p.user_id) AS members,
+               AVG(p.accuracy) AS avg_accuracy
+        FROM (` + domainPracticeSQL + `) p
+        JOIN domain_nodes dn ON dn.id = p.node_id
+        GROUP BY p.node_id, dn.title
+        ORDER BY avg_accuracy ASC NULLS LAST, recordings DESC
+    `
+	err := h.DB.Raw(sql, params).Scan(&rows).Error
+	return rows, err
+}
+
+func (h *DomainAnalyticsHandler) assignmentCompletion(domainID uint) ([]AssignmentCompletion, error) {
+	var assignments []model.Assignment
+	if err := h.DB.Preload("Nodes").Where("domain_id = ?", domainID).
+		Order("due_at DESC").Limit(analyticsAssignmentLimit).Find(&assignments).Error; err != nil {
+		return nil, err
+	}
+	out := make([]AssignmentCompletion, 0, len(assignments))
+	for i := range assignments {
+		a := &assignments[i]
+		ids, err := assignmentAssignees(h.DB, a)
+		if err != nil {
+			return nil, err
+		}
+		progress, err := assignmentProgress(h.DB, a, ids)
+		if err != nil {
+			return nil, err
+		}
+		summary := summarize(progress)
+		item := AssignmentCompletion{ID: a.ID, Title: a.Title, DueAt: a.DueAt, Summary: summary}
+		if summary.Assignees > 0 {
+			item.CompletionRate = float64(summary.Done+summary.Late) / float64(summary.Assignees)
+		}
+		out = append(out, item)
+	}
+	return out, nil
+}
+
+// activeMembers 按周期统计有练习活动的成员数，分桶与补零方式同 TaskHandler.ScoreTrend
+func (h *DomainAnalyticsHandler) activeMembers(period string, params map[string]interface{}) ([]ActivePoint, error) {
+	tz, loc := utils.AppLocation()
+	now := time.Now().In(loc)
+
+	var base time.Time
+	var trunc string
+	var steps int
+	var addStep func(t time.Time, n int) time.Time
+	switch period {
+	case "week":
+		wd := int(now.Weekday())
+		if wd == 0 {
+			wd = 7
+		}
+		base = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc).AddDate(0, 0, -(wd - 1))
+		trunc = "week"
+		steps = 12
+		addStep = func(t time.Time, n int) time.Time { return t.AddDate(0, 0, 7*n) }
+	case "month":
+		base = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+		trunc = "month"
+		steps = 12
+		addStep = func(t time.Time, n int) time.Time { return t.AddDate(0, n, 0) }
+	default: // day
+		base = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+		trunc = "day"
+		steps = 30
+		addStep = func(t time.Time, n int) time.Time { return t.AddDate(0, 0, n) }
+	}
+	start := addStep(base, -(steps - 1))
+	end := addStep(base, 1)
+
+	type row struct {
+		Period  string
+		Members int64
+	}
+	var rows []row
+	args := map[string]interface{}{"trunc": trunc, "tz": tz, "start": start, "end": end}
+	for k, v := range params {
+		args[k] = v
+	}
+	sql := `
+        SELECT to_char(date_trunc(@trunc, p.created_at AT TIME ZONE @tz), 'YYYY-MM-DD') AS period,
+               COUNT(DISTINCT p.user_id) AS members
+        FROM (` + domainPracticeSQL + `) p
+        WHERE p.created_at >= @start AND p.created_at < @end
+        GROUP BY 1
+        ORDER BY 1
+    `
+	if err := h.DB.Raw(sql, args).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, r := range rows {
+		counts[r.Period] = r.Members
+	}
+	out := make([]ActivePoint, 0, steps)
+	for i := 0; i < steps; i++ {
+		key := addStep(start, i).Format("2006-01-02")
+		out = append(out, ActivePoint{Date: key, Members: counts[key]})
+	}
+	return out, nil
+}
+
+// ---------------------- 排行榜 ----------------------
+
+// GetLeaderboard GET /domains/:domainId/leaderboard?metric=minutes|mastered&window=day|week|term&limit=
+// 只有选择参与的成员会上榜；minutes 按练习时长排名（value 以毫秒为单位），mastered 按窗口内
+// 录音准确率达到 masteryAccuracy 的不同节点数排名。
+func (h *DomainAnalyticsHandler) GetLeaderboard(c *gin.Context) {
+	domain := c.MustGet("domain").(model.Domain)
+	member := c.MustGet("domainMember").(model.DomainMember)
+	metric := c.DefaultQuery("metric", "minutes")
+	window := c.DefaultQuery("window", "week")
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > maxLeaderboardLimit {
+		limit = 20
+	}
+
+	_, loc := utils.AppLocation()
+	now := time.Now().In(loc)
+	var since time.Time
+	switch window {
+	case "day":
+		since = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	case "week":
+		wd := int(now.Weekday())
+		if wd == 0 {
+			wd = 7
+		}
+		since = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc).AddDate(0, 0, -(wd - 1))
+	case "term":
+		since = domain.CreatedAt
+		if domain.TermStartsAt != nil {
+			since = *domain.TermStartsAt
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "window must be day, week or term"})
+		return
+	}
+
+	var value, unit string
+	switch metric {
+	case "minutes":
+		value, unit = "COALESCE(SUM(p.duration_ms), 0)", "ms"
+	case "mastered":
+		value, unit = "COUNT(DISTINCT p.node_id) FILTER (WHERE p.accuracy >= @mastery)", "nodes"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "metric must be minutes or mastered"})
+		return
+	}
+
+	type row struct {
+		UserID    uint
+		Username  string
+		AvatarURL string
+		Value     int64
+	}
+	var rows []row
+	sql := `
+        SELECT m.user_id, u.username, u.avatar_url, ` + value + ` AS value
+        FROM domain_members m
+        JOIN users u ON u.id = m.user_id
+        LEFT JOIN (` + domainPracticeSQL + `) p ON p.user_id = m.user_id AND p.created_at >= @since
+        WHERE m.domain_id = @domain AND m.leaderboard_opt_in
+        GROUP BY m.user_id, u.username, u.avatar_url
+    `
+	params := map[string]interface{}{
+		"domain":  domain.ID,
+		"kind":    model.RevisionKindDomainNode,
+		"since":   since,
+		"mastery": masteryAccuracy,
+	}
+	if err := h.DB.Raw(sql, params).Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Value != rows[j].Value {
+			return rows[i].Value > rows[j].Value
+		}
+		return rows[i].Username < rows[j].Username
+	})
+	// 先为所有人排出名次（并列同名次），再截取前 limit 名，当前用户不在前列时也能看到自己的名次
+	entries := make([]LeaderboardEntry, 0, len(rows))
+	var me *LeaderboardEntry
+	for i, r := range rows {
+		rank := i + 1
+		if i > 0 && r.Value == rows[i-1].Value {
+			rank = entries[len(entries)-1].Rank
+		}
+		entry := LeaderboardEntry{
+			Rank:  rank,
+			User:  AuthorResponse{ID: r.UserID, Username: r.Username, AvatarURL: r.AvatarURL},
+			Value: r.Value,
+		}
+		if r.UserID == member.UserID {
+			e := entry
+			me = &e
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"metric":  metric,
+		"unit":    unit,
+		"window":  window,
+		"since":   since,
+		"opt_in":  member.LeaderboardOptIn,
+		"me":      me,
+		"entries": entries,
+	})
+}
+
+// SetLeaderboardOptIn PUT /domains/:domainId/leaderboard/opt-in
+// 成员自行选择是否出现在排行榜中
+func (h *DomainAnalyticsHandler) SetLeaderboardOptIn(c *gin.Context) {
+	member := c.MustGet("domainMember").(model.DomainMember)
+	var input LeaderboardOptInInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.DB.Model(&model.DomainMember{}).Where("id = ?", member.ID).
+		Update("leaderboard_opt_in", *input.OptIn).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update leaderboard setting"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"opt_in": *input.OptIn})
+}
@@ -0,0 +1,485 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/shuind/language-learner/backend/internal/model"
+	"github.com/shuind/language-learner/backend/internal/utils"
+)
+
+// DomainInvitationHandler 处理圈子的邀请链接、加入码轮换与加入审核
+type DomainInvitationHandler struct {
+	DB *gorm.DB
+}
+
+func NewDomainInvitationHandler(db *gorm.DB) *DomainInvitationHandler {
+	return &DomainInvitationHandler{DB: db}
+}
+
+// invitationTokenBytes 是邀请令牌的随机字节数（编码后 32 个字符）
+const invitationTokenBytes = 24
+
+// joinCodeLength 是圈子加入码的长度
+const joinCodeLength = 8
+
+var (
+	// ErrDomainBanned 表示用户已被禁止加入该圈子
+	ErrDomainBanned = errors.New("user is banned from this domain")
+	// ErrAlreadyMember 表示用户已是圈子成员
+	ErrAlreadyMember = errors.New("user is already a member")
+	// ErrJoinRequestPending 表示用户已有待审核的加入申请
+	ErrJoinRequestPending = errors.New("join request already pending")
+	// ErrInvitationInvalid 表示邀请不存在、已撤销、已过期、次数已用完或不是发给当前用户的
+	ErrInvitationInvalid = errors.New("invitation is invalid")
+)
+
+// 加入圈子的结果
+const (
+	JoinResultJoined  = "joined"
+	JoinResultPending = "pending" // 已提交申请，等待审核
+)
+
+type CreateInvitationInput struct {
+	ExpiresAt    *time.Time `json:"expires_at"`                         // 为空表示永不过期
+	MaxUses      *int       `json:"max_uses" binding:"omitempty,min=1"` // 为空表示不限次数
+	TargetUserID *uint      `json:"target_user_id"`
+}
+
+type AcceptInvitationInput struct {
+	Message string `json:"message" binding:"max=500"` // 需要审核时附带的申请说明
+}
+
+type UpdateDomainSettingsInput struct {
+	RequireApproval *bool      `json:"require_approval"`
+	TermStartsAt    *time.Time `json:"term_starts_at"` // 排行榜 term 窗口的起点
+	AllowFork       *bool      `json:"allow_fork"`     // 是否允许普通成员保存圈子内容到个人空间
+}
+
+// InvitationPreview 是受邀者打开链接时看到的信息
+type InvitationPreview struct {
+	DomainID        uint       `json:"domain_id"`
+	DomainName      string     `json:"domain_name"`
+	Description     string     `json:"description"`
+	RequireApproval bool       `json:"require_approval"` // 对当前用户是否需要审核
+	ExpiresAt       *time.Time `json:"expires_at"`
+}
+
+// InvitationDomain 是接受邀请后返回的圈子信息。邀请只授予这一次加入，
+// 因此不含长期有效的加入码，申请待审核时也不会借此拿到
+type InvitationDomain struct {
+	ID          uint   `json:"id"`
+	OwnerID     uint   `json:"owner_id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type JoinRequestResponse struct {
+	model.DomainJoinRequest
+	User AuthorResponse `json:"user"`
+}
+
+// JoinDomain 让用户加入圈子：被封禁或已是成员时返回错误；
+// 圈子开启审核且不是定向邀请时创建一条待审核的申请，否则直接成为成员。
+// invitation 为空表示通过加入码加入；使用次数须由调用方在同一事务中先行占用。
+func JoinDomain(tx *gorm.DB, domain *model.Domain, userID uint, invitation *model.DomainInvitation, message string) (string, *model.DomainJoinRequest, error) {
+	var count int64
+	if err := tx.Model(&model.DomainBan{}).Where("domain_id = ? AND user_id = ?", domain.ID, userID).Count(&count).Error; err != nil {
+		return "", nil, err
+	}
+	if count > 0 {
+		return "", nil, ErrDomainBanned
+	}
+	if err := tx.Model(&model.DomainMember{}).Where("domain_id = ? AND user_id = ?", domain.ID, userID).Count(&count).Error; err != nil {
+		return "", nil, err
+	}
+	if count > 0 {
+		return "", nil, ErrAlreadyMember
+	}
+
+	targeted := invitation != nil && invitation.TargetUserID != nil
+	if domain.RequireApproval && !targeted {
+		if err := tx.Model(&model.DomainJoinRequest{}).
+			Where("domain_id = ? AND user_id = ? AND status = ?", domain.ID, userID, model.JoinRequestPending).
+			Count(&count).Error; err != nil {
+			return "", nil, err
+		}
+		if count > 0 {
+			return "", nil, ErrJoinRequestPending
+		}
+		req := model.DomainJoinRequest{DomainID: domain.ID, UserID: userID, Message: message, Status: model.JoinRequestPending}
+		if invitation != nil {
+			req.InvitationID = &invitation.ID
+		}
+		if err := tx.Create(&req).Error; err != nil {
+			return "", nil, err
+		}
+		return JoinResultPending, &req, nil
+	}
+
+	member := model.DomainMember{DomainID: domain.ID, UserID: userID, Role: model.DomainRoleMember}
+	if err := tx.Create(&member).Error; err != nil {
+		return "", nil, err
+	}
+	if err := recordMemberJoined(tx, domain.ID, userID); err != nil {
+		return "", nil, err
+	}
+	return JoinResultJoined, nil, nil
+}
+
+// RespondJoinError 把 JoinDomain 的错误转换为 HTTP 响应
+func RespondJoinError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrDomainBanned):
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are banned from this domain"})
+	case errors.Is(err, ErrAlreadyMember):
+		c.JSON(http.StatusConflict, gin.H{"error": "You are already a member of this domain"})
+	case errors.Is(err, ErrJoinRequestPending):
+		c.JSON(http.StatusConflict, gin.H{"error": "Your join request is already pending"})
+	case errors.Is(err, ErrInvitationInvalid):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found, expired or used up"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join domain"})
+	}
+}
+
+// ---------------------- 邀请链接（圈主与管理员） ----------------------
+
+// CreateInvitation POST /domains/:domainId/invitations
+func (h *DomainInvitationHandler) CreateInvitation(c *gin.Context) {
+	domain := c.MustGet("domain").(model.Domain)
+	userID := c.MustGet("userID").(uint)
+	var input CreateInvitationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.ExpiresAt != nil && input.ExpiresAt.Before(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+		return
+	}
+	if input.TargetUserID != nil {
+		var count int64
+		h.DB.Model(&model.User{}).Where("id = ?", *input.TargetUserID).Count(&count)
+		if count == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Target user not found"})
+			return
+		}
+	}
+
+	token, err := utils.GenerateSecureToken(invitationTokenBytes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate invitation token"})
+		return
+	}
+	invitation := model.DomainInvitation{
+		DomainID:     domain.ID,
+		CreatedBy:    userID,
+		Token:        token,
+		ExpiresAt:    input.ExpiresAt,
+		MaxUses:      input.MaxUses,
+		TargetUserID: input.TargetUserID,
+	}
+	if err := h.DB.Create(&invitation).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+	c.JSON(http.StatusCreated, invitation)
+}
+
+// ListInvitations GET /domains/:domainId/invitations
+func (h *DomainInvitationHandler) ListInvitations(c *gin.Context) {
+	domain := c.MustGet("domain").(model.Domain)
+	var invitations []model.DomainInvitation
+	if err := h.DB.Where("domain_id = ?", domain.ID).Order("created_at DESC").Find(&invitations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list invitations"})
+		return
+	}
+	c.JSON(http.StatusOK, invitations)
+}
+
+// RevokeInvitation DELETE /domains/:domainId/invitations/:invitationId
+func (h *DomainInvitationHandler) RevokeInvitation(c *gin.Context) {
+	domain := c.MustGet("domain").(model.Domain)
+	res := h.DB.Model(&model.DomainInvitation{}).
+		Where("id = ? AND domain_id = ? AND revoked_at IS NULL", c.Param("invitationId"), domain.ID).
+		Update("revoked_at", time.Now())
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke invitation"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RotateJoinCode POST /domains/:domainId/join-code
+// 生成新的加入码，旧码立即失效
+func (h *DomainInvitationHandler) RotateJoinCode(c *gin.Context) {
+	domain := c.MustGet("domain").(model.Domain)
+	// 加入码较短，极少数情况下会与其他圈子冲突，冲突时重试
+	for attempt := 0; attempt < 3; attempt++ {
+		code, err := utils.GenerateSecureCode(joinCodeLength)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate join code"})
+			return
+		}
+		var count int64
+		h.DB.Model(&model.Domain{}).Where("join_code = ?", code).Count(&count)
+		if count > 0 {
+			continue
+		}
+		if err := h.DB.Model(&model.Domain{}).Where("id = ?", domain.ID).Update("join_code", code).Error; err != nil {
+			continue
+		}
+		c.JSON(http.StatusOK, gin.H{"join_code": code})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate join code"})
+}
+
+// UpdateSettings PATCH /domains/:domainId/settings
+// 只修改请求中出现的设置项
+func (h *DomainInvitationHandler) UpdateSettings(c *gin.Context) {
+	domain := c.MustGet("domain").(model.Domain)
+	var input UpdateDomainSettingsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	updates := map[string]interface{}{}
+	if input.RequireApproval != nil {
+		updates["require_approval"] = *input.RequireApproval
+	}
+	if input.TermStartsAt != nil {
+		updates["term_starts_at"] = *input.TermStartsAt
+	}
+	if input.AllowFork != nil {
+		updates["allow_fork"] = *input.AllowFork
+	}
+	if len(updates) > 0 {
+		if err := h.DB.Model(&model.Domain{}).Where("id = ?", domain.ID).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update domain settings"})
+			return
+		}
+		h.DB.First(&domain, domain.ID)
+	}
+	c.JSON(http.StatusOK, domain)
+}
+
+// ---------------------- 受邀者 ----------------------
+
+// PreviewInvitation GET /invitations/:token
+func (h *DomainInvitationHandler) PreviewInvitation(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	invitation, domain, err := h.resolveInvitation(h.DB, c.Param("token"), userID)
+	if err != nil {
+		RespondJoinError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, InvitationPreview{
+		DomainID:        domain.ID,
+		DomainName:      domain.Name,
+		Description:     domain.Description,
+		RequireApproval: domain.RequireApproval && invitation.TargetUserID == nil,
+		ExpiresAt:       invitation.ExpiresAt,
+	})
+}
+
+// AcceptInvitation POST /invitations/:token/accept
+// 使用次数在加入或提交申请时占用；占用与加入在同一事务中，失败时一并回滚。
+// 占用时以剩余次数为条件更新，并发使用时不会超过上限。
+func (h *DomainInvitationHandler) AcceptInvitation(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	var input AcceptInvitationInput
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var result string
+	var req *model.DomainJoinRequest
+	var domain *model.Domain
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		invitation, d, err := h.resolveInvitation(tx, c.Param("token"), userID)
+		if err != nil {
+			return err
+		}
+		domain = d
+		res := tx.Model(&model.DomainInvitation{}).
+			Where("id = ? AND (max_uses IS NULL OR uses_count < max_uses)", invitation.ID).
+			Update("uses_count", gorm.Expr("uses_count + 1"))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrInvitationInvalid
+		}
+		result, req, err = JoinDomain(tx, domain, userID, invitation, input.Message)
+		return err
+	})
+	if err != nil {
+		RespondJoinError(c, err)
+		return
+	}
+	summary := InvitationDomain{ID: domain.ID, OwnerID: domain.OwnerID, Name: domain.Name, Description: domain.Description}
+	if result == JoinResultPending {
+		c.JSON(http.StatusAccepted, gin.H{"result": result, "request": req, "domain": summary})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": result, "domain": summary})
+}
+
+// resolveInvitation 校验邀请令牌对 userID 是否有效
+func (h *DomainInvitationHandler) resolveInvitation(db *gorm.DB, token string, userID uint) (*model.DomainInvitation, *model.Domain, error) {
+	var invitation model.DomainInvitation
+	if err := db.Where("token = ? AND revoked_at IS NULL", token).First(&invitation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvitationInvalid
+		}
+		return nil, nil, err
+	}
+	if invitation.ExpiresAt != nil && invitation.ExpiresAt.Before(time.Now()) {
+		return nil, nil, ErrInvitationInvalid
+	}
+	if invitation.MaxUses != nil && invitation.UsesCount >= *invitation.MaxUses {
+		return nil, nil, ErrInvitationInvalid
+	}
+	if invitation.TargetUserID != nil && *invitation.TargetUserID != userID {
+		return nil, nil, ErrInvitationInvalid
+	}
+	var domain model.Domain
+	if err := db.First(&domain, invitation.DomainID).Error; err != nil {
+		return nil, nil, ErrInvitationInvalid
+	}
+	return &invitation, &domain, nil
+}
+
+// ---------------------- 加入审核 ----------------------
+
+// ListJoinRequests GET /domains/:domainId/join-requests?status=pending
+func (h *DomainInvitationHandler) ListJoinRequests(c *gin.Context) {
+	domain := c.MustGet("domain").(model.Domain)
+	status := c.DefaultQuery("status", model.JoinRequestPending)
+	var requests []model.DomainJoinRequest
+	if err := h.DB.Preload("User").Where("domain_id = ? AND status = ?", domain.ID, status).
+		Order("created_at").Find(&requests).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list join requests"})
+		return
+	}
+	resp := make([]JoinRequestResponse, len(requests))
+	for i, r := range requests {
+		resp[i] = JoinRequestResponse{
+			DomainJoinRequest: r,
+			User:              AuthorResponse{ID: r.User.ID, Username: r.User.Username, AvatarURL: r.User.AvatarURL},
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ApproveJoinRequest POST /domains/:domainId/join-requests/:requestId/approve
+func (h *DomainInvitationHandler) ApproveJoinRequest(c *gin.Context) {
+	h.review(c, model.JoinRequestApproved)
+}
+
+// RejectJoinRequest POST /domains/:domainId/join-requests/:requestId/reject
+func (h *DomainInvitationHandler) RejectJoinRequest(c *gin.Context) {
+	h.review(c, model.JoinRequestRejected)
+}
+
+func (h *DomainInvitationHandler) review(c *gin.Context, status string) {
+	domain := c.MustGet("domain").(model.Domain)
+	reviewerID := c.MustGet("userID").(uint)
+
+	var req model.DomainJoinRequest
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND domain_id = ? AND status = ?", c.Param("requestId"), domain.ID, model.JoinRequestPending).
+			First(&req).Error; err != nil {
+			return err
+		}
+		if status == model.JoinRequestApproved {
+			// 申请提交后可能已被封禁或已通过其他方式加入
+			var banned int64
+			tx.Model(&model.DomainBan{}).Where("domain_id = ? AND user_id = ?", domain.ID, req.UserID).Count(&banned)
+			if banned > 0 {
+				return ErrDomainBanned
+			}
+			member := model.DomainMember{DomainID: domain.ID, UserID: req.UserID, Role: model.DomainRoleMember}
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&member)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				if err := recordMemberJoined(tx, domain.ID, req.UserID); err != nil {
+					return err
+				}
+			}
+		}
+		now := time.Now()
+		req.Status, req.ReviewedBy, req.ReviewedAt = status, &reviewerID, &now
+		return tx.Model(&req).Updates(map[string]interface{}{
+			"status":      status,
+			"reviewed_by": reviewerID,
+			"reviewed_at": now,
+		}).Error
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Pending join request not found"})
+		case errors.Is(err, ErrDomainBanned):
+			c.JSON(http.StatusConflict, gin.H{"error": "The user is banned from this domain"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to review join request"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, req)
+}
+
+// ListMyJoinRequests GET /domain-join-requests
+// 当前用户提交过的加入申请
+func (h *DomainInvitationHandler) ListMyJoinRequests(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	var requests []model.DomainJoinRequest
+	if err := h.DB.Where("user_id = ?", userID).Order("created_at DESC").Limit(100).Find(&requests).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list join requests"})
+		return
+	}
+	c.JSON(http.StatusOK, requests)
+}
+
+// CancelJoinRequest DELETE /domain-join-requests/:id
+// 撤回尚未审核的申请
+func (h *DomainInvitationHandler) CancelJoinRequest(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	res := h.DB.Where("id = ? AND user_id = ? AND status = ?", c.Param("id"), userID, model.JoinRequestPending).
+		Delete(&model.DomainJoinRequest{})
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel join request"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pending join request not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// recordMemberJoined 记录新成员加入的动态，标题为成员的用户名
+func recordMemberJoined(tx *gorm.DB, domainID, userID uint) error {
+	var username string
+	if err := tx.Model(&model.User{}).Where("id = ?", userID).Pluck("username", &username).Error; err != nil {
+		return err
+	}
+	return RecordDomainActivity(tx, domainID, userID, model.ActivityMemberJoined, userID, username)
+}
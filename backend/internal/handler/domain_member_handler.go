@@ -0,0 +1,323 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/shuind/language-learner/backend/internal/model"
+)
+
+// DomainMemberHandler 处理圈子成员管理：角色、移除、封禁、退出与转让圈主。
+// 路由需挂在 middleware.DomainRoleMiddleware 之后，由它提供 "domain" 与 "domainMember"。
+type DomainMemberHandler struct {
+	DB *gorm.DB
+}
+
+func NewDomainMemberHandler(db *gorm.DB) *DomainMemberHandler {
+	return &DomainMemberHandler{DB: db}
+}
+
+// maxMemberLimit 是成员列表每页的最大条数
+const maxMemberLimit = 100
+
+type DomainMemberResponse struct {
+	User     AuthorResponse `json:"user"`
+	Role     string         `json:"role"`
+	JoinedAt time.Time      `json:"joined_at"`
+}
+
+type DomainBanResponse struct {
+	User      AuthorResponse `json:"user"`
+	BannedBy  uint           `json:"banned_by"`
+	Reason    string         `json:"reason"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+type UpdateMemberRoleInput struct {
+	Role string `json:"role" binding:"required,oneof=admin member"`
+}
+
+type BanMemberInput struct {
+	UserID uint   `json:"user_id" binding:"required"`
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+type TransferOwnershipInput struct {
+	UserID uint `json:"user_id" binding:"required"`
+}
+
+// roleRank 用于比较角色高低，数值越大权限越高
+func roleRank(role string) int {
+	switch role {
+	case model.DomainRoleOwner:
+		return 2
+	case model.DomainRoleAdmin:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// ListMembers GET /domains/:domainId/members?page=&limit=&role=&q=
+// 圈主在前，其次是管理员，同一角色按加入时间排序
+func (h *DomainMemberHandler) ListMembers(c *gin.Context) {
+	domain := c.MustGet("domain").(model.Domain)
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > maxMemberLimit {
+		limit = 20
+	}
+
+	query := h.DB.Model(&model.DomainMember{}).Where("domain_members.domain_id = ?", domain.ID)
+	if role := c.Query("role"); role != "" {
+		query = query.Where("domain_members.role = ?", role)
+	}
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		query = query.Joins("JOIN users ON users.id = domain_members.user_id").
+			Where("users.username ILIKE ?", "%"+escapeLike(q)+"%")
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list members"})
+		return
+	}
+	var members []model.DomainMember
+	if err := query.Preload("User").
+		Order("CASE domain_members.role WHEN 'owner' THEN 0 WHEN 'admin' THEN 1 ELSE 2 END, domain_members.joined_at, domain_members.id").
+		Offset((page - 1) * limit).Limit(limit).Find(&members).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list members"})
+		return
+	}
+
+	resp := make([]DomainMemberResponse, len(members))
+	for i, m := range members {
+		resp[i] = DomainMemberResponse{
+			User:     AuthorResponse{ID: m.User.ID, Username: m.User.Username, AvatarURL: m.User.AvatarURL},
+			Role:     m.Role,
+			JoinedAt: m.JoinedAt,
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"total": total, "page": page, "members": resp})
+}
+
+// UpdateMemberRole PUT /domains/:domainId/members/:userId/role
+// 只有圈主可以任免管理员；圈主本人的角色只能通过转让改变
+func (h *DomainMemberHandler) UpdateMemberRole(c *gin.Context) {
+	domain := c.MustGet("domain").(model.Domain)
+	var input UpdateMemberRoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	target, ok := h.findMember(c, domain.ID)
+	if !ok {
+		return
+	}
+	if target.Role == model.DomainRoleOwner {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The owner's role can only change by transferring ownership"})
+		return
+	}
+	if err := h.DB.Model(&model.DomainMember{}).Where("id = ?", target.ID).Update("role", input.Role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update member role"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"user_id": target.UserID, "role": input.Role})
+}
+
+// RemoveMember DELETE /domains/:domainId/members/:userId
+// 圈主可以移除任何其他成员，管理员只能移除普通成员
+func (h *DomainMemberHandler) RemoveMember(c *gin.Context) {
+	domain := c.MustGet("domain").(model.Domain)
+	actor := c.MustGet("domainMember").(model.DomainMember)
+	target, ok := h.findMember(c, domain.ID)
+	if !ok {
+		return
+	}
+	if !canManage(actor, *target) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied: you cannot remove this member"})
+		return
+	}
+	if err := h.DB.Delete(&model.DomainMember{}, target.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// LeaveDomain POST /domains/:domainId/leave
+// 圈主需要先转让圈子才能退出
+func (h *DomainMemberHandler) LeaveDomain(c *gin.Context) {
+	member := c.MustGet("domainMember").(model.DomainMember)
+	if member.Role == model.DomainRoleOwner {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The owner must transfer ownership before leaving"})
+		return
+	}
+	if err := h.DB.Delete(&model.DomainMember{}, member.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave domain"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ---------------------- 封禁 ----------------------
+
+// ListBans GET /domains/:domainId/bans
+func (h *DomainMemberHandler) ListBans(c *gin.Context) {
+	domain := c.MustGet("domain").(model.Domain)
+	var bans []model.DomainBan
+	if err := h.DB.Preload("User").Where("domain_id = ?", domain.ID).Order("created_at DESC").Find(&bans).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list bans"})
+		return
+	}
+	resp := make([]DomainBanResponse, len(bans))
+	for i, b := range bans {
+		resp[i] = DomainBanResponse{
+			User:      AuthorResponse{ID: b.User.ID, Username: b.User.Username, AvatarURL: b.User.AvatarURL},
+			BannedBy:  b.BannedBy,
+			Reason:    b.Reason,
+			CreatedAt: b.CreatedAt,
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// BanMember POST /domains/:domainId/bans
+// 把用户移出圈子并禁止其再次加入；对象可以是成员，也可以是尚未加入的用户。
+// 与移除成员相同，管理员只能封禁普通成员。
+func (h *DomainMemberHandler) BanMember(c *gin.Context) {
+	domain := c.MustGet("domain").(model.Domain)
+	actor := c.MustGet("domainMember").(model.DomainMember)
+	var input BanMemberInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.UserID == actor.UserID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot ban yourself"})
+		return
+	}
+	var count int64
+	h.DB.Model(&model.User{}).Where("id = ?", input.UserID).Count(&count)
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var target model.DomainMember
+	err := h.DB.Where("domain_id = ? AND user_id = ?", domain.ID, input.UserID).First(&target).Error
+	isMember := err == nil
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load member"})
+		return
+	}
+	if isMember && !canManage(actor, target) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied: you cannot ban this member"})
+		return
+	}
+
+	ban := model.DomainBan{DomainID: domain.ID, UserID: input.UserID, BannedBy: actor.UserID, Reason: input.Reason}
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if isMember {
+			if err := tx.Delete(&model.DomainMember{}, target.ID).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Where("domain_id = ? AND user_id = ?", domain.ID, input.UserID).FirstOrCreate(&ban).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to ban member"})
+		return
+	}
+	c.JSON(http.StatusCreated, ban)
+}
+
+// UnbanMember DELETE /domains/:domainId/bans/:userId
+// 解除封禁后用户可以重新用邀请码加入
+func (h *DomainMemberHandler) UnbanMember(c *gin.Context) {
+	domain := c.MustGet("domain").(model.Domain)
+	result := h.DB.Where("domain_id = ? AND user_id = ?", domain.ID, c.Param("userId")).Delete(&model.DomainBan{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unban member"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ban not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ---------------------- 转让 ----------------------
+
+// TransferOwnership POST /domains/:domainId/transfer
+// 新圈主须是现有成员；原圈主降为管理员。Domain.OwnerID 与双方角色在同一事务中更新，
+// 更新 owner_id 时以原圈主为条件，并发转让时只有一个会成功。
+func (h *DomainMemberHandler) TransferOwnership(c *gin.Context) {
+	domain := c.MustGet("domain").(model.Domain)
+	owner := c.MustGet("domainMember").(model.DomainMember)
+	var input TransferOwnershipInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.UserID == owner.UserID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You already own this domain"})
+		return
+	}
+	var target model.DomainMember
+	if err := h.DB.Where("domain_id = ? AND user_id = ?", domain.ID, input.UserID).First(&target).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "The new owner must be a member of this domain"})
+		return
+	}
+
+	errOwnerChanged := errors.New("owner changed")
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.Domain{}).Where("id = ? AND owner_id = ?", domain.ID, owner.UserID).Update("owner_id", target.UserID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errOwnerChanged
+		}
+		if err := tx.Model(&model.DomainMember{}).Where("id = ?", owner.ID).Update("role", model.DomainRoleAdmin).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.DomainMember{}).Where("id = ?", target.ID).Update("role", model.DomainRoleOwner).Error
+	})
+	if err != nil {
+		if errors.Is(err, errOwnerChanged) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Ownership has already changed"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to transfer ownership"})
+		return
+	}
+	domain.OwnerID = target.UserID
+	c.JSON(http.StatusOK, domain)
+}
+
+// ---------------------- 通用实现 ----------------------
+
+// findMember 按路由中的 :userId 查找圈子成员
+func (h *DomainMemberHandler) findMember(c *gin.Context, domainID uint) (*model.DomainMember, bool) {
+	var member model.DomainMember
+	if err := h.DB.Where("domain_id = ? AND user_id = ?", domainID, c.Param("userId")).First(&member).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return nil, false
+	}
+	return &member, true
+}
+
+// canManage 判断 actor 能否移除或封禁 target：只能管理角色比自己低的成员
+func canManage(actor, target model.DomainMember) bool {
+	return actor.UserID != target.UserID && roleRank(actor.Role) > roleRank(target.Role)
+}
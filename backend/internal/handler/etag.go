@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ErrVersionConflict 表示资源在读取之后已被其他请求修改
+var ErrVersionConflict = errors.New("resource has been modified by another request")
+
+// VersionETag 把版本号编码为 ETag，例如 "v3"
+func VersionETag(version int) string {
+	return `"v` + strconv.Itoa(version) + `"`
+}
+
+// SetVersionETag 在响应头中写入当前版本的 ETag
+func SetVersionETag(c *gin.Context, version int) {
+	c.Header("ETag", VersionETag(version))
+}
+
+// IfMatch 检查 If-Match 请求头是否与当前版本匹配。
+// 未携带该请求头或为 * 时视为匹配，以兼容尚未支持 ETag 的客户端；
+// 也接受不带引号的纯数字版本号。
+func IfMatch(c *gin.Context, current int) bool {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		tag = strings.TrimPrefix(strings.Trim(tag, `"`), "v")
+		if v, err := strconv.Atoi(tag); err == nil && v == current {
+			return true
+		}
+	}
+	return false
+}
+
+// RespondVersionConflict 返回 412，并附带服务器端的当前版本，客户端据此合并后重试
+func RespondVersionConflict(c *gin.Context, version int, current interface{}) {
+	SetVersionETag(c, version)
+	c.JSON(http.StatusPreconditionFailed, gin.H{
+		"error":   "Resource has been modified by another request",
+		"version": version,
+		"current": current,
+	})
+}
+
+// UpdateVersioned 仅当数据库中的版本仍为 version 时执行更新，并把版本号加一。
+// 版本已变化（或记录已不存在）时返回 ErrVersionConflict。
+func UpdateVersioned(tx *gorm.DB, model interface{}, id uint, version int, updates map[string]interface{}) error {
+	updates["version"] = gorm.Expr("version + 1")
+	res := tx.Model(model).Where("id = ? AND version = ?", id, version).Updates(updates)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
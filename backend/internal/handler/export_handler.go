@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/shuind/language-learner/backend/internal/nodecontent"
+	"github.com/shuind/language-learner/backend/internal/nodetree"
+)
+
+// 导出格式
+const (
+	ExportMarkdown = "markdown"
+	ExportJSON     = "json"
+	ExportTSV      = "tsv" // 每行一张卡片，可直接导入 Anki 等闪卡软件
+)
+
+// ExportNode 是 JSON 导出中的一个节点，card / qa 的内容展开为结构化字段
+type ExportNode struct {
+	NodeType string            `json:"node_type"`
+	Title    string            `json:"title"`
+	Content  string            `json:"content,omitempty"`
+	Card     *nodecontent.Card `json:"card,omitempty"`
+	QA       *nodecontent.QA   `json:"qa,omitempty"`
+	Children []*ExportNode     `json:"children,omitempty"`
+}
+
+// ExportMyTree GET /nodes/export?root_id=&format=markdown|json|tsv
+func (h *TreeHandler) ExportMyTree(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	h.export(c, nodetree.Nodes, userID)
+}
+
+// ExportDomainTree GET /domains/:domainId/export?root_id=&format=
+func (h *TreeHandler) ExportDomainTree(c *gin.Context) {
+	domainID, ok := h.requireMember(c)
+	if !ok {
+		return
+	}
+	h.export(c, nodetree.DomainNodes, domainID)
+}
+
+func (h *TreeHandler) export(c *gin.Context, kind nodetree.Kind, ownerID uint) {
+	format := c.DefaultQuery("format", ExportMarkdown)
+	if format != ExportMarkdown && format != ExportJSON && format != ExportTSV {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be one of markdown, json, tsv"})
+		return
+	}
+	rootID, ok := parseRootID(c)
+	if !ok {
+		return
+	}
+
+	entries, err := nodetree.Subtree(h.DB, kind, ownerID, rootID, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load tree"})
+		return
+	}
+	if rootID != nil && len(entries) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found or permission denied"})
+		return
+	}
+	roots, err := buildTree(h.DB, kind, entries, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load tree"})
+		return
+	}
+
+	name := "export"
+	if rootID != nil {
+		name = fmt.Sprintf("node-%d", *rootID)
+	}
+	var body []byte
+	var contentType, ext string
+	switch format {
+	case ExportJSON:
+		body, err = json.MarshalIndent(toExportNodes(roots), "", "  ")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export"})
+			return
+		}
+		contentType, ext = "application/json; charset=utf-8", "json"
+	case ExportTSV:
+		var b strings.Builder
+		writeTSV(&b, roots)
+		body, contentType, ext = []byte(b.String()), "text/tab-separated-values; charset=utf-8", "tsv"
+	default:
+		var b strings.Builder
+		writeMarkdown(&b, roots, 1)
+		body, contentType, ext = []byte(b.String()), "text/markdown; charset=utf-8", "md"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, ext))
+	c.Data(http.StatusOK, contentType, body)
+}
+
+// parseRootID 解析可选的 root_id 查询参数，空、null、0 表示整个工作区
+func parseRootID(c *gin.Context) (*uint, bool) {
+	s := c.Query("root_id")
+	if s == "" || s == "null" || s == "0" {
+		return nil, true
+	}
+	id, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid root_id format"})
+		return nil, false
+	}
+	v := uint(id)
+	return &v, true
+}
+
+func toExportNodes(nodes []*TreeNode) []*ExportNode {
+	out := make([]*ExportNode, 0, len(nodes))
+	for _, n := range nodes {
+		e := &ExportNode{NodeType: n.NodeType, Title: n.Title, Children: toExportNodes(n.Children)}
+		switch n.NodeType {
+		case nodecontent.TypeCard:
+			card := nodecontent.DecodeCard(n.Content)
+			e.Card = &card
+		case nodecontent.TypeQA:
+			qa := nodecontent.DecodeQA(n.Content)
+			e.QA = &qa
+		default:
+			e.Content = n.Content
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// writeMarkdown 以标题层级表示目录结构，超过六级时不再加深
+func writeMarkdown(b *strings.Builder, nodes []*TreeNode, level int) {
+	heading := strings.Repeat("#", min(level, 6))
+	for _, n := range nodes {
+		fmt.Fprintf(b, "%s %s\n\n", heading, n.Title)
+		switch n.NodeType {
+		case nodecontent.TypeCard:
+			card := nodecontent.DecodeCard(n.Content)
+			fmt.Fprintf(b, "**正面**\n\n%s\n\n**背面**\n\n%s\n\n", card.Front, card.Back)
+		case nodecontent.TypeQA:
+			qa := nodecontent.DecodeQA(n.Content)
+			fmt.Fprintf(b, "**问题**\n\n%s\n\n**答案**\n\n%s\n\n", qa.Prompt, qa.Answer)
+		case nodecontent.TypeText:
+			if n.Content != "" {
+				fmt.Fprintf(b, "%s\n\n", n.Content)
+			}
+		}
+		writeMarkdown(b, n.Children, level+1)
+	}
+}
+
+// writeTSV 只导出 card 与 qa 节点，每行为“正面/问题<TAB>背面/答案”
+func writeTSV(b *strings.Builder, nodes []*TreeNode) {
+	for _, n := range nodes {
+		switch n.NodeType {
+		case nodecontent.TypeCard:
+			card := nodecontent.DecodeCard(n.Content)
+			fmt.Fprintf(b, "%s\t%s\n", tsvField(card.Front), tsvField(card.Back))
+		case nodecontent.TypeQA:
+			qa := nodecontent.DecodeQA(n.Content)
+			fmt.Fprintf(b, "%s\t%s\n", tsvField(qa.Prompt), tsvField(qa.Answer))
+		}
+		writeTSV(b, n.Children)
+	}
+}
+
+var tsvReplacer = strings.NewReplacer("\t", " ", "\r\n", "<br>", "\n", "<br>", "\r", "<br>")
+
+// tsvField 把字段中的制表符和换行替换掉，保证每张卡片占一行
+func tsvField(s string) string {
+	return tsvReplacer.Replace(s)
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/shuind/language-learner/backend/internal/model"
+	"github.com/shuind/language-learner/backend/internal/nodetree"
+)
+
+// ForkHandler 把圈子内容保存到成员的个人空间，与发布（个人空间 -> 圈子）方向相反。
+// 圈子内的路由需挂在 middleware.DomainRoleMiddleware 之后。
+type ForkHandler struct {
+	DB *gorm.DB
+}
+
+func NewForkHandler(db *gorm.DB) *ForkHandler {
+	return &ForkHandler{DB: db}
+}
+
+type ForkDomainNodeInput struct {
+	TargetParentID *uint `json:"target_parent_id"` // 个人空间中的目标文件夹，为空表示根目录
+}
+
+// ForkDomainNode POST /domains/:domainId/nodes/:nodeId/fork
+// 复制整棵子树（含标签与逐句译文，不含录音）到当前用户的个人空间，并记录出处
+func (h *ForkHandler) ForkDomainNode(c *gin.Context) {
+	domain := c.MustGet("domain").(model.Domain)
+	member := c.MustGet("domainMember").(model.DomainMember)
+	userID := member.UserID
+	if !domain.AllowFork && member.Role == model.DomainRoleMember {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Saving content from this domain is not allowed"})
+		return
+	}
+	var input ForkDomainNodeInput
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var source model.DomainNode
+	if err := h.DB.Where("id = ? AND domain_id = ?", c.Param("nodeId"), domain.ID).First(&source).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found in this domain"})
+		return
+	}
+
+	var copied model.Node
+	var count int
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if input.TargetParentID != nil {
+			if err := checkTargetFolder(tx, userID, *input.TargetParentID); err != nil {
+				return err
+			}
+		}
+		newID, n, err := nodetree.CopySubtree(tx, nodetree.DomainNodes, source.ID, nodetree.CopyTarget{
+			Kind:        nodetree.Nodes,
+			OwnerID:     userID,
+			ParentID:    input.TargetParentID,
+			AfterCreate: RevisionRecorder(model.RevisionKindNode, userID),
+		})
+		if err != nil {
+			return err
+		}
+		count = n
+		fork := model.NodeFork{
+			UserID:       userID,
+			NodeID:       newID,
+			DomainID:     domain.ID,
+			DomainNodeID: source.ID,
+			DomainName:   domain.Name,
+			SourceTitle:  source.Title,
+		}
+		if err := tx.Create(&fork).Error; err != nil {
+			return err
+		}
+		return tx.First(&copied, newID).Error
+	})
+	if err != nil {
+		if errors.Is(err, errTargetFolder) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Target folder not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save domain content"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"node": copied, "copied": count})
+}
+
+// GetNodeAttribution GET /nodes/:id/attribution
+// 返回节点（或其最近的祖先）保存自哪个圈子；不是从圈子保存的节点返回 404
+func (h *ForkHandler) GetNodeAttribution(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	var node model.Node
+	if err := h.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&node).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
+		return
+	}
+	ids, err := nodetree.AncestorIDs(h.DB, nodetree.Nodes, node.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load attribution"})
+		return
+	}
+
+	var forks []model.NodeFork
+	if err := h.DB.Where("user_id = ? AND node_id IN ?", userID, ids).Find(&forks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load attribution"})
+		return
+	}
+	if len(forks) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "This node was not saved from a domain"})
+		return
+	}
+	// ids 从根节点排到节点自身；多次保存嵌套在一起时，以离节点最近的一次为准
+	depth := make(map[uint]int, len(ids))
+	for i, id := range ids {
+		depth[id] = i
+	}
+	nearest := forks[0]
+	for _, f := range forks[1:] {
+		if depth[f.NodeID] > depth[nearest.NodeID] {
+			nearest = f
+		}
+	}
+	c.JSON(http.StatusOK, nearest)
+}
+
+// ListMyForks GET /forks
+func (h *ForkHandler) ListMyForks(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	var forks []model.NodeFork
+	if err := h.DB.Where("user_id = ?", userID).Order("created_at DESC").Limit(100).Find(&forks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list saved content"})
+		return
+	}
+	c.JSON(http.StatusOK, forks)
+}
@@ -0,0 +1,238 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/shuind/language-learner/backend/internal/model"
+	"github.com/shuind/language-learner/backend/internal/nodetree"
+)
+
+// NodeBatchHandler 处理个人节点的批量树操作：复制、创建副本与批量移动
+type NodeBatchHandler struct {
+	DB *gorm.DB
+}
+
+func NewNodeBatchHandler(db *gorm.DB) *NodeBatchHandler {
+	return &NodeBatchHandler{DB: db}
+}
+
+// duplicateSuffix 追加在“创建副本”生成的根节点标题后
+const duplicateSuffix = " (副本)"
+
+// maxBulkMove 限制一次批量移动的节点数
+const maxBulkMove = 200
+
+type CopyNodeInput struct {
+	TargetParentID *uint `json:"target_parent_id"` // null 表示复制到根目录
+}
+
+type BulkMoveInput struct {
+	NodeIDs     []uint `json:"node_ids" binding:"required,min=1"`
+	NewParentID *uint  `json:"new_parent_id"`
+}
+
+// errTargetFolder 表示目标文件夹不存在、不属于当前用户或不是文件夹
+var errTargetFolder = errors.New("target folder not found")
+
+// errCyclicMove 表示目标位于被移动节点的子树中
+var errCyclicMove = errors.New("cannot move a node into its own descendant")
+
+// DuplicateNode POST /nodes/:id/duplicate
+// 在原节点旁边（同一父节点下，紧随其后）创建整棵子树的副本
+func (h *NodeBatchHandler) DuplicateNode(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	source, ok := h.loadOwnedNode(c, userID)
+	if !ok {
+		return
+	}
+
+	h.copySubtree(c, userID, source, nodetree.CopyTarget{
+		Kind:      nodetree.Nodes,
+		OwnerID:   userID,
+		ParentID:  source.ParentID,
+		RootTitle: truncateTitle(source.Title + duplicateSuffix),
+	}, &source.ID)
+}
+
+// CopyNode POST /nodes/:id/copy
+// 把整棵子树复制到指定文件夹下
+func (h *NodeBatchHandler) CopyNode(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	var input CopyNodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	source, ok := h.loadOwnedNode(c, userID)
+	if !ok {
+		return
+	}
+
+	h.copySubtree(c, userID, source, nodetree.CopyTarget{
+		Kind:     nodetree.Nodes,
+		OwnerID:  userID,
+		ParentID: input.TargetParentID,
+	}, nil)
+}
+
+// BulkMoveNodes PUT /nodes/bulk-move
+// 在一个事务中把多个节点移动到同一文件夹下，任一节点校验失败则全部不生效
+func (h *NodeBatchHandler) BulkMoveNodes(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	var input BulkMoveInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(input.NodeIDs) > maxBulkMove {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many nodes in one request"})
+		return
+	}
+
+	// 去重，保持请求中的顺序
+	seen := make(map[uint]bool, len(input.NodeIDs))
+	ids := make([]uint, 0, len(input.NodeIDs))
+	for _, id := range input.NodeIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&model.Node{}).Where("id IN ? AND user_id = ?", ids, userID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count != int64(len(ids)) {
+			return gorm.ErrRecordNotFound
+		}
+
+		if input.NewParentID != nil {
+			if err := checkTargetFolder(tx, userID, *input.NewParentID); err != nil {
+				return err
+			}
+			// 目标文件夹的祖先链中不能包含任何被移动的节点
+			path, err := nodetree.AncestorIDs(tx, nodetree.Nodes, *input.NewParentID)
+			if err != nil {
+				return err
+			}
+			for _, id := range path {
+				if seen[id] {
+					return errCyclicMove
+				}
+			}
+		}
+
+		// 按请求顺序依次排到目标文件夹末尾
+		pos, err := nodetree.NextPosition(tx, nodetree.Nodes, userID, input.NewParentID)
+		if err != nil {
+			return err
+		}
+		for i, id := range ids {
+			if err := tx.Model(&model.Node{}).Where("id = ?", id).Updates(map[string]interface{}{
+				"parent_id": input.NewParentID,
+				"position":  pos + nodetree.PositionGap*float64(i),
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "One or more nodes not found or permission denied"})
+		case errors.Is(err, errTargetFolder):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Target folder not found"})
+		case errors.Is(err, errCyclicMove):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot move a parent node into its own descendant"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move nodes"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Nodes moved successfully", "moved": len(ids)})
+}
+
+// ---------------------- 通用实现 ----------------------
+
+func (h *NodeBatchHandler) loadOwnedNode(c *gin.Context, userID uint) (*model.Node, bool) {
+	nodeID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid node ID"})
+		return nil, false
+	}
+	var node model.Node
+	if err := h.DB.Where("id = ? AND user_id = ?", nodeID, userID).First(&node).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found or permission denied"})
+		return nil, false
+	}
+	return &node, true
+}
+
+// copySubtree 在事务中校验目标位置并执行复制，成功后返回副本根节点
+// afterID 不为空时把副本放在该同级节点之后，否则追加到目标文件夹末尾
+func (h *NodeBatchHandler) copySubtree(c *gin.Context, userID uint, source *model.Node, target nodetree.CopyTarget, afterID *uint) {
+	target.AfterCreate = RevisionRecorder(model.RevisionKindNode, userID)
+
+	var copied model.Node
+	var count int
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if target.ParentID != nil {
+			if err := checkTargetFolder(tx, userID, *target.ParentID); err != nil {
+				return err
+			}
+		}
+		newID, n, err := nodetree.CopySubtree(tx, nodetree.Nodes, source.ID, target)
+		if err != nil {
+			return err
+		}
+		count = n
+		if afterID != nil {
+			if _, err := nodetree.MoveAfter(tx, nodetree.Nodes, userID, target.ParentID, newID, afterID); err != nil {
+				return err
+			}
+		}
+		return tx.First(&copied, newID).Error
+	})
+	if err != nil {
+		if errors.Is(err, errTargetFolder) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Target folder not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to copy node"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"node": copied, "copied": count})
+}
+
+// checkTargetFolder 校验目标是当前用户名下的文件夹
+func checkTargetFolder(tx *gorm.DB, userID, folderID uint) error {
+	var count int64
+	if err := tx.Model(&model.Node{}).
+		Where("id = ? AND user_id = ? AND node_type = ?", folderID, userID, "folder").
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return errTargetFolder
+	}
+	return nil
+}
+
+// truncateTitle 保证追加后缀后的标题不超过 varchar(255)
+func truncateTitle(title string) string {
+	runes := []rune(title)
+	if len(runes) > 255 {
+		return string(runes[:255])
+	}
+	return title
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/shuind/language-learner/backend/internal/model"
+)
+
+// NodeFilter 是个人节点的标签/复习筛选条件，列表、搜索与保存的筛选共用
+type NodeFilter struct {
+	TagIDs       []uint
+	Mode         string // model.TagModeAny 或 model.TagModeAll
+	DueForReview bool
+}
+
+var errInvalidTagMode = errors.New("tag_mode must be 'and' or 'or'")
+
+// Empty 表示没有任何筛选条件
+func (f NodeFilter) Empty() bool {
+	return len(f.TagIDs) == 0 && !f.DueForReview
+}
+
+// ParseNodeFilter 读取查询参数 tags=1,2&tag_mode=and&due=true
+func ParseNodeFilter(c *gin.Context) (NodeFilter, error) {
+	f := NodeFilter{Mode: c.DefaultQuery("tag_mode", model.TagModeAny)}
+	if f.Mode != model.TagModeAny && f.Mode != model.TagModeAll {
+		return f, errInvalidTagMode
+	}
+	if s := c.Query("tags"); s != "" {
+		for _, part := range strings.Split(s, ",") {
+			id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 32)
+			if err != nil {
+				return f, errors.New("invalid tag id: " + part)
+			}
+			f.TagIDs = append(f.TagIDs, uint(id))
+		}
+	}
+	f.DueForReview = c.Query("due") == "true"
+	return f, nil
+}
+
+// FilterFromSaved 把保存的筛选转换为 NodeFilter
+func FilterFromSaved(sf *model.SavedFilter) NodeFilter {
+	f := NodeFilter{Mode: sf.Mode, DueForReview: sf.DueForReview}
+	for _, t := range sf.Tags {
+		f.TagIDs = append(f.TagIDs, t.ID)
+	}
+	return f
+}
+
+// Scope 返回按筛选条件限定节点的查询条件，idColumn 是查询中节点 ID 所在的列
+func (f NodeFilter) Scope(idColumn string, userID uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(f.TagIDs) > 0 {
+			// 只认当前用户自己的标签，防止通过他人的标签 ID 探测数据
+			sub := "SELECT nt.node_id FROM node_tags nt JOIN tags t ON t.id = nt.tag_id WHERE t.user_id = ? AND nt.tag_id IN ?"
+			if f.Mode == model.TagModeAll {
+				sub += " GROUP BY nt.node_id HAVING COUNT(DISTINCT nt.tag_id) = ?"
+				db = db.Where(idColumn+" IN ("+sub+")", userID, f.TagIDs, len(uniqueIDs(f.TagIDs)))
+			} else {
+				db = db.Where(idColumn+" IN ("+sub+")", userID, f.TagIDs)
+			}
+		}
+		if f.DueForReview {
+			db = db.Where(idColumn+" IN (SELECT node_id FROM node_reviews WHERE user_id = ? AND due_at <= NOW())", userID)
+		}
+		return db
+	}
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	out := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
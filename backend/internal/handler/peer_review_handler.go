@@ -0,0 +1,688 @@
+package handler
+
+import (
+	"errors"
+	"math/rand"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/shuind/language-learner/backend/internal/model"
+	"github.com/shuind/language-learner/backend/internal/nodecontent"
+)
+
+// PeerReviewHandler 处理圈子的评分量表与录音互评。
+// 圈子内的路由需挂在 middleware.DomainRoleMiddleware 之后。
+type PeerReviewHandler struct {
+	DB *gorm.DB
+}
+
+func NewPeerReviewHandler(db *gorm.DB) *PeerReviewHandler {
+	return &PeerReviewHandler{DB: db}
+}
+
+type RubricCriterionInput struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Description string `json:"description"`
+	MaxPoints   int    `json:"max_points" binding:"required,min=1,max=100"`
+}
+
+type RubricInput struct {
+	Name        string                 `json:"name" binding:"required,max=100"`
+	Description string                 `json:"description"`
+	Criteria    []RubricCriterionInput `json:"criteria" binding:"required,min=1,max=20,dive"`
+}
+
+type CreatePeerReviewRoundInput struct {
+	Title            string     `json:"title" binding:"required,max=255"`
+	RubricID         uint       `json:"rubric_id" binding:"required"`
+	NodeIDs          []uint     `json:"node_ids" binding:"required,min=1,max=50"`
+	ReviewsPerMember int        `json:"reviews_per_member" binding:"required,min=1,max=10"`
+	Anonymous        *bool      `json:"anonymous"` // 默认匿名
+	DueAt            *time.Time `json:"due_at"`
+}
+
+type PeerReviewScoreInput struct {
+	CriterionID uint   `json:"criterion_id" binding:"required"`
+	Points      int    `json:"points" binding:"min=0"`
+	Comment     string `json:"comment" binding:"max=2000"`
+}
+
+type SubmitPeerReviewInput struct {
+	Scores  []PeerReviewScoreInput `json:"scores" binding:"required,min=1,dive"`
+	Comment string                 `json:"comment" binding:"max=5000"`
+}
+
+// PeerReviewTask 是评审者看到的一条待评审录音；匿名轮次不返回作者
+type PeerReviewTask struct {
+	model.PeerReviewAssignment
+	AudioURL  string          `json:"audio_url"`
+	NodeID    *uint           `json:"domain_node_id"`
+	NodeTitle string          `json:"node_title"`
+	Author    *AuthorResponse `json:"author,omitempty"`
+}
+
+// CriterionAggregate 是一个评分项的汇总
+type CriterionAggregate struct {
+	CriterionID uint     `json:"criterion_id"`
+	Name        string   `json:"name"`
+	MaxPoints   int      `json:"max_points"`
+	Average     *float64 `json:"average"`
+	Reviews     int      `json:"reviews"`
+}
+
+// ReceivedReview 是作者收到的一条评审；匿名轮次不返回评审者
+type ReceivedReview struct {
+	RoundID     uint                    `json:"round_id"`
+	Reviewer    *AuthorResponse         `json:"reviewer,omitempty"`
+	Comment     string                  `json:"comment"`
+	SubmittedAt *time.Time              `json:"submitted_at"`
+	Scores      []model.PeerReviewScore `json:"scores"`
+}
+
+var (
+	errRubricInUse     = errors.New("rubric is used by a peer review round")
+	errNoSubmissions   = errors.New("no recordings to review")
+	errRoundNodes      = errors.New("round nodes must be recitable nodes of this domain")
+	errInvalidScores   = errors.New("scores must cover every criterion of the rubric within its point scale")
+	errRoundNotOpen    = errors.New("peer review round is closed")
+	errRubricNotFound  = errors.New("rubric not found")
+	errReviewForbidden = errors.New("not allowed to view these reviews")
+)
+
+// ---------------------- 评分量表 ----------------------
+
+// ListRubrics GET /domains/:domainId/rubrics
+func (h *PeerReviewHandler) ListRubrics(c *gin.Context) {
+	domain := c.MustGet("domain").(model.Domain)
+	var rubrics []model.Rubric
+	if err := h.DB.Preload("Criteria", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Where("domain_id = ?", domain.ID).Order("created_at DESC").Find(&rubrics).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list rubrics"})
+		return
+	}
+	c.JSON(http.StatusOK, rubrics)
+}
+
+// CreateRubric POST /domains/:domainId/rubrics
+func (h *PeerReviewHandler) CreateRubric(c *gin.Context) {
+	domain := c.MustGet("domain").(model.Domain)
+	var input RubricInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rubric := model.Rubric{
+		DomainID:    domain.ID,
+		CreatedBy:   c.MustGet("userID").(uint),
+		Name:        input.Name,
+		Description: input.Description,
+		Criteria:    criteriaFromInput(input.Criteria),
+	}
+	if err := h.DB.Create(&rubric).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create rubric"})
+		return
+	}
+	c.JSON(http.StatusCreated, rubric)
+}
+
+// UpdateRubric PUT /domains/:domainId/rubrics/:rubricId
+// 已被互评使用的量表只能修改名称与说明，评分项不能再变
+func (h *PeerReviewHandler) UpdateRubric(c *gin.Context) {
+	rubric, ok := h.findRubric(c)
+	if !ok {
+		return
+	}
+	var input RubricInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(rubric).Updates(map[string]interface{}{
+			"name":        input.Name,
+			"description": input.Description,
+		}).Error; err != nil {
+			return err
+		}
+		if criteriaEqual(rubric.Criteria, input.Criteria) {
+			return nil
+		}
+		var used int64
+		tx.Model(&model.PeerReviewRound{}).Where("rubric_id = ?", rubric.ID).Count(&used)
+		if used > 0 {
+			return errRubricInUse
+		}
+		if err := tx.Where("rubric_id = ?", rubric.ID).Delete(&model.RubricCriterion{}).Error; err != nil {
+			return err
+		}
+		criteria := criteriaFromInput(input.Criteria)
+		for i := range criteria {
+			criteria[i].RubricID = rubric.ID
+		}
+		return tx.Create(&criteria).Error
+	})
+	if err != nil {
+		if errors.Is(err, errRubricInUse) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Criteria of a rubric in use cannot be changed"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update rubric"})
+		return
+	}
+	if rubric, ok = h.findRubric(c); ok {
+		c.JSON(http.StatusOK, rubric)
+	}
+}
+
+// DeleteRubric DELETE /domains/:domainId/rubrics/:rubricId
+// 量表为软删除，已有互评的结果仍可查看
+func (h *PeerReviewHandler) DeleteRubric(c *gin.Context) {
+	rubric, ok := h.findRubric(c)
+	if !ok {
+		return
+	}
+	if err := h.DB.Delete(rubric).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete rubric"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *PeerReviewHandler) findRubric(c *gin.Context) (*model.Rubric, bool) {
+	domain := c.MustGet("domain").(model.Domain)
+	var rubric model.Rubric
+	if err := h.DB.Preload("Criteria", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Where("id = ? AND domain_id = ?", c.Param("rubricId"), domain.ID).First(&rubric).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rubric not found"})
+		return nil, false
+	}
+	return &rubric, true
+}
+
+func criteriaFromInput(input []RubricCriterionInput) []model.RubricCriterion {
+	criteria := make([]model.RubricCriterion, len(input))
+	for i, in := range input {
+		criteria[i] = model.RubricCriterion{Name: in.Name, Description: in.Description, MaxPoints: in.MaxPoints, Position: i}
+	}
+	return criteria
+}
+
+func criteriaEqual(existing []model.RubricCriterion, input []RubricCriterionInput) bool {
+	if len(existing) != len(input) {
+		return false
+	}
+	for i, in := range input {
+		e := existing[i]
+		if e.Name != in.Name || e.Description != in.Description || e.MaxPoints != in.MaxPoints {
+			return false
+		}
+	}
+	return true
+}
+
+// ---------------------- 互评轮次 ----------------------
+
+// CreatePeerReviewRound POST /domains/:domainId/peer-reviews
+// 每位作者在每个指定节点上取最新的一条已完成录音；每位普通成员分到 K 条他人的录音，
+// 分配时优先选择被分配次数最少的录音，使每条录音得到的评审数尽量均衡
+func (h *PeerReviewHandler) CreatePeerReviewRound(c *gin.Context) {
+	domain := c.MustGet("domain").(model.Domain)
+	var input CreatePeerReviewRoundInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	round := model.PeerReviewRound{
+		DomainID:         domain.ID,
+		RubricID:         input.RubricID,
+		CreatedBy:        c.MustGet("userID").(uint),
+		Title:            input.Title,
+		ReviewsPerMember: input.ReviewsPerMember,
+		Anonymous:        input.Anonymous == nil || *input.Anonymous,
+		DueAt:            input.DueAt,
+		Status:           model.PeerReviewOpen,
+	}
+
+	var assigned int
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		var rubrics int64
+		tx.Model(&model.Rubric{}).Where("id = ? AND domain_id = ?", input.RubricID, domain.ID).Count(&rubrics)
+		if rubrics == 0 {
+			return errRubricNotFound
+		}
+		var nodes []model.DomainNode
+		if err := tx.Where("id IN ? AND domain_id = ?", input.NodeIDs, domain.ID).Find(&nodes).Error; err != nil {
+			return err
+		}
+		if len(nodes) == 0 {
+			return errRoundNodes
+		}
+		nodeIDs := make([]uint, len(nodes))
+		for i, n := range nodes {
+			if !nodecontent.Recitable(n.NodeType) {
+				return errRoundNodes
+			}
+			nodeIDs[i] = n.ID
+		}
+
+		// 每位仍在圈子中的作者在每个节点上最新的一条录音
+		type submission struct {
+			ID     uint
+			UserID uint
+		}
+		var submissions []submission
+		if err := tx.Raw(`
+            SELECT DISTINCT ON (r.user_id, r.domain_node_id) r.id, r.user_id
+            FROM recordings r
+            JOIN domain_members m ON m.user_id = r.user_id AND m.domain_id = ?
+            WHERE r.domain_node_id IN ? AND r.status = 'completed' AND r.deleted_at IS NULL
+            ORDER BY r.user_id, r.domain_node_id, r.created_at DESC
+        `, domain.ID, nodeIDs).Scan(&submissions).Error; err != nil {
+			return err
+		}
+		var reviewers []uint
+		if err := tx.Model(&model.DomainMember{}).
+			Where("domain_id = ? AND role = ?", domain.ID, model.DomainRoleMember).
+			Pluck("user_id", &reviewers).Error; err != nil {
+			return err
+		}
+
+		load := make(map[uint]int, len(submissions)) // 录音 ID -> 已分配的评审数
+		var rows []model.PeerReviewAssignment
+		rand.Shuffle(len(reviewers), func(i, j int) { reviewers[i], reviewers[j] = reviewers[j], reviewers[i] })
+		for _, reviewer := range reviewers {
+			candidates := make([]submission, 0, len(submissions))
+			for _, s := range submissions {
+				if s.UserID != reviewer {
+					candidates = append(candidates, s)
+				}
+			}
+			rand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+			sort.SliceStable(candidates, func(i, j int) bool { return load[candidates[i].ID] < load[candidates[j].ID] })
+			for _, s := range candidates[:min(round.ReviewsPerMember, len(candidates))] {
+				load[s.ID]++
+				rows = append(rows, model.PeerReviewAssignment{ReviewerID: reviewer, RecordingID: s.ID})
+			}
+		}
+		if len(rows) == 0 {
+			return errNoSubmissions
+		}
+
+		if err := tx.Omit("Nodes").Create(&round).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&round).Association("Nodes").Replace(nodes); err != nil {
+			return err
+		}
+		for i := range rows {
+			rows[i].RoundID = round.ID
+		}
+		assigned = len(rows)
+		return tx.Create(&rows).Error
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, errRubricNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Rubric not found"})
+		case errors.Is(err, errRoundNodes):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, errNoSubmissions):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "There are no recordings from other members to review"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create peer review round"})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"round": round, "assignments": assigned})
+}
+
+// ListPeerReviewRounds GET /domains/:domainId/peer-reviews
+// 每轮附带当前用户待评审与已评审的数量
+func (h *PeerReviewHandler) ListPeerReviewRounds(c *gin.Context) {
+	domain := c.MustGet("domain").(model.Domain)
+	userID := c.MustGet("userID").(uint)
+	var rounds []model.PeerReviewRound
+	if err := h.DB.Where("domain_id = ?", domain.ID).Order("created_at DESC").Find(&rounds).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list peer review rounds"})
+		return
+	}
+	type progress struct {
+		RoundID   uint
+		Total     int
+		Submitted int
+	}
+	var rows []progress
+	if err := h.DB.Model(&model.PeerReviewAssignment{}).
+		Select("round_id, COUNT(*) AS total, COUNT(submitted_at) AS submitted").
+		Where("reviewer_id = ? AND round_id IN (?)", userID,
+			h.DB.Model(&model.PeerReviewRound{}).Select("id").Where("domain_id = ?", domain.ID)).
+		Group("round_id").Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load peer review progress"})
+		return
+	}
+	byRound := make(map[uint]progress, len(rows))
+	for _, r := range rows {
+		byRound[r.RoundID] = r
+	}
+
+	type item struct {
+		model.PeerReviewRound
+		MyAssigned  int `json:"my_assigned"`
+		MySubmitted int `json:"my_submitted"`
+	}
+	resp := make([]item, len(rounds))
+	for i, r := range rounds {
+		p := byRound[r.ID]
+		resp[i] = item{PeerReviewRound: r, MyAssigned: p.Total, MySubmitted: p.Submitted}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetPeerReviewRound GET /domains/:domainId/peer-reviews/:roundId
+// 圈主与管理员查看每位评审者的完成情况
+func (h *PeerReviewHandler) GetPeerReviewRound(c *gin.Context) {
+	round, ok := h.findRound(c)
+	if !ok {
+		return
+	}
+	type row struct {
+		ReviewerID uint
+		Username   string
+		AvatarURL  string
+		Total      int
+		Submitted  int
+	}
+	var rows []row
+	if err := h.DB.Table("peer_review_assignments a").
+		Select("a.reviewer_id, u.username, u.avatar_url, COUNT(*) AS total, COUNT(a.submitted_at) AS submitted").
+		Joins("JOIN users u ON u.id = a.reviewer_id").
+		Where("a.round_id = ?", round.ID).
+		Group("a.reviewer_id, u.username, u.avatar_url").
+		Order("u.username").Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load peer review round"})
+		return
+	}
+	type reviewer struct {
+		User      AuthorResponse `json:"user"`
+		Assigned  int            `json:"assigned"`
+		Submitted int            `json:"submitted"`
+	}
+	reviewers := make([]reviewer, len(rows))
+	for i, r := range rows {
+		reviewers[i] = reviewer{
+			User:      AuthorResponse{ID: r.ReviewerID, Username: r.Username, AvatarURL: r.AvatarURL},
+			Assigned:  r.Total,
+			Submitted: r.Submitted,
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"round": round, "reviewers": reviewers})
+}
+
+// ClosePeerReviewRound POST /domains/:domainId/peer-reviews/:roundId/close
+// 关闭后不能再提交或修改评审
+func (h *PeerReviewHandler) ClosePeerReviewRound(c *gin.Context) {
+	round, ok := h.findRound(c)
+	if !ok {
+		return
+	}
+	if err := h.DB.Model(round).Update("status", model.PeerReviewClosed).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to close peer review round"})
+		return
+	}
+	round.Status = model.PeerReviewClosed
+	c.JSON(http.StatusOK, round)
+}
+
+func (h *PeerReviewHandler) findRound(c *gin.Context) (*model.PeerReviewRound, bool) {
+	domain := c.MustGet("domain").(model.Domain)
+	var round model.PeerReviewRound
+	if err := h.DB.Preload("Nodes").Where("id = ? AND domain_id = ?", c.Param("roundId"), domain.ID).First(&round).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Peer review round not found"})
+		return nil, false
+	}
+	return &round, true
+}
+
+// ---------------------- 评审者 ----------------------
+
+// ListMyPeerReviews GET /domains/:domainId/peer-reviews/:roundId/my-reviews
+// 返回分配给当前用户的录音、已提交的评分以及本轮使用的量表
+func (h *PeerReviewHandler) ListMyPeerReviews(c *gin.Context) {
+	round, ok := h.findRound(c)
+	if !ok {
+		return
+	}
+	userID := c.MustGet("userID").(uint)
+	var assignments []model.PeerReviewAssignment
+	if err := h.DB.Preload("Scores").Where("round_id = ? AND reviewer_id = ?", round.ID, userID).
+		Order("id").Find(&assignments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load reviews"})
+		return
+	}
+
+	recordings := make(map[uint]model.Recording, len(assignments))
+	if len(assignments) > 0 {
+		ids := make([]uint, len(assignments))
+		for i, a := range assignments {
+			ids[i] = a.RecordingID
+		}
+		var rows []model.Recording
+		h.DB.Preload("User").Preload("DomainNode").Where("id IN ?", ids).Find(&rows)
+		for _, r := range rows {
+			recordings[r.ID] = r
+		}
+	}
+	tasks := make([]PeerReviewTask, 0, len(assignments))
+	for _, a := range assignments {
+		rec, ok := recordings[a.RecordingID]
+		if !ok {
+			continue // 录音已被作者删除
+		}
+		task := PeerReviewTask{
+			PeerReviewAssignment: a,
+			AudioURL:             rec.AudioURL,
+			NodeID:               rec.DomainNodeID,
+			NodeTitle:            rec.DomainNode.Title,
+		}
+		if !round.Anonymous {
+			task.Author = &AuthorResponse{ID: rec.User.ID, Username: rec.User.Username, AvatarURL: rec.User.AvatarURL}
+		}
+		tasks = append(tasks, task)
+	}
+
+	var rubric model.Rubric
+	h.DB.Unscoped().Preload("Criteria", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).First(&rubric, round.RubricID)
+	c.JSON(http.StatusOK, gin.H{"round": round, "rubric": rubric, "reviews": tasks})
+}
+
+// SubmitPeerReview PUT /domains/:domainId/peer-reviews/:roundId/reviews/:assignmentId
+// 提交或修改一条评审，须为量表的每个评分项打分；提交后重新汇总录音的互评得分
+func (h *PeerReviewHandler) SubmitPeerReview(c *gin.Context) {
+	round, ok := h.findRound(c)
+	if !ok {
+		return
+	}
+	userID := c.MustGet("userID").(uint)
+	var input SubmitPeerReviewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var assignment model.PeerReviewAssignment
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if round.Status != model.PeerReviewOpen || (round.DueAt != nil && time.Now().After(*round.DueAt)) {
+			return errRoundNotOpen
+		}
+		if err := tx.Where("id = ? AND round_id = ? AND reviewer_id = ?", c.Param("assignmentId"), round.ID, userID).
+			First(&assignment).Error; err != nil {
+			return err
+		}
+		var criteria []model.RubricCriterion
+		if err := tx.Where("rubric_id = ?", round.RubricID).Find(&criteria).Error; err != nil {
+			return err
+		}
+		maxPoints := make(map[uint]int, len(criteria))
+		for _, cr := range criteria {
+			maxPoints[cr.ID] = cr.MaxPoints
+		}
+		seen := make(map[uint]bool, len(input.Scores))
+		for _, s := range input.Scores {
+			limit, ok := maxPoints[s.CriterionID]
+			if !ok || seen[s.CriterionID] || s.Points > limit {
+				return errInvalidScores
+			}
+			seen[s.CriterionID] = true
+		}
+		if len(seen) != len(criteria) {
+			return errInvalidScores
+		}
+
+		if err := tx.Where("assignment_id = ?", assignment.ID).Delete(&model.PeerReviewScore{}).Error; err != nil {
+			return err
+		}
+		scores := make([]model.PeerReviewScore, len(input.Scores))
+		for i, s := range input.Scores {
+			scores[i] = model.PeerReviewScore{AssignmentID: assignment.ID, CriterionID: s.CriterionID, Points: s.Points, Comment: s.Comment}
+		}
+		if err := tx.Create(&scores).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		if err := tx.Model(&assignment).Updates(map[string]interface{}{"comment": input.Comment, "submitted_at": now}).Error; err != nil {
+			return err
+		}
+		assignment.Comment, assignment.SubmittedAt, assignment.Scores = input.Comment, &now, scores
+		return refreshPeerReviewScore(tx, assignment.RecordingID)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Review assignment not found"})
+		case errors.Is(err, errRoundNotOpen):
+			c.JSON(http.StatusConflict, gin.H{"error": "This peer review round is closed"})
+		case errors.Is(err, errInvalidScores):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit review"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, assignment)
+}
+
+// refreshPeerReviewScore 重新计算录音的互评汇总：每条评审的得分率为总分 / 满分，取平均
+func refreshPeerReviewScore(tx *gorm.DB, recordingID uint) error {
+	var result struct {
+		Reviews int
+		Score   *float64
+	}
+	if err := tx.Raw(`
+        SELECT COUNT(*) AS reviews, AVG(t.ratio) AS score FROM (
+            SELECT SUM(s.points)::float / NULLIF(SUM(c.max_points), 0) AS ratio
+            FROM peer_review_assignments a
+            JOIN peer_review_scores s ON s.assignment_id = a.id
+            JOIN rubric_criteria c ON c.id = s.criterion_id
+            WHERE a.recording_id = ? AND a.submitted_at IS NOT NULL
+            GROUP BY a.id
+        ) t
+    `, recordingID).Scan(&result).Error; err != nil {
+		return err
+	}
+	return tx.Model(&model.Recording{}).Where("id = ?", recordingID).Updates(map[string]interface{}{
+		"peer_review_score": result.Score,
+		"peer_review_count": result.Reviews,
+	}).Error
+}
+
+// ---------------------- 录音作者 ----------------------
+
+// GetRecordingPeerReviews GET /recordings/:id/peer-reviews
+// 录音作者与圈主、管理员可以查看各评分项的平均分和收到的评审；匿名轮次只对圈主与管理员显示评审者
+func (h *PeerReviewHandler) GetRecordingPeerReviews(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	var recording model.Recording
+	if err := h.DB.Preload("DomainNode").First(&recording, c.Param("id")).Error; err != nil || recording.DomainNodeID == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Recording not found"})
+		return
+	}
+	manager := isDomainManager(h.DB, recording.DomainNode.DomainID, userID)
+	if recording.UserID != userID && !manager {
+		c.JSON(http.StatusForbidden, gin.H{"error": errReviewForbidden.Error()})
+		return
+	}
+
+	var assignments []model.PeerReviewAssignment
+	if err := h.DB.Preload("Scores").Where("recording_id = ? AND submitted_at IS NOT NULL", recording.ID).
+		Order("submitted_at").Find(&assignments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load reviews"})
+		return
+	}
+	roundIDs := make([]uint, 0, len(assignments))
+	reviewerIDs := make([]uint, 0, len(assignments))
+	for _, a := range assignments {
+		roundIDs = append(roundIDs, a.RoundID)
+		reviewerIDs = append(reviewerIDs, a.ReviewerID)
+	}
+	rounds := map[uint]model.PeerReviewRound{}
+	users := map[uint]model.User{}
+	var criteria []model.RubricCriterion
+	if len(assignments) > 0 {
+		var rows []model.PeerReviewRound
+		h.DB.Where("id IN ?", roundIDs).Find(&rows)
+		rubricIDs := make([]uint, 0, len(rows))
+		for _, r := range rows {
+			rounds[r.ID] = r
+			rubricIDs = append(rubricIDs, r.RubricID)
+		}
+		var us []model.User
+		h.DB.Where("id IN ?", reviewerIDs).Find(&us)
+		for _, u := range us {
+			users[u.ID] = u
+		}
+		h.DB.Where("rubric_id IN ?", rubricIDs).Order("rubric_id, position").Find(&criteria)
+	}
+
+	// 按评分项汇总
+	type sum struct {
+		total, count int
+	}
+	sums := make(map[uint]*sum, len(criteria))
+	reviews := make([]ReceivedReview, 0, len(assignments))
+	for _, a := range assignments {
+		for _, s := range a.Scores {
+			if sums[s.CriterionID] == nil {
+				sums[s.CriterionID] = &sum{}
+			}
+			sums[s.CriterionID].total += s.Points
+			sums[s.CriterionID].count++
+		}
+		review := ReceivedReview{RoundID: a.RoundID, Comment: a.Comment, SubmittedAt: a.SubmittedAt, Scores: a.Scores}
+		if !rounds[a.RoundID].Anonymous || manager {
+			u := users[a.ReviewerID]
+			review.Reviewer = &AuthorResponse{ID: u.ID, Username: u.Username, AvatarURL: u.AvatarURL}
+		}
+		reviews = append(reviews, review)
+	}
+	aggregates := make([]CriterionAggregate, 0, len(criteria))
+	for _, cr := range criteria {
+		agg := CriterionAggregate{CriterionID: cr.ID, Name: cr.Name, MaxPoints: cr.MaxPoints}
+		if s := sums[cr.ID]; s != nil && s.count > 0 {
+			avg := float64(s.total) / float64(s.count)
+			agg.Average, agg.Reviews = &avg, s.count
+		}
+		aggregates = append(aggregates, agg)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"recording_id":      recording.ID,
+		"peer_review_score": recording.PeerReviewScore,
+		"peer_review_count": recording.PeerReviewCount,
+		"criteria":          aggregates,
+		"reviews":           reviews,
+	})
+}
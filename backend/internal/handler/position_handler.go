@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/shuind/language-learner/backend/internal/model"
+	"github.com/shuind/language-learner/backend/internal/nodetree"
+)
+
+// PositionHandler 处理个人节点与圈子节点的手动排序
+type PositionHandler struct {
+	DB *gorm.DB
+}
+
+func NewPositionHandler(db *gorm.DB) *PositionHandler {
+	return &PositionHandler{DB: db}
+}
+
+// ReorderNodesReq 按给定顺序重排同一文件夹下的节点
+type ReorderNodesReq struct {
+	ParentID *uint  `json:"parent_id"` // null 表示根目录
+	IDs      []uint `json:"ids" binding:"required,min=1"`
+}
+
+// PlaceNodeReq 把节点放到某个同级节点之后，after_id 为 null 表示放到最前面
+type PlaceNodeReq struct {
+	AfterID *uint `json:"after_id"`
+}
+
+// ---------------------- 个人节点 ----------------------
+
+// ReorderNodes PUT /nodes/reorder
+func (h *PositionHandler) ReorderNodes(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	h.reorder(c, nodetree.Nodes, userID)
+}
+
+// PlaceNode PUT /nodes/:id/position
+func (h *PositionHandler) PlaceNode(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	var node model.Node
+	if err := h.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&node).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found or permission denied"})
+		return
+	}
+	h.place(c, nodetree.Nodes, userID, node.ParentID, node.ID)
+}
+
+// ---------------------- 圈子节点 (圈主) ----------------------
+
+// ReorderDomainNodes PUT /domains/:domainId/nodes/reorder
+func (h *PositionHandler) ReorderDomainNodes(c *gin.Context) {
+	domain := c.MustGet("domain").(model.Domain)
+	h.reorder(c, nodetree.DomainNodes, domain.ID)
+}
+
+// PlaceDomainNode PUT /domains/:domainId/nodes/:nodeId/position
+func (h *PositionHandler) PlaceDomainNode(c *gin.Context) {
+	domain := c.MustGet("domain").(model.Domain)
+	nodeID, err := strconv.ParseUint(c.Param("nodeId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid node ID"})
+		return
+	}
+	var node model.DomainNode
+	if err := h.DB.Where("id = ? AND domain_id = ?", nodeID, domain.ID).First(&node).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found in this domain"})
+		return
+	}
+	h.place(c, nodetree.DomainNodes, domain.ID, node.ParentID, node.ID)
+}
+
+// ---------------------- 通用实现 ----------------------
+
+func (h *PositionHandler) reorder(c *gin.Context, kind nodetree.Kind, ownerID uint) {
+	var req ReorderNodesReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad request"})
+		return
+	}
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		return nodetree.Reorder(tx, kind, ownerID, req.ParentID, req.IDs)
+	})
+	if err != nil {
+		if errors.Is(err, nodetree.ErrNotSiblings) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "All nodes must be in the given folder"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *PositionHandler) place(c *gin.Context, kind nodetree.Kind, ownerID uint, parentID *uint, nodeID uint) {
+	var req PlaceNodeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad request"})
+		return
+	}
+	var pos float64
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		pos, err = nodetree.MoveAfter(tx, kind, ownerID, parentID, nodeID, req.AfterID)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, nodetree.ErrNotSiblings) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "after_id must be a sibling of the node"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update position"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": nodeID, "position": pos})
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/shuind/language-learner/backend/internal/model" // 确保此路径与您的项目结构匹配
+	"github.com/shuind/language-learner/backend/internal/search"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause" // 【核心】引入 GORM 的 clause 包
 )
@@ -179,6 +180,7 @@ func (h *PostHandler) GetPost(c *gin.Context) {
 		}
 	}
 
+	SetVersionETag(c, post.Version)
 	postResponse := PostResponse{
 		Post:           post,
 		User:           AuthorResponse{ID: post.User.ID, Username: post.User.Username, AvatarURL: post.User.AvatarURL},
@@ -310,6 +312,9 @@ func (h *PostHandler) CreatePost(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create post"})
 		return
 	}
+	if err := search.IndexPost(h.DB, &newPost); err != nil {
+		log.Printf("ERROR indexing post %d: %v", newPost.ID, err)
+	}
 
 	h.DB.Preload("User").First(&newPost, newPost.ID)
 
@@ -349,15 +354,32 @@ func (h *PostHandler) UpdatePost(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found or permission denied"})
 		return
 	}
+	// 多个标签页同时编辑时，以 If-Match 携带的版本为准，避免后保存的一方静默覆盖
+	if !IfMatch(c, post.Version) {
+		RespondVersionConflict(c, post.Version, post)
+		return
+	}
 
-	post.Title = input.Title
-	post.Content = input.Content
+	updates := map[string]interface{}{"title": input.Title, "content": input.Content}
 	// 如果前端想在更新的同时发布，也可以在这里处理
 	if input.Status == "published" {
-		post.Status = "published"
+		updates["status"] = "published"
 	}
 
-	h.DB.Save(&post)
+	if err := UpdateVersioned(h.DB, &model.Post{}, post.ID, post.Version, updates); err != nil {
+		h.DB.First(&post, post.ID)
+		if errors.Is(err, ErrVersionConflict) {
+			RespondVersionConflict(c, post.Version, post)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update post"})
+		return
+	}
+	h.DB.First(&post, post.ID)
+	if err := search.IndexPost(h.DB, &post); err != nil {
+		log.Printf("ERROR indexing post %d: %v", post.ID, err)
+	}
+	SetVersionETag(c, post.Version)
 	c.JSON(http.StatusOK, post)
 }
 
@@ -483,6 +505,9 @@ func (h *PostHandler) CreateAnswer(c *gin.Context) {
 		if err := tx.Create(&answer).Error; err != nil {
 			return err
 		}
+		if err := search.IndexPost(tx, &answer); err != nil {
+			return err
+		}
 		return tx.Model(&model.Post{}).Where("id = ?", questionID).UpdateColumn("answers_count", gorm.Expr("answers_count + 1")).Error
 	})
 	if err != nil {
@@ -0,0 +1,467 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/minio/minio-go/v7"
+	"gorm.io/gorm"
+
+	"github.com/shuind/language-learner/backend/internal/asr"
+	"github.com/shuind/language-learner/backend/internal/lang"
+	"github.com/shuind/language-learner/backend/internal/model"
+	"github.com/shuind/language-learner/backend/internal/nodecontent"
+	"github.com/shuind/language-learner/backend/internal/scoring"
+	"github.com/shuind/language-learner/backend/internal/tts"
+)
+
+// PracticeHandler 处理跟读练习：节点按句切分，每句配上示范音频，
+// 用户逐句录音后立即得到与示范的对比结果
+type PracticeHandler struct {
+	DB          *gorm.DB
+	Queue       TaskPublisher
+	Minio       *minio.Client
+	Bucket      string
+	Transcriber asr.Transcriber
+}
+
+func NewPracticeHandler(db *gorm.DB, queue TaskPublisher, minioClient *minio.Client, bucket string, transcriber asr.Transcriber) *PracticeHandler {
+	return &PracticeHandler{DB: db, Queue: queue, Minio: minioClient, Bucket: bucket, Transcriber: transcriber}
+}
+
+type CreatePracticeSessionInput struct {
+	Voice string `json:"voice" binding:"max=64"` // 需要 TTS 示范时使用的音色
+}
+
+// SegmentReference 是一句话的示范音频及这句话在其中的起止时间
+type SegmentReference struct {
+	ReferenceAudioID uint   `json:"reference_audio_id"`
+	Source           string `json:"source"`
+	Status           string `json:"status"`
+	AudioURL         string `json:"audio_url"`
+	StartMs          int    `json:"start_ms"`
+	EndMs            int    `json:"end_ms"` // 时长未知时为 0
+	DurationMs       int    `json:"duration_ms"`
+}
+
+// PracticeRecordingResponse 是一次逐句录音及其对比结果
+type PracticeRecordingResponse struct {
+	model.PracticeRecording
+	Alignment *scoring.Alignment `json:"alignment"`
+}
+
+// PracticeSegmentResponse 是会话中的一句话
+type PracticeSegmentResponse struct {
+	lang.Segment
+	Reference  *SegmentReference           `json:"reference"` // 尚无示范音频时为空
+	Recordings []PracticeRecordingResponse `json:"recordings"`
+}
+
+// PracticeSessionResponse 是会话详情
+type PracticeSessionResponse struct {
+	model.PracticeSession
+	Segments []PracticeSegmentResponse `json:"segments"`
+	// 每句取最好的一次录音，对已练习的句子求平均准确率
+	PracticedSegments int      `json:"practiced_segments"`
+	AverageAccuracy   *float64 `json:"average_accuracy"`
+}
+
+// ---------------------- 开始练习 ----------------------
+
+// CreateNodePracticeSession POST /nodes/:id/practice-sessions
+func (h *PracticeHandler) CreateNodePracticeSession(c *gin.Context) {
+	if t, ok := nodeReferenceTarget(c, h.DB); ok {
+		h.create(c, t, c.MustGet("userID").(uint))
+	}
+}
+
+// CreateDomainNodePracticeSession POST /domain-nodes/:id/practice-sessions
+func (h *PracticeHandler) CreateDomainNodePracticeSession(c *gin.Context) {
+	t, ok := domainNodeReferenceTarget(c, h.DB, false)
+	if !ok {
+		return
+	}
+	// 老节点可能还没有修订记录，此时以圈主的名义补建基线版本
+	var domain model.Domain
+	if err := h.DB.Select("id", "owner_id").First(&domain, t.domainID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Domain not found"})
+		return
+	}
+	h.create(c, t, domain.OwnerID)
+}
+
+// create 创建会话：以节点当前的修订版本切分句子，并为还没有示范音频的句子请求 TTS 合成
+func (h *PracticeHandler) create(c *gin.Context, t *referenceAudioTarget, baselineAuthorID uint) {
+	userID := c.MustGet("userID").(uint)
+	var input CreatePracticeSessionInput
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	segments := lang.Segments(nodecontent.Reference(t.nodeType, t.content))
+	if len(segments) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Node has no content to practice"})
+		return
+	}
+	rev, err := RecordRevision(h.DB, t.kind, t.nodeID, baselineAuthorID, t.title, t.content)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve node revision"})
+		return
+	}
+
+	session := model.PracticeSession{
+		UserID:       userID,
+		NodeKind:     t.kind,
+		NodeID:       t.nodeID,
+		RevisionID:   rev.ID,
+		Mode:         model.PracticeModeShadowing,
+		Language:     t.language,
+		Voice:        input.Voice,
+		SegmentCount: len(segments),
+		Status:       model.PracticeSessionActive,
+	}
+	if err := h.DB.Create(&session).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create practice session"})
+		return
+	}
+
+	refs, err := segmentReferences(h.DB, &session, segments, nodecontent.Reference(t.nodeType, t.content))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load reference audio"})
+		return
+	}
+	for i, ref := range refs {
+		if ref != nil {
+			continue
+		}
+		// 合成失败不影响练习，示范音频会在之后的查询中出现
+		if _, err := requestReferenceAudio(c.Request.Context(), h.DB, h.Queue, t, i, segments[i].Text, input.Voice, userID); err != nil {
+			log.Printf("WARN: Failed to request reference audio for segment %d of %s %d: %v", i, t.kind, t.nodeID, err)
+		}
+	}
+	h.respond(c, http.StatusCreated, &session)
+}
+
+// ---------------------- 会话 ----------------------
+
+// ListPracticeSessions GET /practice-sessions?node_kind=&node_id=
+// 返回当前用户的练习会话，最新的在前
+func (h *PracticeHandler) ListPracticeSessions(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	query := h.DB.Where("user_id = ?", userID)
+	if kind := c.Query("node_kind"); kind != "" {
+		query = query.Where("node_kind = ?", kind)
+	}
+	if nodeID := c.Query("node_id"); nodeID != "" {
+		query = query.Where("node_id = ?", nodeID)
+	}
+	var sessions []model.PracticeSession
+	if err := query.Order("created_at DESC").Limit(100).Find(&sessions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load practice sessions"})
+		return
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+// GetPracticeSession GET /practice-sessions/:id
+// 返回每句的文字、示范音频及其时间段，以及已有的录音
+func (h *PracticeHandler) GetPracticeSession(c *gin.Context) {
+	if session, ok := h.findSession(c); ok {
+		h.respond(c, http.StatusOK, session)
+	}
+}
+
+// FinishPracticeSession POST /practice-sessions/:id/finish
+func (h *PracticeHandler) FinishPracticeSession(c *gin.Context) {
+	session, ok := h.findSession(c)
+	if !ok {
+		return
+	}
+	if session.Status != model.PracticeSessionFinished {
+		now := time.Now()
+		if err := h.DB.Model(session).Updates(map[string]interface{}{
+			"status":      model.PracticeSessionFinished,
+			"finished_at": now,
+		}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to finish practice session"})
+			return
+		}
+		session.Status, session.FinishedAt = model.PracticeSessionFinished, &now
+	}
+	h.respond(c, http.StatusOK, session)
+}
+
+func (h *PracticeHandler) findSession(c *gin.Context) (*model.PracticeSession, bool) {
+	userID := c.MustGet("userID").(uint)
+	var session model.PracticeSession
+	if err := h.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&session).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Practice session not found"})
+		return nil, false
+	}
+	return &session, true
+}
+
+// ---------------------- 逐句录音 ----------------------
+
+// maxAttemptSize 限制一句录音的大小
+const maxAttemptSize = 10 << 20
+
+// CreateAttempt POST /practice-sessions/:id/segments/:index/attempts
+// 表单字段：audio_file（录音）、duration_ms（录音时长，由客户端提供，按文件大小截断）。
+// 录音被同步识别并与这句话的示范音频对比，识别失败时只返回时长对比。
+func (h *PracticeHandler) CreateAttempt(c *gin.Context) {
+	// 1. 校验会话与句子
+	session, ok := h.findSession(c)
+	if !ok {
+		return
+	}
+	if session.Status != model.PracticeSessionActive {
+		c.JSON(http.StatusConflict, gin.H{"error": "Practice session is already finished"})
+		return
+	}
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil || index < 0 || index >= session.SegmentCount {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Segment index out of range", "segments": session.SegmentCount})
+		return
+	}
+	durationMs, err := strconv.Atoi(c.PostForm("duration_ms"))
+	if err != nil || durationMs <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "duration_ms must be a positive integer"})
+		return
+	}
+
+	file, err := c.FormFile("audio_file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not get audio file"})
+		return
+	}
+	if file.Size > maxAttemptSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Audio file is too large"})
+		return
+	}
+	durationMs = ClampDurationMs(durationMs, file.Size)
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open uploaded file"})
+		return
+	}
+	audio, err := io.ReadAll(src)
+	src.Close()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file content"})
+		return
+	}
+
+	// 2. 找到这句话及其示范音频
+	text, err := h.sessionText(session)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load session content"})
+		return
+	}
+	segments := lang.Segments(text)
+	if index >= len(segments) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Segment index out of range", "segments": len(segments)})
+		return
+	}
+	refs, err := segmentReferences(h.DB, session, segments, text)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load reference audio"})
+		return
+	}
+
+	// 3. 保存录音
+	contentType := file.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "audio/") {
+		contentType = "audio/webm"
+	}
+	objectName := fmt.Sprintf("practice/%d/%d-%s.%s", session.ID, index, uuid.New().String(), tts.Extension(contentType))
+	if _, err := h.Minio.PutObject(c.Request.Context(), h.Bucket, objectName, bytes.NewReader(audio), int64(len(audio)), minio.PutObjectOptions{
+		ContentType: contentType,
+	}); err != nil {
+		log.Printf("ERROR: Failed to upload practice recording to MinIO: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload file to storage"})
+		return
+	}
+
+	// 4. 识别并与示范对比
+	rec := model.PracticeRecording{
+		SessionID:    session.ID,
+		SegmentIndex: index,
+		AudioURL:     ObjectURL(h.Bucket, objectName),
+		ObjectName:   objectName,
+		DurationMs:   durationMs,
+	}
+	referenceMs := 0
+	if ref := refs[index]; ref != nil && ref.Status == model.ReferenceAudioCompleted {
+		rec.ReferenceAudioID = &ref.ReferenceAudioID
+		referenceMs = ref.EndMs - ref.StartMs
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	recognized, err := h.Transcriber.Transcribe(ctx, audio, session.Language)
+	cancel()
+	var alignment scoring.Alignment
+	if err != nil {
+		log.Printf("WARN: Transcription failed for practice session %d segment %d: %v", session.ID, index, err)
+		rec.AiStatus = "failed"
+		alignment = scoring.AlignDuration(referenceMs, durationMs)
+	} else {
+		rec.AiStatus = "completed"
+		rec.RecognizedText = recognized
+		alignment = scoring.Align(session.Language, segments[index].Text, recognized, referenceMs, durationMs)
+		rec.Accuracy = alignment.Accuracy
+	}
+	encoded, _ := json.Marshal(alignment)
+	rec.Alignment = string(encoded)
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&rec).Error; err != nil {
+			return err
+		}
+		return tx.Model(session).Update("updated_at", time.Now()).Error
+	})
+	if err != nil {
+		h.Minio.RemoveObject(context.Background(), h.Bucket, objectName, minio.RemoveObjectOptions{})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save practice recording"})
+		return
+	}
+	c.JSON(http.StatusCreated, PracticeRecordingResponse{PracticeRecording: rec, Alignment: &alignment})
+}
+
+// ---------------------- 通用实现 ----------------------
+
+// sessionText 返回会话开始时节点的朗读内容
+func (h *PracticeHandler) sessionText(session *model.PracticeSession) (string, error) {
+	var rev model.NodeRevision
+	if err := h.DB.Select("id", "content").First(&rev, session.RevisionID).Error; err != nil {
+		return "", err
+	}
+	nodeType := nodecontent.TypeText
+	var err error
+	switch session.NodeKind {
+	case model.RevisionKindNode:
+		err = h.DB.Unscoped().Model(&model.Node{}).Select("node_type").Where("id = ?", session.NodeID).Scan(&nodeType).Error
+	case model.RevisionKindDomainNode:
+		err = h.DB.Unscoped().Model(&model.DomainNode{}).Select("node_type").Where("id = ?", session.NodeID).Scan(&nodeType).Error
+	}
+	if err != nil {
+		return "", err
+	}
+	return nodecontent.Reference(nodeType, rev.Content), nil
+}
+
+func (h *PracticeHandler) respond(c *gin.Context, status int, session *model.PracticeSession) {
+	text, err := h.sessionText(session)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load session content"})
+		return
+	}
+	segments := lang.Segments(text)
+	refs, err := segmentReferences(h.DB, session, segments, text)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load reference audio"})
+		return
+	}
+	var recordings []model.PracticeRecording
+	if err := h.DB.Where("session_id = ?", session.ID).Order("created_at").Find(&recordings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load practice recordings"})
+		return
+	}
+
+	resp := PracticeSessionResponse{PracticeSession: *session, Segments: make([]PracticeSegmentResponse, len(segments))}
+	for i, s := range segments {
+		resp.Segments[i] = PracticeSegmentResponse{Segment: s, Reference: refs[i], Recordings: make([]PracticeRecordingResponse, 0)}
+	}
+	best := make(map[int]float64)
+	for _, r := range recordings {
+		if r.SegmentIndex >= len(segments) {
+			continue
+		}
+		item := PracticeRecordingResponse{PracticeRecording: r}
+		if r.Alignment != "" {
+			var a scoring.Alignment
+			if json.Unmarshal([]byte(r.Alignment), &a) == nil {
+				item.Alignment = &a
+			}
+		}
+		resp.Segments[r.SegmentIndex].Recordings = append(resp.Segments[r.SegmentIndex].Recordings, item)
+		if r.Accuracy != nil {
+			if prev, ok := best[r.SegmentIndex]; !ok || *r.Accuracy > prev {
+				best[r.SegmentIndex] = *r.Accuracy
+			}
+		}
+	}
+	if len(best) > 0 {
+		var sum float64
+		for _, v := range best {
+			sum += v
+		}
+		avg := sum / float64(len(best))
+		resp.AverageAccuracy = &avg
+	}
+	resp.PracticedSegments = len(best)
+	c.JSON(status, resp)
+}
+
+// segmentReferences 为每句话找到示范音频，依次尝试：
+// 所有者为这句话上传的录音、所有者上传的整段录音中这句话的时间段、会话音色的 TTS 音频。
+// 结果按句子序号排列，找不到时为 nil。
+func segmentReferences(db *gorm.DB, session *model.PracticeSession, segments []lang.Segment, text string) ([]*SegmentReference, error) {
+	var audios []model.ReferenceAudio
+	if err := db.Where("node_kind = ? AND node_id = ? AND status IN ?", session.NodeKind, session.NodeID,
+		[]string{model.ReferenceAudioPending, model.ReferenceAudioProcessing, model.ReferenceAudioCompleted}).
+		Find(&audios).Error; err != nil {
+		return nil, err
+	}
+	type key struct {
+		segment int
+		source  string
+		hash    string
+	}
+	byKey := make(map[key]*model.ReferenceAudio, len(audios))
+	for i := range audios {
+		a := &audios[i]
+		byKey[key{a.SegmentIndex, a.Source, a.ContentHash}] = a
+	}
+
+	clip := func(a *model.ReferenceAudio) *SegmentReference {
+		return &SegmentReference{
+			ReferenceAudioID: a.ID, Source: a.Source, Status: a.Status, AudioURL: a.AudioURL,
+			StartMs: 0, EndMs: a.DurationMs, DurationMs: a.DurationMs,
+		}
+	}
+
+	var timings []model.SegmentTiming
+	whole := byKey[key{model.ReferenceAudioWholeNode, model.ReferenceAudioSourceUpload, tts.CacheKey(text, session.Language, "")}]
+	if whole != nil && whole.Timings != "" {
+		if err := json.Unmarshal([]byte(whole.Timings), &timings); err != nil || len(timings) != len(segments) {
+			timings = nil
+		}
+	}
+
+	refs := make([]*SegmentReference, len(segments))
+	for i, s := range segments {
+		if a := byKey[key{i, model.ReferenceAudioSourceUpload, tts.CacheKey(s.Text, session.Language, "")}]; a != nil {
+			refs[i] = clip(a)
+		} else if timings != nil {
+			refs[i] = &SegmentReference{
+				ReferenceAudioID: whole.ID, Source: whole.Source, Status: whole.Status, AudioURL: whole.AudioURL,
+				StartMs: timings[i].StartMs, EndMs: timings[i].EndMs, DurationMs: whole.DurationMs,
+			}
+		} else if a := byKey[key{i, model.ReferenceAudioSourceTTS, tts.CacheKey(s.Text, session.Language, session.Voice)}]; a != nil {
+			refs[i] = clip(a)
+		}
+	}
+	return refs, nil
+}
@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"github.com/shuind/language-learner/backend/internal/model"
+	"github.com/shuind/language-learner/backend/internal/testdb"
+	"github.com/shuind/language-learner/backend/internal/tts"
+)
+
+// fakeTranscriber 按请求的顺序返回预设的识别结果
+type fakeTranscriber struct {
+	mu        sync.Mutex
+	results   []string
+	errs      []error
+	languages []string
+}
+
+func (f *fakeTranscriber) Transcribe(ctx context.Context, audio []byte, language string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	i := len(f.languages)
+	f.languages = append(f.languages, language)
+	if i < len(f.errs) && f.errs[i] != nil {
+		return "", f.errs[i]
+	}
+	if i < len(f.results) {
+		return f.results[i], nil
+	}
+	return "", nil
+}
+
+// fakeStorage 是只接受上传与删除的对象存储，返回一个指向它的 minio 客户端
+func fakeStorage(t *testing.T) (*minio.Client, *[]string) {
+	t.Helper()
+	var mu sync.Mutex
+	var puts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			mu.Lock()
+			puts = append(puts, r.URL.Path)
+			mu.Unlock()
+			w.Header().Set("ETag", `"d41d8cd98f00b204e9800998ecf8427e"`)
+			w.WriteHeader(http.StatusOK)
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	client, err := minio.New(strings.TrimPrefix(srv.URL, "http://"), &minio.Options{
+		Creds:  credentials.NewStaticV4("test", "testsecret", ""),
+		Region: "us-east-1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, &puts
+}
+
+// attemptRequest 构造逐句录音的上传请求
+func attemptRequest(t *testing.T, sessionID uint, index, durationMs, size int) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("duration_ms", strconv.Itoa(durationMs))
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", `form-data; name="audio_file"; filename="attempt.webm"`)
+	header.Set("Content-Type", "audio/webm")
+	part, err := form.CreatePart(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(bytes.Repeat([]byte{0}, size))
+	form.Close()
+	req := httptest.NewRequest(http.MethodPost,
+		"/practice-sessions/"+strconv.FormatUint(uint64(sessionID), 10)+"/segments/"+strconv.Itoa(index)+"/attempts", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return req
+}
+
+func TestPracticeSessionShadowing(t *testing.T) {
+	db := testdb.Open(t, &model.User{}, &model.Tag{}, &model.Node{}, &model.NodeRevision{}, &model.Annotation{},
+		&model.ReferenceAudio{}, &model.PracticeSession{}, &model.PracticeRecording{})
+	gin.SetMode(gin.TestMode)
+
+	node := model.Node{UserID: 1, NodeType: "text", Title: "Tea", Content: "I like green tea. Good morning.", Language: "en"}
+	if err := db.Create(&node).Error; err != nil {
+		t.Fatal(err)
+	}
+	storage, puts := fakeStorage(t)
+	queue := &fakeQueue{}
+	transcriber := &fakeTranscriber{
+		results: []string{"i like tea"},
+		errs:    []error{nil, errors.New("asr unavailable")},
+	}
+	h := NewPracticeHandler(db, queue, storage, "recordings", transcriber)
+
+	r := gin.New()
+	auth := func(c *gin.Context) { c.Set("userID", uint(1)) }
+	r.POST("/nodes/:id/practice-sessions", auth, h.CreateNodePracticeSession)
+	r.GET("/practice-sessions/:id", auth, h.GetPracticeSession)
+	r.POST("/practice-sessions/:id/segments/:index/attempts", auth, h.CreateAttempt)
+
+	// 1. 开始练习：每句请求一段 TTS 示范音频
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/nodes/"+strconv.FormatUint(uint64(node.ID), 10)+"/practice-sessions", nil))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create session status = %d: %s", w.Code, w.Body.String())
+	}
+	var session PracticeSessionResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &session); err != nil {
+		t.Fatal(err)
+	}
+	if session.SegmentCount != 2 || queue.count() != 2 {
+		t.Fatalf("session has %d segments and queued %d syntheses, want 2 and 2", session.SegmentCount, queue.count())
+	}
+
+	// 2. worker 用 tts.Fake 合成示范音频：每字 0.1 秒，“I like green tea.” 为 1700ms
+	synth := &tts.Fake{}
+	var pending []model.ReferenceAudio
+	db.Where("status = ?", model.ReferenceAudioPending).Find(&pending)
+	for i := range pending {
+		synthesize(t, db, synth, &pending[i])
+	}
+
+	// 3. 第一句漏读 green，用了两倍时长
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, attemptRequest(t, session.ID, 0, 3400, 4000))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("attempt status = %d: %s", w.Code, w.Body.String())
+	}
+	var attempt PracticeRecordingResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &attempt); err != nil {
+		t.Fatal(err)
+	}
+	a := attempt.Alignment
+	if a == nil || a.Accuracy == nil || *a.Accuracy != 0.75 {
+		t.Fatalf("alignment = %+v, want accuracy 0.75", a)
+	}
+	if len(a.MissingWords) != 1 || a.MissingWords[0] != "green" {
+		t.Fatalf("missing words = %q, want [green]", a.MissingWords)
+	}
+	if a.ReferenceDurationMs != 1700 || a.DurationRatio == nil || *a.DurationRatio != 2 {
+		t.Fatalf("duration comparison = %d ms, ratio %v; want 1700 ms, ratio 2", a.ReferenceDurationMs, a.DurationRatio)
+	}
+	if attempt.ReferenceAudioID == nil || attempt.AiStatus != "completed" {
+		t.Fatalf("attempt = %+v, want a completed attempt linked to the reference audio", attempt.PracticeRecording)
+	}
+	if len(*puts) != 1 || !strings.HasPrefix((*puts)[0], "/recordings/practice/") {
+		t.Fatalf("uploaded objects %q", *puts)
+	}
+
+	// 4. 识别失败时只比较时长
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, attemptRequest(t, session.ID, 1, 1300, 4000))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("attempt status = %d: %s", w.Code, w.Body.String())
+	}
+	attempt = PracticeRecordingResponse{}
+	json.Unmarshal(w.Body.Bytes(), &attempt)
+	if attempt.AiStatus != "failed" || attempt.Accuracy != nil || attempt.Alignment.DurationRatio == nil || *attempt.Alignment.DurationRatio != 1 {
+		t.Fatalf("failed transcription attempt = %+v, alignment %+v", attempt.PracticeRecording, attempt.Alignment)
+	}
+	for _, l := range transcriber.languages {
+		if l != "en" {
+			t.Fatalf("transcribed with language %q, want en", l)
+		}
+	}
+
+	// 5. 会话汇总：只有识别成功的句子计入平均准确率
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/practice-sessions/"+strconv.FormatUint(uint64(session.ID), 10), nil))
+	session = PracticeSessionResponse{}
+	json.Unmarshal(w.Body.Bytes(), &session)
+	if session.PracticedSegments != 1 || session.AverageAccuracy == nil || *session.AverageAccuracy != 0.75 {
+		t.Fatalf("summary = %d segments, average %v; want 1 and 0.75", session.PracticedSegments, session.AverageAccuracy)
+	}
+	if ref := session.Segments[1].Reference; ref == nil || ref.Status != model.ReferenceAudioCompleted || ref.EndMs != 1300 {
+		t.Fatalf("second segment reference = %+v, want the synthesized 1300 ms clip", ref)
+	}
+}
@@ -0,0 +1,538 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/shuind/language-learner/backend/internal/collab"
+	"github.com/shuind/language-learner/backend/internal/model"
+	"github.com/shuind/language-learner/backend/internal/nodetree"
+	"github.com/shuind/language-learner/backend/internal/search"
+	"github.com/shuind/language-learner/backend/internal/textdiff"
+)
+
+// PublicationHandler 管理个人节点发布到圈子后的关联：查看、预览差异、同步与解除关联。
+// 发布本身由 PublishNodeToDomainHandler 完成，并通过 LinkPublication 建立关联。
+type PublicationHandler struct {
+	DB     *gorm.DB
+	Collab *collab.Hub // 正在协同编辑的副本不会被同步覆盖
+}
+
+func NewPublicationHandler(db *gorm.DB, hub *collab.Hub) *PublicationHandler {
+	return &PublicationHandler{DB: db, Collab: hub}
+}
+
+// 同步时每个节点的变化
+const (
+	SyncActionAdded   = "added"   // 源节点是新增的，同步时在副本中创建
+	SyncActionUpdated = "updated" // 源节点的标题、内容或语言有修改
+	SyncActionMoved   = "moved"   // 源节点换了父节点
+	SyncActionRemoved = "removed" // 源节点已删除或移出发布的子树，副本默认只做标记
+)
+
+// 变化未被应用的原因
+const (
+	SyncSkipLocalChanges = "local_changes" // 圈子里有人改过副本，需要 overwrite_local
+	SyncSkipCollab       = "collab_active" // 副本正在协同编辑
+	SyncSkipCopyDeleted  = "copy_deleted"  // 副本已在圈子中删除
+	SyncSkipParent       = "parent_missing"
+)
+
+type SyncPublicationInput struct {
+	OverwriteLocal bool `json:"overwrite_local"` // 覆盖圈子里对副本的修改
+	DeleteRemoved  bool `json:"delete_removed"`  // 把源节点已删除的副本移入圈子回收站
+}
+
+// PublicationChange 是同步预览或结果中的一项
+type PublicationChange struct {
+	Action       string `json:"action"`
+	SourceNodeID uint   `json:"source_node_id"`
+	DomainNodeID *uint  `json:"domain_node_id"` // 新增节点在预览中为空
+	NodeType     string `json:"node_type"`
+	Title        string `json:"title"`
+	OldTitle     string `json:"old_title,omitempty"`
+	// Unified 是副本内容到源节点内容的 unified diff，仅 updated 时返回
+	Unified      string `json:"unified,omitempty"`
+	LocalChanges bool   `json:"local_changes,omitempty"`
+	Skipped      string `json:"skipped,omitempty"`
+}
+
+type PublicationResponse struct {
+	model.Publication
+	DomainName  string `json:"domain_name"`
+	SourceTitle string `json:"source_title"`
+}
+
+// publicationHash 是源节点中会同步到副本的字段的摘要
+func publicationHash(nodeType, title, content, language string) string {
+	h := sha256.New()
+	for _, s := range []string{nodeType, title, content, language} {
+		io.WriteString(h, s)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// LinkPublication 在副本创建后记录发布关联；idMap 是 nodetree.CopySubtree 写出的源节点到副本的对应关系
+func LinkPublication(tx *gorm.DB, userID, domainID, sourceRootID uint, targetParentID *uint, idMap map[uint]uint) (*model.Publication, error) {
+	pub := model.Publication{
+		UserID:         userID,
+		SourceNodeID:   sourceRootID,
+		DomainID:       domainID,
+		TargetParentID: targetParentID,
+		CopyRootID:     idMap[sourceRootID],
+		LastSyncedAt:   time.Now(),
+	}
+	if err := tx.Create(&pub).Error; err != nil {
+		return nil, err
+	}
+
+	sourceIDs := make([]uint, 0, len(idMap))
+	for id := range idMap {
+		sourceIDs = append(sourceIDs, id)
+	}
+	var sources []model.Node
+	if err := tx.Where("id IN ?", sourceIDs).Find(&sources).Error; err != nil {
+		return nil, err
+	}
+	links := make([]model.PublicationNode, 0, len(sources))
+	for _, s := range sources {
+		links = append(links, model.PublicationNode{
+			PublicationID: pub.ID,
+			SourceNodeID:  s.ID,
+			DomainNodeID:  idMap[s.ID],
+			SyncedHash:    publicationHash(s.NodeType, s.Title, s.Content, s.Language),
+			SyncedVersion: 1,
+		})
+	}
+	if len(links) > 0 {
+		if err := tx.Create(&links).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &pub, nil
+}
+
+// ListPublications GET /publications?source_node_id=
+func (h *PublicationHandler) ListPublications(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	query := h.DB.Preload("Domain").Where("user_id = ?", userID)
+	if s := c.Query("source_node_id"); s != "" {
+		query = query.Where("source_node_id = ?", s)
+	}
+	var pubs []model.Publication
+	if err := query.Order("created_at DESC").Find(&pubs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list publications"})
+		return
+	}
+
+	titles := map[uint]string{}
+	if len(pubs) > 0 {
+		ids := make([]uint, len(pubs))
+		for i, p := range pubs {
+			ids[i] = p.SourceNodeID
+		}
+		var nodes []model.Node
+		h.DB.Unscoped().Select("id", "title").Where("id IN ?", ids).Find(&nodes)
+		for _, n := range nodes {
+			titles[n.ID] = n.Title
+		}
+	}
+	resp := make([]PublicationResponse, len(pubs))
+	for i, p := range pubs {
+		resp[i] = PublicationResponse{Publication: p, DomainName: p.Domain.Name, SourceTitle: titles[p.SourceNodeID]}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// PreviewPublicationSync GET /publications/:id/sync?overwrite_local=&delete_removed=
+// 返回再次同步会产生的变化，不修改数据
+func (h *PublicationHandler) PreviewPublicationSync(c *gin.Context) {
+	pub, ok := h.findPublication(c)
+	if !ok {
+		return
+	}
+	opts := SyncPublicationInput{
+		OverwriteLocal: c.Query("overwrite_local") == "true",
+		DeleteRemoved:  c.Query("delete_removed") == "true",
+	}
+	changes, err := h.sync(h.DB, pub, opts, c.MustGet("userID").(uint), false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute changes"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"publication": pub, "changes": changes})
+}
+
+// SyncPublication POST /publications/:id/sync
+func (h *PublicationHandler) SyncPublication(c *gin.Context) {
+	pub, ok := h.findPublication(c)
+	if !ok {
+		return
+	}
+	var input SyncPublicationInput
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	changes, err := h.apply(pub, input, c.MustGet("userID").(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sync publication"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"publication": pub, "changes": changes})
+}
+
+// SyncNodePublications POST /nodes/:id/publications/sync
+// 把一个源节点推送到它发布过的所有圈子；已不再是圈主或管理员的圈子会被跳过
+func (h *PublicationHandler) SyncNodePublications(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	var input SyncPublicationInput
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var pubs []model.Publication
+	if err := h.DB.Where("user_id = ? AND source_node_id = ?", userID, c.Param("id")).Find(&pubs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load publications"})
+		return
+	}
+
+	type result struct {
+		PublicationID uint                `json:"publication_id"`
+		DomainID      uint                `json:"domain_id"`
+		Changes       []PublicationChange `json:"changes,omitempty"`
+		Error         string              `json:"error,omitempty"`
+	}
+	results := make([]result, 0, len(pubs))
+	for i := range pubs {
+		pub := &pubs[i]
+		r := result{PublicationID: pub.ID, DomainID: pub.DomainID}
+		if !h.canManage(pub.DomainID, userID) {
+			r.Error = "You are no longer an owner or admin of this domain"
+		} else if changes, err := h.apply(pub, input, userID); err != nil {
+			r.Error = "Failed to sync publication"
+		} else {
+			r.Changes = changes
+		}
+		results = append(results, r)
+	}
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// UnlinkPublication DELETE /publications/:id
+// 解除关联，圈子中的副本保留，此后不再同步
+func (h *PublicationHandler) UnlinkPublication(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	var pub model.Publication
+	if err := h.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&pub).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Publication not found"})
+		return
+	}
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("publication_id = ?", pub.ID).Delete(&model.PublicationNode{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&pub).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlink publication"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// findPublication 加载当前用户的发布，并确认其仍是目标圈子的圈主或管理员
+func (h *PublicationHandler) findPublication(c *gin.Context) (*model.Publication, bool) {
+	userID := c.MustGet("userID").(uint)
+	var pub model.Publication
+	if err := h.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&pub).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Publication not found"})
+		return nil, false
+	}
+	if !h.canManage(pub.DomainID, userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are no longer an owner or admin of this domain"})
+		return nil, false
+	}
+	return &pub, true
+}
+
+func (h *PublicationHandler) canManage(domainID, userID uint) bool {
+	var count int64
+	h.DB.Model(&model.DomainMember{}).
+		Where("domain_id = ? AND user_id = ? AND role IN ?", domainID, userID, []string{model.DomainRoleOwner, model.DomainRoleAdmin}).
+		Count(&count)
+	return count > 0
+}
+
+func (h *PublicationHandler) apply(pub *model.Publication, opts SyncPublicationInput, userID uint) ([]PublicationChange, error) {
+	var changes []PublicationChange
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if changes, err = h.sync(tx, pub, opts, userID, true); err != nil {
+			return err
+		}
+		// 同步确实改动了副本时，在圈子动态中记一条内容更新
+		for _, ch := range changes {
+			if ch.Skipped == "" && ch.Action != SyncActionRemoved {
+				var title string
+				if err := tx.Model(&model.DomainNode{}).Where("id = ?", pub.CopyRootID).Pluck("title", &title).Error; err != nil {
+					return err
+				}
+				if err := RecordDomainActivity(tx, pub.DomainID, userID, model.ActivityNodeUpdated, pub.CopyRootID, title); err != nil {
+					return err
+				}
+				break
+			}
+		}
+		pub.LastSyncedAt = time.Now()
+		return tx.Model(pub).Update("last_synced_at", pub.LastSyncedAt).Error
+	})
+	return changes, err
+}
+
+// sync 比较源子树与副本，apply 为 false 时只返回变化。
+// 源子树自上而下遍历，新增节点挂到父节点的副本下；副本被删除的节点及其新增的子节点会被跳过。
+func (h *PublicationHandler) sync(tx *gorm.DB, pub *model.Publication, opts SyncPublicationInput, userID uint, apply bool) ([]PublicationChange, error) {
+	ids, err := nodetree.SubtreeIDs(tx, nodetree.Nodes, pub.SourceNodeID)
+	if err != nil {
+		return nil, err
+	}
+	var sources []model.Node
+	if len(ids) > 0 {
+		if err := tx.Where("id IN ? AND user_id = ?", ids, pub.UserID).Order(nodetree.Nodes.Order).Find(&sources).Error; err != nil {
+			return nil, err
+		}
+	}
+	children := make(map[uint][]*model.Node, len(sources))
+	var root *model.Node
+	for i := range sources {
+		s := &sources[i]
+		if s.ID == pub.SourceNodeID {
+			root = s
+		} else if s.ParentID != nil {
+			children[*s.ParentID] = append(children[*s.ParentID], s)
+		}
+	}
+
+	var links []model.PublicationNode
+	if err := tx.Where("publication_id = ?", pub.ID).Find(&links).Error; err != nil {
+		return nil, err
+	}
+	linkBySource := make(map[uint]*model.PublicationNode, len(links))
+	copyIDs := make([]uint, 0, len(links))
+	for i := range links {
+		linkBySource[links[i].SourceNodeID] = &links[i]
+		copyIDs = append(copyIDs, links[i].DomainNodeID)
+	}
+	copies := make(map[uint]*model.DomainNode, len(copyIDs))
+	if len(copyIDs) > 0 {
+		var rows []model.DomainNode
+		if err := tx.Where("id IN ? AND domain_id = ?", copyIDs, pub.DomainID).Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		for i := range rows {
+			copies[rows[i].ID] = &rows[i]
+		}
+	}
+
+	var changes []PublicationChange
+	visited := make(map[uint]bool, len(sources))
+	copyOf := make(map[uint]uint, len(sources)) // 源节点 ID -> 副本 ID，预览中新增的节点记为 0
+	queue := []*model.Node{}
+	if root != nil {
+		queue = append(queue, root)
+	}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		visited[s.ID] = true
+		hash := publicationHash(s.NodeType, s.Title, s.Content, s.Language)
+
+		// 副本应在的父节点：根节点为发布时选择的文件夹，其余为父节点的副本
+		parentID, parentOK := pub.TargetParentID, true
+		if s.ID != pub.SourceNodeID {
+			var pid uint
+			pid, parentOK = copyOf[*s.ParentID]
+			parentID = &pid
+		}
+
+		link := linkBySource[s.ID]
+		if link == nil {
+			change := PublicationChange{Action: SyncActionAdded, SourceNodeID: s.ID, NodeType: s.NodeType, Title: s.Title}
+			switch {
+			case !parentOK:
+				change.Skipped = SyncSkipParent
+			case apply:
+				id, err := h.createCopy(tx, pub, s, parentID, hash, userID)
+				if err != nil {
+					return nil, err
+				}
+				change.DomainNodeID = &id
+				copyOf[s.ID] = id
+			default:
+				copyOf[s.ID] = 0
+			}
+			changes = append(changes, change)
+			queue = append(queue, children[s.ID]...)
+			continue
+		}
+
+		cp := copies[link.DomainNodeID]
+		if cp == nil {
+			// 副本已被删除，源节点有修改时提示一次，子节点不再同步
+			if link.SyncedHash != hash {
+				id := link.DomainNodeID
+				changes = append(changes, PublicationChange{
+					Action: SyncActionUpdated, SourceNodeID: s.ID, DomainNodeID: &id,
+					NodeType: s.NodeType, Title: s.Title, Skipped: SyncSkipCopyDeleted,
+				})
+			}
+			continue
+		}
+		copyOf[s.ID] = cp.ID
+
+		if link.SyncedHash != hash {
+			id := cp.ID
+			change := PublicationChange{
+				Action: SyncActionUpdated, SourceNodeID: s.ID, DomainNodeID: &id,
+				NodeType: s.NodeType, Title: s.Title, OldTitle: cp.Title,
+				Unified:      textdiff.Unified(cp.Content, s.Content, diffContextLines),
+				LocalChanges: cp.Version != link.SyncedVersion,
+			}
+			switch {
+			case change.LocalChanges && !opts.OverwriteLocal:
+				change.Skipped = SyncSkipLocalChanges
+			case h.Collab != nil && h.Collab.Active(cp.ID):
+				change.Skipped = SyncSkipCollab
+			case apply:
+				if err := h.updateCopy(tx, cp, s, link, hash, userID); err != nil {
+					return nil, err
+				}
+			}
+			changes = append(changes, change)
+		}
+
+		if s.ID != pub.SourceNodeID && parentOK && *parentID != 0 && (cp.ParentID == nil || *cp.ParentID != *parentID) {
+			id := cp.ID
+			changes = append(changes, PublicationChange{
+				Action: SyncActionMoved, SourceNodeID: s.ID, DomainNodeID: &id, NodeType: s.NodeType, Title: s.Title,
+			})
+			if apply {
+				position, err := nodetree.NextPosition(tx, nodetree.DomainNodes, pub.DomainID, parentID)
+				if err != nil {
+					return nil, err
+				}
+				if err := tx.Model(&model.DomainNode{}).Where("id = ?", cp.ID).
+					Updates(map[string]interface{}{"parent_id": *parentID, "position": position}).Error; err != nil {
+					return nil, err
+				}
+			}
+		}
+		queue = append(queue, children[s.ID]...)
+	}
+
+	// 源节点已不在子树中的关联：副本仍在时标记（或按要求删除）
+	now := time.Now()
+	for i := range links {
+		link := &links[i]
+		cp := copies[link.DomainNodeID]
+		if visited[link.SourceNodeID] || cp == nil {
+			continue
+		}
+		id := cp.ID
+		changes = append(changes, PublicationChange{
+			Action: SyncActionRemoved, SourceNodeID: link.SourceNodeID, DomainNodeID: &id, NodeType: cp.NodeType, Title: cp.Title,
+		})
+		if !apply {
+			continue
+		}
+		if opts.DeleteRemoved {
+			// copies 在删除前载入：父节点的副本已在本轮删除时，该副本随之删除，找不到未删除的节点
+			if _, err := nodetree.SoftDeleteSubtree(tx, nodetree.DomainNodes, cp.ID); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, err
+			}
+			if err := tx.Delete(link).Error; err != nil {
+				return nil, err
+			}
+		} else if link.SourceRemovedAt == nil {
+			if err := tx.Model(link).Update("source_removed_at", now).Error; err != nil {
+				return nil, err
+			}
+		}
+	}
+	return changes, nil
+}
+
+// createCopy 为新增的源节点创建副本并建立关联
+func (h *PublicationHandler) createCopy(tx *gorm.DB, pub *model.Publication, s *model.Node, parentID *uint, hash string, userID uint) (uint, error) {
+	position, err := nodetree.NextPosition(tx, nodetree.DomainNodes, pub.DomainID, parentID)
+	if err != nil {
+		return 0, err
+	}
+	node := model.DomainNode{
+		DomainID: pub.DomainID,
+		ParentID: parentID,
+		NodeType: s.NodeType,
+		Title:    s.Title,
+		Content:  s.Content,
+		Language: s.Language,
+		Position: position,
+	}
+	if err := tx.Create(&node).Error; err != nil {
+		return 0, err
+	}
+	if err := search.IndexDomainNode(tx, &node); err != nil {
+		return 0, err
+	}
+	if _, err := RecordRevision(tx, model.RevisionKindDomainNode, node.ID, userID, node.Title, node.Content); err != nil {
+		return 0, err
+	}
+	link := model.PublicationNode{
+		PublicationID: pub.ID,
+		SourceNodeID:  s.ID,
+		DomainNodeID:  node.ID,
+		SyncedHash:    hash,
+		SyncedVersion: node.Version,
+	}
+	return node.ID, tx.Create(&link).Error
+}
+
+// updateCopy 用源节点覆盖副本，记录修订并使示范音频失效
+func (h *PublicationHandler) updateCopy(tx *gorm.DB, cp *model.DomainNode, s *model.Node, link *model.PublicationNode, hash string, userID uint) error {
+	updates := map[string]interface{}{
+		"node_type": s.NodeType,
+		"title":     s.Title,
+		"content":   s.Content,
+		"language":  s.Language,
+	}
+	if err := UpdateVersioned(tx, &model.DomainNode{}, cp.ID, cp.Version, updates); err != nil {
+		return err
+	}
+	if err := tx.First(cp, cp.ID).Error; err != nil {
+		return err
+	}
+	if err := search.IndexDomainNode(tx, cp); err != nil {
+		return err
+	}
+	if _, err := RecordRevision(tx, model.RevisionKindDomainNode, cp.ID, userID, cp.Title, cp.Content); err != nil {
+		return err
+	}
+	if err := InvalidateReferenceAudio(tx, model.RevisionKindDomainNode, cp.ID, cp.NodeType, cp.Content, cp.Language); err != nil {
+		return err
+	}
+	if err := RealignTranslations(tx, model.RevisionKindDomainNode, cp.ID, cp.NodeType, cp.Content); err != nil {
+		return err
+	}
+	return tx.Model(link).Updates(map[string]interface{}{
+		"synced_hash":    hash,
+		"synced_version": cp.Version,
+	}).Error
+}
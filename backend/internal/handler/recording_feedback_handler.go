@@ -0,0 +1,284 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/shuind/language-learner/backend/internal/model"
+	"github.com/shuind/language-learner/backend/internal/task"
+)
+
+// RecordingFeedbackHandler 处理录音上带时间点的反馈：圈主与管理员的语音讲解，以及给录音作者的汇总视图。
+// 文字评论仍由 /recordings/:id/comments 创建，同样可以带时间区间与类别。
+// 语音反馈的音频与录音一样经 audio_processing 队列由 worker 上传。
+type RecordingFeedbackHandler struct {
+	DB    *gorm.DB
+	Queue TaskPublisher
+}
+
+func NewRecordingFeedbackHandler(db *gorm.DB, queue TaskPublisher) *RecordingFeedbackHandler {
+	return &RecordingFeedbackHandler{DB: db, Queue: queue}
+}
+
+// maxVoiceFeedbackSize 限制上传的语音反馈大小
+const maxVoiceFeedbackSize = 10 << 20
+
+var errFeedbackRange = errors.New("feedback range must satisfy 0 <= start_ms <= end_ms within the recording, and end_ms requires start_ms")
+
+// ValidateFeedbackRange 校验评论在录音中的时间区间；durationMs 为 0 表示录音时长未知，此时不检查上界
+func ValidateFeedbackRange(startMs, endMs *int, durationMs int) error {
+	if startMs == nil {
+		if endMs != nil {
+			return errFeedbackRange
+		}
+		return nil
+	}
+	if *startMs < 0 || (durationMs > 0 && *startMs > durationMs) {
+		return errFeedbackRange
+	}
+	if endMs != nil && (*endMs < *startMs || (durationMs > 0 && *endMs > durationMs)) {
+		return errFeedbackRange
+	}
+	return nil
+}
+
+// ValidCommentCategory 判断评论类别是否有效，空字符串表示不分类
+func ValidCommentCategory(category string) bool {
+	switch category {
+	case "", model.CommentCategoryPronunciation, model.CommentCategoryOmission, model.CommentCategoryFluency:
+		return true
+	}
+	return false
+}
+
+// FeedbackItem 是汇总视图中的一条评论
+type FeedbackItem struct {
+	model.Comment
+	User AuthorResponse `json:"user"`
+	// FromManager 表示评论者是录音所在圈子的圈主或管理员
+	FromManager bool `json:"from_manager"`
+}
+
+// CreateVoiceFeedback POST /recordings/:id/voice-feedback
+// 只有录音所在圈子的圈主与管理员可以留下语音反馈。表单字段：file、duration_ms，
+// 可选 start_ms、end_ms、category、content。返回 202，音频上传完成前 audio_status 为 processing
+func (h *RecordingFeedbackHandler) CreateVoiceFeedback(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	var recording model.Recording
+	if err := h.DB.Preload("DomainNode").First(&recording, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Recording not found"})
+		return
+	}
+	if recording.DomainNodeID == nil || !isDomainManager(h.DB, recording.DomainNode.DomainID, userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only domain owners and admins can leave voice feedback"})
+		return
+	}
+
+	// 1. 解析表单
+	durationMs, err := strconv.Atoi(c.PostForm("duration_ms"))
+	if err != nil || durationMs <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "duration_ms must be a positive integer"})
+		return
+	}
+	startMs, ok := optionalFormInt(c, "start_ms")
+	if !ok {
+		return
+	}
+	endMs, ok := optionalFormInt(c, "end_ms")
+	if !ok {
+		return
+	}
+	if err := ValidateFeedbackRange(startMs, endMs, recording.DurationMs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	category := c.PostForm("category")
+	if !ValidCommentCategory(category) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category"})
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file is received"})
+		return
+	}
+	contentType := file.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "audio/") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file type. Only audio files are allowed."})
+		return
+	}
+	if file.Size > maxVoiceFeedbackSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Audio file is too large"})
+		return
+	}
+
+	// 2. 读取音频，上传交给 worker
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open uploaded file"})
+		return
+	}
+	defer src.Close()
+	audio, err := io.ReadAll(src)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file content"})
+		return
+	}
+
+	// 3. 保存为一条评论并更新评论计数
+	comment := model.Comment{
+		RecordingID:     recording.ID,
+		UserID:          userID,
+		Content:         c.PostForm("content"),
+		StartMs:         startMs,
+		EndMs:           endMs,
+		Category:        category,
+		AudioDurationMs: durationMs,
+		AudioStatus:     model.CommentAudioProcessing,
+	}
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&comment).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.Recording{}).Where("id = ?", recording.ID).
+			Update("comments_count", gorm.Expr("comments_count + 1")).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save voice feedback"})
+		return
+	}
+
+	// 4. 评论提交后再发布任务，避免 worker 先于事务读到不存在的评论
+	body, _ := json.Marshal(task.VoiceFeedbackTask{
+		RecordingID: recording.ID,
+		CommentID:   comment.ID,
+		FileContent: audio,
+		ContentType: contentType,
+	})
+	if err := h.Queue.Publish(c.Request.Context(), task.AudioProcessingQueue, body); err != nil {
+		log.Printf("Failed to publish voice feedback task: %v", err)
+		h.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Unscoped().Delete(&comment).Error; err != nil {
+				return err
+			}
+			return tx.Model(&model.Recording{}).Where("id = ?", recording.ID).
+				Update("comments_count", gorm.Expr("comments_count - 1")).Error
+		})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue file for processing"})
+		return
+	}
+
+	h.DB.Preload("User").First(&comment, comment.ID)
+	c.JSON(http.StatusAccepted, FeedbackItem{
+		Comment:     comment,
+		User:        AuthorResponse{ID: comment.User.ID, Username: comment.User.Username, AvatarURL: comment.User.AvatarURL},
+		FromManager: true,
+	})
+}
+
+// GetRecordingFeedback GET /recordings/:id/feedback
+// 录音作者的反馈汇总：按时间点排列的评论、针对整条录音的评论、各类别数量以及互评得分。
+// 圈子录音的圈主与管理员也可以查看
+func (h *RecordingFeedbackHandler) GetRecordingFeedback(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	var recording model.Recording
+	if err := h.DB.Preload("DomainNode").First(&recording, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Recording not found"})
+		return
+	}
+	if recording.UserID != userID &&
+		(recording.DomainNodeID == nil || !isDomainManager(h.DB, recording.DomainNode.DomainID, userID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return
+	}
+
+	var comments []model.Comment
+	if err := h.DB.Preload("User").Where("recording_id = ?", recording.ID).
+		Order("created_at ASC").Find(&comments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load feedback"})
+		return
+	}
+	managers := map[uint]bool{}
+	if recording.DomainNodeID != nil {
+		var ids []uint
+		h.DB.Model(&model.DomainMember{}).
+			Where("domain_id = ? AND role IN ?", recording.DomainNode.DomainID, []string{model.DomainRoleOwner, model.DomainRoleAdmin}).
+			Pluck("user_id", &ids)
+		for _, id := range ids {
+			managers[id] = true
+		}
+	}
+
+	timeline := make([]FeedbackItem, 0, len(comments))
+	general := make([]FeedbackItem, 0, len(comments))
+	categories := map[string]int{
+		model.CommentCategoryPronunciation: 0,
+		model.CommentCategoryOmission:      0,
+		model.CommentCategoryFluency:       0,
+	}
+	voice := 0
+	for _, cm := range comments {
+		item := FeedbackItem{
+			Comment:     cm,
+			User:        AuthorResponse{ID: cm.User.ID, Username: cm.User.Username, AvatarURL: cm.User.AvatarURL},
+			FromManager: managers[cm.UserID],
+		}
+		if cm.Category != "" {
+			categories[cm.Category]++
+		}
+		if cm.AudioStatus != "" {
+			voice++
+		}
+		if cm.StartMs != nil {
+			timeline = append(timeline, item)
+		} else {
+			general = append(general, item)
+		}
+	}
+	// 同一时间点的评论保持发表顺序
+	sort.SliceStable(timeline, func(i, j int) bool { return *timeline[i].StartMs < *timeline[j].StartMs })
+
+	c.JSON(http.StatusOK, gin.H{
+		"recording_id":      recording.ID,
+		"duration_ms":       recording.DurationMs,
+		"timeline":          timeline,
+		"general":           general,
+		"categories":        categories,
+		"voice_count":       voice,
+		"peer_review_score": recording.PeerReviewScore,
+		"peer_review_count": recording.PeerReviewCount,
+	})
+}
+
+// isDomainManager 判断用户是否是圈子的圈主或管理员
+func isDomainManager(db *gorm.DB, domainID, userID uint) bool {
+	var count int64
+	db.Model(&model.DomainMember{}).
+		Where("domain_id = ? AND user_id = ? AND role IN ?", domainID, userID, []string{model.DomainRoleOwner, model.DomainRoleAdmin}).
+		Count(&count)
+	return count > 0
+}
+
+// optionalFormInt 读取可选的非负整数表单字段，格式错误时直接返回 400
+func optionalFormInt(c *gin.Context, key string) (*int, bool) {
+	s := c.PostForm(key)
+	if s == "" {
+		return nil, true
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": key + " must be a non-negative integer"})
+		return nil, false
+	}
+	return &v, true
+}
@@ -0,0 +1,439 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/minio/minio-go/v7"
+	"gorm.io/gorm"
+
+	"github.com/shuind/language-learner/backend/internal/lang"
+	"github.com/shuind/language-learner/backend/internal/model"
+	"github.com/shuind/language-learner/backend/internal/nodecontent"
+	"github.com/shuind/language-learner/backend/internal/task"
+	"github.com/shuind/language-learner/backend/internal/tts"
+)
+
+// TaskPublisher 把任务发布到消息队列，由 mq.RabbitMQManager 实现
+type TaskPublisher interface {
+	Publish(ctx context.Context, queue string, body []byte) error
+}
+
+// ReferenceAudioHandler 处理朗读示范音频的生成、上传与查询，合成由 worker 异步完成
+type ReferenceAudioHandler struct {
+	DB     *gorm.DB
+	Queue  TaskPublisher
+	Minio  *minio.Client
+	Bucket string
+}
+
+func NewReferenceAudioHandler(db *gorm.DB, queue TaskPublisher, minioClient *minio.Client, bucket string) *ReferenceAudioHandler {
+	return &ReferenceAudioHandler{DB: db, Queue: queue, Minio: minioClient, Bucket: bucket}
+}
+
+type CreateReferenceAudioInput struct {
+	Segment *int   `json:"segment" binding:"omitempty,min=0"` // 为空表示朗读整个节点
+	Voice   string `json:"voice" binding:"max=64"`
+}
+
+// referenceAudioTarget 是要朗读的节点
+type referenceAudioTarget struct {
+	kind     string
+	nodeID   uint
+	domainID uint // 仅圈子节点
+	title    string
+	nodeType string
+	content  string
+	language string
+}
+
+// ---------------------- 个人节点 ----------------------
+
+// ListNodeReferenceAudio GET /nodes/:id/reference-audio
+func (h *ReferenceAudioHandler) ListNodeReferenceAudio(c *gin.Context) {
+	if t, ok := nodeReferenceTarget(c, h.DB); ok {
+		h.list(c, t)
+	}
+}
+
+// CreateNodeReferenceAudio POST /nodes/:id/reference-audio
+func (h *ReferenceAudioHandler) CreateNodeReferenceAudio(c *gin.Context) {
+	if t, ok := nodeReferenceTarget(c, h.DB); ok {
+		h.create(c, t)
+	}
+}
+
+// UploadNodeReferenceAudio POST /nodes/:id/reference-audio/upload
+func (h *ReferenceAudioHandler) UploadNodeReferenceAudio(c *gin.Context) {
+	if t, ok := nodeReferenceTarget(c, h.DB); ok {
+		h.upload(c, t)
+	}
+}
+
+// nodeReferenceTarget 读取当前用户自己的、可朗读的节点
+func nodeReferenceTarget(c *gin.Context, db *gorm.DB) (*referenceAudioTarget, bool) {
+	userID := c.MustGet("userID").(uint)
+	var node model.Node
+	if err := db.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&node).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found or permission denied"})
+		return nil, false
+	}
+	if !nodecontent.Recitable(node.NodeType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Folders have no content to read aloud"})
+		return nil, false
+	}
+	return &referenceAudioTarget{
+		kind:     model.RevisionKindNode,
+		nodeID:   node.ID,
+		title:    node.Title,
+		nodeType: node.NodeType,
+		content:  node.Content,
+		language: node.Language,
+	}, true
+}
+
+// ---------------------- 圈子节点 ----------------------
+
+// ListDomainNodeReferenceAudio GET /domain-nodes/:id/reference-audio
+func (h *ReferenceAudioHandler) ListDomainNodeReferenceAudio(c *gin.Context) {
+	if t, ok := domainNodeReferenceTarget(c, h.DB, false); ok {
+		h.list(c, t)
+	}
+}
+
+// CreateDomainNodeReferenceAudio POST /domain-nodes/:id/reference-audio
+// 圈子成员均可请求生成
+func (h *ReferenceAudioHandler) CreateDomainNodeReferenceAudio(c *gin.Context) {
+	if t, ok := domainNodeReferenceTarget(c, h.DB, false); ok {
+		h.create(c, t)
+	}
+}
+
+// UploadDomainNodeReferenceAudio POST /domain-nodes/:id/reference-audio/upload
+// 只有圈主和管理员可以上传
+func (h *ReferenceAudioHandler) UploadDomainNodeReferenceAudio(c *gin.Context) {
+	if t, ok := domainNodeReferenceTarget(c, h.DB, true); ok {
+		h.upload(c, t)
+	}
+}
+
+// domainNodeReferenceTarget 读取可朗读的圈子节点，当前用户须是圈子成员；edit 为 true 时须是圈主或管理员
+func domainNodeReferenceTarget(c *gin.Context, db *gorm.DB, edit bool) (*referenceAudioTarget, bool) {
+	userID := c.MustGet("userID").(uint)
+	var node model.DomainNode
+	if err := db.First(&node, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Domain node not found"})
+		return nil, false
+	}
+	if !nodecontent.Recitable(node.NodeType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Folders have no content to read aloud"})
+		return nil, false
+	}
+	var member model.DomainMember
+	if err := db.Where("domain_id = ? AND user_id = ?", node.DomainID, userID).First(&member).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: you are not a member of this domain"})
+		return nil, false
+	}
+	if edit && member.Role != "owner" && member.Role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return nil, false
+	}
+	return &referenceAudioTarget{
+		kind:     model.RevisionKindDomainNode,
+		nodeID:   node.ID,
+		domainID: node.DomainID,
+		title:    node.Title,
+		nodeType: node.NodeType,
+		content:  node.Content,
+		language: node.Language,
+	}, true
+}
+
+// ---------------------- 通用实现 ----------------------
+
+func (h *ReferenceAudioHandler) list(c *gin.Context, t *referenceAudioTarget) {
+	var audios []model.ReferenceAudio
+	if err := h.DB.Where("node_kind = ? AND node_id = ?", t.kind, t.nodeID).
+		Order("segment_index, voice").Find(&audios).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load reference audio"})
+		return
+	}
+	c.JSON(http.StatusOK, audios)
+}
+
+// create 请求生成整个节点或其中一句的示范音频，见 requestReferenceAudio
+func (h *ReferenceAudioHandler) create(c *gin.Context, t *referenceAudioTarget) {
+	userID := c.MustGet("userID").(uint)
+	var input CreateReferenceAudioInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	segmentIndex, text, ok := referenceText(c, t, input.Segment)
+	if !ok {
+		return
+	}
+
+	audio, err := requestReferenceAudio(c.Request.Context(), h.DB, h.Queue, t, segmentIndex, text, input.Voice, userID)
+	if err != nil {
+		if errors.Is(err, errQueueUnavailable) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue reference audio for synthesis"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save reference audio"})
+		return
+	}
+	if audio.Status == model.ReferenceAudioPending {
+		c.JSON(http.StatusAccepted, audio)
+		return
+	}
+	c.JSON(http.StatusOK, audio)
+}
+
+// referenceText 返回要朗读的文字：segment 为空时是整个节点，否则是其中一句
+func referenceText(c *gin.Context, t *referenceAudioTarget, segment *int) (int, string, bool) {
+	text := nodecontent.Reference(t.nodeType, t.content)
+	segmentIndex := model.ReferenceAudioWholeNode
+	if segment != nil {
+		segments := lang.Segments(text)
+		if *segment >= len(segments) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Segment index out of range", "segments": len(segments)})
+			return 0, "", false
+		}
+		segmentIndex = *segment
+		text = segments[segmentIndex].Text
+	}
+	if text == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Node has no content to read aloud"})
+		return 0, "", false
+	}
+	return segmentIndex, text, true
+}
+
+var errQueueUnavailable = errors.New("queue unavailable")
+
+// requestReferenceAudio 请求合成一段文字的示范音频：
+// 已有对应的记录时直接返回（失败的记录会重新排队），其他节点合成过相同内容时复用其音频，
+// 否则创建一条 pending 记录并交给 worker 合成。
+func requestReferenceAudio(ctx context.Context, db *gorm.DB, queue TaskPublisher, t *referenceAudioTarget, segmentIndex int, text, voice string, userID uint) (*model.ReferenceAudio, error) {
+	hash := tts.CacheKey(text, t.language, voice)
+
+	// 1. 已有这段内容的记录
+	var audio model.ReferenceAudio
+	err := db.Where("node_kind = ? AND node_id = ? AND segment_index = ? AND source = ? AND content_hash = ?",
+		t.kind, t.nodeID, segmentIndex, model.ReferenceAudioSourceTTS, hash).First(&audio).Error
+	if err == nil && audio.Status != model.ReferenceAudioFailed {
+		return &audio, nil
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// 2. 缓存命中：复用相同内容已合成的音频
+	var cached model.ReferenceAudio
+	hit := db.Where("content_hash = ? AND source = ? AND status = ?", hash, model.ReferenceAudioSourceTTS, model.ReferenceAudioCompleted).
+		First(&cached).Error == nil
+
+	audio.NodeKind, audio.NodeID, audio.SegmentIndex, audio.Source, audio.ContentHash = t.kind, t.nodeID, segmentIndex, model.ReferenceAudioSourceTTS, hash
+	audio.Text, audio.Language, audio.Voice = text, t.language, voice
+	audio.RequestedBy, audio.Error = userID, ""
+	if hit {
+		audio.Status = model.ReferenceAudioCompleted
+		audio.AudioURL, audio.ObjectName, audio.ContentType, audio.DurationMs = cached.AudioURL, cached.ObjectName, cached.ContentType, cached.DurationMs
+	} else {
+		audio.Status = model.ReferenceAudioPending
+		audio.AudioURL, audio.ObjectName, audio.ContentType, audio.DurationMs = "", "", "", 0
+	}
+	if err := db.Save(&audio).Error; err != nil {
+		return nil, err
+	}
+	if hit {
+		return &audio, nil
+	}
+
+	// 3. 交给 worker 合成
+	body, _ := json.Marshal(task.ReferenceAudioTask{ReferenceAudioID: audio.ID})
+	if err := queue.Publish(ctx, task.ReferenceAudioQueue, body); err != nil {
+		log.Printf("Failed to publish reference audio task %d: %v", audio.ID, err)
+		db.Model(&audio).Updates(map[string]interface{}{"status": model.ReferenceAudioFailed, "error": errQueueUnavailable.Error()})
+		return nil, errQueueUnavailable
+	}
+	return &audio, nil
+}
+
+// maxReferenceUploadSize 限制上传的示范录音大小
+const maxReferenceUploadSize = 20 << 20
+
+// upload 保存节点所有者上传的示范录音。
+// 表单字段：file（音频）、duration_ms（时长）、segment（可选，为空表示整个节点）、
+// timings（可选，仅整个节点：各句起止时间的 JSON 数组，长度须与句子数一致）。
+// 同一句已有上传的录音时替换之。
+func (h *ReferenceAudioHandler) upload(c *gin.Context, t *referenceAudioTarget) {
+	userID := c.MustGet("userID").(uint)
+
+	// 1. 解析表单
+	var segment *int
+	if s := c.PostForm("segment"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid segment"})
+			return
+		}
+		segment = &v
+	}
+	durationMs, err := strconv.Atoi(c.PostForm("duration_ms"))
+	if err != nil || durationMs <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "duration_ms must be a positive integer"})
+		return
+	}
+	segmentIndex, text, ok := referenceText(c, t, segment)
+	if !ok {
+		return
+	}
+
+	var timingsJSON string
+	if raw := c.PostForm("timings"); raw != "" {
+		if segment != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "timings are only allowed for whole-node audio"})
+			return
+		}
+		var timings []model.SegmentTiming
+		if err := json.Unmarshal([]byte(raw), &timings); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timings"})
+			return
+		}
+		if n := len(lang.Segments(text)); len(timings) != n {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "timings must have one entry per segment", "segments": n})
+			return
+		}
+		for _, tm := range timings {
+			if tm.StartMs < 0 || tm.EndMs <= tm.StartMs || tm.EndMs > durationMs {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Each timing must satisfy 0 <= start_ms < end_ms <= duration_ms"})
+				return
+			}
+		}
+		timingsJSON = raw
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file is received"})
+		return
+	}
+	contentType := file.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "audio/") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file type. Only audio files are allowed."})
+		return
+	}
+	if file.Size > maxReferenceUploadSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Audio file is too large"})
+		return
+	}
+
+	// 2. 上传到对象存储
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open uploaded file"})
+		return
+	}
+	defer src.Close()
+	objectName := fmt.Sprintf("reference/%s-%d/%s.%s", t.kind, t.nodeID, uuid.New().String(), tts.Extension(contentType))
+	if _, err := h.Minio.PutObject(c.Request.Context(), h.Bucket, objectName, src, file.Size, minio.PutObjectOptions{
+		ContentType: contentType,
+	}); err != nil {
+		log.Printf("ERROR: Failed to upload reference audio to MinIO: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload file to storage"})
+		return
+	}
+
+	// 3. 保存记录，替换之前上传的同一句录音
+	hash := tts.CacheKey(text, t.language, "")
+	var audio model.ReferenceAudio
+	err = h.DB.Where("node_kind = ? AND node_id = ? AND segment_index = ? AND source = ? AND content_hash = ?",
+		t.kind, t.nodeID, segmentIndex, model.ReferenceAudioSourceUpload, hash).First(&audio).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load reference audio"})
+		return
+	}
+	previous := audio.ObjectName
+	audio = model.ReferenceAudio{
+		ID:           audio.ID,
+		CreatedAt:    audio.CreatedAt,
+		NodeKind:     t.kind,
+		NodeID:       t.nodeID,
+		SegmentIndex: segmentIndex,
+		Source:       model.ReferenceAudioSourceUpload,
+		ContentHash:  hash,
+		Text:         text,
+		Language:     t.language,
+		Status:       model.ReferenceAudioCompleted,
+		AudioURL:     ObjectURL(h.Bucket, objectName),
+		ObjectName:   objectName,
+		ContentType:  contentType,
+		DurationMs:   durationMs,
+		Timings:      timingsJSON,
+		RequestedBy:  userID,
+	}
+	if err := h.DB.Save(&audio).Error; err != nil {
+		h.Minio.RemoveObject(context.Background(), h.Bucket, objectName, minio.RemoveObjectOptions{})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save reference audio"})
+		return
+	}
+	if previous != "" {
+		if err := h.Minio.RemoveObject(context.Background(), h.Bucket, previous, minio.RemoveObjectOptions{}); err != nil {
+			log.Printf("WARN: Failed to remove replaced reference audio %s: %v", previous, err)
+		}
+	}
+	c.JSON(http.StatusCreated, audio)
+}
+
+// ObjectURL 返回对象存储中文件的公开访问地址
+func ObjectURL(bucket, objectName string) string {
+	publicEndpoint := os.Getenv("MINIO_PUBLIC_ENDPOINT")
+	if publicEndpoint == "" {
+		publicEndpoint = "http://localhost:9000"
+	}
+	return fmt.Sprintf("%s/%s/%s", publicEndpoint, bucket, objectName)
+}
+
+// InvalidateReferenceAudio 在节点内容或语言修改后删除不再对应的示范音频记录；
+// 未改动的句子仍然有效。音频文件保留在对象存储中：合成的音频按内容寻址，可能被其他节点共用。
+func InvalidateReferenceAudio(tx *gorm.DB, kind string, nodeID uint, nodeType, content, language string) error {
+	var audios []model.ReferenceAudio
+	if err := tx.Select("id", "segment_index", "voice", "content_hash").
+		Where("node_kind = ? AND node_id = ?", kind, nodeID).Find(&audios).Error; err != nil {
+		return err
+	}
+	if len(audios) == 0 {
+		return nil
+	}
+
+	text := nodecontent.Reference(nodeType, content)
+	segments := lang.Segments(text)
+	var stale []uint
+	for _, a := range audios {
+		current := text
+		if a.SegmentIndex != model.ReferenceAudioWholeNode {
+			current = ""
+			if a.SegmentIndex < len(segments) {
+				current = segments[a.SegmentIndex].Text
+			}
+		}
+		if current == "" || tts.CacheKey(current, language, a.Voice) != a.ContentHash {
+			stale = append(stale, a.ID)
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+	return tx.Delete(&model.ReferenceAudio{}, stale).Error
+}
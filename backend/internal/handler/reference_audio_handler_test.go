@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/shuind/language-learner/backend/internal/model"
+	"github.com/shuind/language-learner/backend/internal/task"
+	"github.com/shuind/language-learner/backend/internal/testdb"
+	"github.com/shuind/language-learner/backend/internal/tts"
+)
+
+// fakeQueue 记录发布的任务
+type fakeQueue struct {
+	mu     sync.Mutex
+	queues []string
+	bodies [][]byte
+}
+
+func (q *fakeQueue) Publish(ctx context.Context, queue string, body []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.queues = append(q.queues, queue)
+	q.bodies = append(q.bodies, body)
+	return nil
+}
+
+func (q *fakeQueue) count() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.bodies)
+}
+
+// synthesize 按 worker 的方式用 tts.Fake 完成一条 pending 记录
+func synthesize(t *testing.T, db *gorm.DB, synth tts.Synthesizer, audio *model.ReferenceAudio) {
+	t.Helper()
+	result, err := synth.Synthesize(context.Background(), audio.Text, audio.Language, audio.Voice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objectName := fmt.Sprintf("tts/%s.%s", audio.ContentHash, tts.Extension(result.ContentType))
+	if err := db.Model(audio).Updates(map[string]interface{}{
+		"status":       model.ReferenceAudioCompleted,
+		"audio_url":    "http://minio/recordings/" + objectName,
+		"object_name":  objectName,
+		"content_type": result.ContentType,
+		"duration_ms":  result.Duration.Milliseconds(),
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRequestReferenceAudioReusesSynthesisByContentHash(t *testing.T) {
+	db := testdb.Open(t, &model.ReferenceAudio{})
+	queue := &fakeQueue{}
+	synth := &tts.Fake{}
+	ctx := context.Background()
+	const text = "The quick brown fox jumps over the lazy dog."
+
+	first := &referenceAudioTarget{kind: model.RevisionKindNode, nodeID: 1, nodeType: "text", content: text, language: "en"}
+	a, err := requestReferenceAudio(ctx, db, queue, first, model.ReferenceAudioWholeNode, text, "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Status != model.ReferenceAudioPending || queue.count() != 1 || queue.queues[0] != task.ReferenceAudioQueue {
+		t.Fatalf("first request: status %q, %d tasks queued", a.Status, queue.count())
+	}
+	synthesize(t, db, synth, a)
+
+	// 另一个节点上的相同文字、语言与音色命中缓存，不再排队合成
+	second := &referenceAudioTarget{kind: model.RevisionKindDomainNode, nodeID: 7, nodeType: "text", content: text, language: "en"}
+	b, err := requestReferenceAudio(ctx, db, queue, second, model.ReferenceAudioWholeNode, text, "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Status != model.ReferenceAudioCompleted {
+		t.Fatalf("cache hit status = %q, want completed", b.Status)
+	}
+	if b.ContentHash != a.ContentHash || b.ObjectName != fmt.Sprintf("tts/%s.wav", a.ContentHash) || b.DurationMs == 0 {
+		t.Fatalf("cache hit did not reuse the synthesized audio: %+v", b)
+	}
+	if queue.count() != 1 || len(synth.Requests()) != 1 {
+		t.Fatalf("cache hit queued %d tasks and synthesized %d times, want 1 and 1", queue.count(), len(synth.Requests()))
+	}
+
+	// 重复请求同一节点返回已有记录
+	again, err := requestReferenceAudio(ctx, db, queue, first, model.ReferenceAudioWholeNode, text, "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != a.ID || queue.count() != 1 {
+		t.Fatalf("repeated request created record %d (want %d), %d tasks queued", again.ID, a.ID, queue.count())
+	}
+
+	// 音色不同即为不同的内容
+	c, err := requestReferenceAudio(ctx, db, queue, second, model.ReferenceAudioWholeNode, text, "alloy", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Status != model.ReferenceAudioPending || c.ContentHash == a.ContentHash || queue.count() != 2 {
+		t.Fatalf("different voice: status %q, %d tasks queued", c.Status, queue.count())
+	}
+}
+
+func TestInvalidateReferenceAudioKeepsUnchangedSegments(t *testing.T) {
+	db := testdb.Open(t, &model.ReferenceAudio{})
+	synth := &tts.Fake{}
+	ctx := context.Background()
+	queue := &fakeQueue{}
+	content := "First sentence. Second sentence."
+	target := &referenceAudioTarget{kind: model.RevisionKindNode, nodeID: 1, nodeType: "text", content: content, language: "en"}
+
+	whole, err := requestReferenceAudio(ctx, db, queue, target, model.ReferenceAudioWholeNode, content, "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := requestReferenceAudio(ctx, db, queue, target, 0, "First sentence.", "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := requestReferenceAudio(ctx, db, queue, target, 1, "Second sentence.", "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range []*model.ReferenceAudio{whole, first, second} {
+		synthesize(t, db, synth, a)
+	}
+
+	if err := InvalidateReferenceAudio(db, model.RevisionKindNode, 1, "text", "First sentence. Changed sentence.", "en"); err != nil {
+		t.Fatal(err)
+	}
+	var left []uint
+	db.Model(&model.ReferenceAudio{}).Order("id").Pluck("id", &left)
+	if len(left) != 1 || left[0] != first.ID {
+		t.Fatalf("remaining audio %v, want only the unchanged first sentence %d", left, first.ID)
+	}
+}
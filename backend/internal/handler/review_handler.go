@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/shuind/language-learner/backend/internal/model"
+	"github.com/shuind/language-learner/backend/internal/nodecontent"
+	"github.com/shuind/language-learner/backend/internal/review"
+)
+
+// ReviewHandler 处理个人节点的间隔重复复习
+type ReviewHandler struct {
+	DB *gorm.DB
+}
+
+func NewReviewHandler(db *gorm.DB) *ReviewHandler {
+	return &ReviewHandler{DB: db}
+}
+
+type ReviewInput struct {
+	Quality *int `json:"quality" binding:"required"` // 0 完全忘记 - 5 毫不费力
+}
+
+// DueNodeResponse 是待复习列表中的一项
+type DueNodeResponse struct {
+	Node   model.Node        `json:"node"`
+	Review model.ReviewState `json:"review"`
+}
+
+// ReviewNode POST /nodes/:id/review
+// 第一次提交会把节点加入复习计划
+func (h *ReviewHandler) ReviewNode(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	var input ReviewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var node model.Node
+	if err := h.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&node).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found or permission denied"})
+		return
+	}
+	if !nodecontent.Recitable(node.NodeType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Folders cannot be reviewed"})
+		return
+	}
+
+	var rec model.NodeReview
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		rec, err = review.RecordNode(tx, userID, node.ID, *input.Quality, time.Now())
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, review.ErrInvalidQuality) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record review"})
+		return
+	}
+	c.JSON(http.StatusOK, rec)
+}
+
+// ListDueNodes GET /reviews/due
+func (h *ReviewHandler) ListDueNodes(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	var reviews []model.NodeReview
+	if err := h.DB.Where("user_id = ? AND due_at <= ?", userID, time.Now()).
+		Where("node_id IN (SELECT id FROM nodes WHERE deleted_at IS NULL)").
+		Order("due_at").
+		Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list due reviews"})
+		return
+	}
+
+	nodeIDs := make([]uint, len(reviews))
+	for i, r := range reviews {
+		nodeIDs[i] = r.NodeID
+	}
+	nodes := make(map[uint]model.Node, len(reviews))
+	if len(nodeIDs) > 0 {
+		var list []model.Node
+		h.DB.Preload("Tags").Where("id IN ?", nodeIDs).Find(&list)
+		for _, n := range list {
+			nodes[n.ID] = n
+		}
+	}
+
+	response := make([]DueNodeResponse, 0, len(reviews))
+	for _, r := range reviews {
+		if n, ok := nodes[r.NodeID]; ok {
+			response = append(response, DueNodeResponse{Node: n, Review: r.ReviewState})
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,456 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/shuind/language-learner/backend/internal/collab"
+	"github.com/shuind/language-learner/backend/internal/model"
+	"github.com/shuind/language-learner/backend/internal/search"
+	"github.com/shuind/language-learner/backend/internal/textdiff"
+)
+
+// diffContextLines 是存储 unified diff 时每个改动块前后保留的行数
+const diffContextLines = 3
+
+// RevisionHandler 处理修订历史；Collab 用于拒绝恢复正在协同编辑的圈子节点
+type RevisionHandler struct {
+	DB     *gorm.DB
+	Collab *collab.Hub
+}
+
+func NewRevisionHandler(db *gorm.DB, hub *collab.Hub) *RevisionHandler {
+	return &RevisionHandler{DB: db, Collab: hub}
+}
+
+// LatestRevision 返回节点当前的最新修订
+func LatestRevision(tx *gorm.DB, kind string, nodeID uint) (*model.NodeRevision, error) {
+	var rev model.NodeRevision
+	if err := tx.Where("node_kind = ? AND node_id = ?", kind, nodeID).
+		Order("version DESC").
+		First(&rev).Error; err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+// RecordRevision 为节点追加一条修订
+// 如果标题和内容与最新修订完全一致，则直接返回最新修订，不会产生重复版本；
+// 对于还没有任何修订的老节点，这一步会补建基线版本。
+func RecordRevision(tx *gorm.DB, kind string, nodeID, authorID uint, title, content string) (*model.NodeRevision, error) {
+	return recordRevision(tx, kind, nodeID, authorID, title, content, nil)
+}
+
+// ResolveRevision 在事务中锁住节点行，返回节点当前内容对应的修订（必要时补建）。
+// 录音上传用它固定朗读的版本：同一节点的首批录音并发到达时，只有一个请求会补建基线版本
+func ResolveRevision(db *gorm.DB, kind string, nodeID, authorID uint) (*model.NodeRevision, error) {
+	var rev *model.NodeRevision
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var title, content string
+		locked := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("title", "content")
+		switch kind {
+		case model.RevisionKindDomainNode:
+			var node model.DomainNode
+			if err := locked.First(&node, nodeID).Error; err != nil {
+				return err
+			}
+			title, content = node.Title, node.Content
+		default:
+			var node model.Node
+			if err := locked.First(&node, nodeID).Error; err != nil {
+				return err
+			}
+			title, content = node.Title, node.Content
+		}
+		var err error
+		rev, err = RecordRevision(tx, kind, nodeID, authorID, title, content)
+		return err
+	})
+	return rev, err
+}
+
+// RevisionRecorder 返回可用作 nodetree.CopyTarget.AfterCreate 的回调，为每个副本节点记录首个修订
+func RevisionRecorder(kind string, authorID uint) func(tx *gorm.DB, id uint, title, content string) error {
+	return func(tx *gorm.DB, id uint, title, content string) error {
+		_, err := RecordRevision(tx, kind, id, authorID, title, content)
+		return err
+	}
+}
+
+func recordRevision(tx *gorm.DB, kind string, nodeID, authorID uint, title, content string, restoredFrom *uint) (*model.NodeRevision, error) {
+	latest, err := LatestRevision(tx, kind, nodeID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	rev := model.NodeRevision{
+		NodeKind:     kind,
+		NodeID:       nodeID,
+		Version:      1,
+		AuthorID:     authorID,
+		Title:        title,
+		Content:      content,
+		RestoredFrom: restoredFrom,
+	}
+	if latest != nil {
+		if latest.Title == title && latest.Content == content && restoredFrom == nil {
+			return latest, nil
+		}
+		_, stats := textdiff.LineDiff(latest.Content, content)
+		rev.Version = latest.Version + 1
+		rev.Diff = textdiff.Unified(latest.Content, content, diffContextLines)
+		rev.LinesAdded = stats.Added
+		rev.LinesRemoved = stats.Removed
+	}
+
+	if err := tx.Create(&rev).Error; err != nil {
+		return nil, err
+	}
+	// 标注锚点跟随内容移动到新修订上
+	if latest != nil {
+		if err := remapAnnotations(tx, kind, nodeID, latest.Content, content, rev.ID); err != nil {
+			return nil, err
+		}
+	}
+	return &rev, nil
+}
+
+// --- DTO ---
+
+type RevisionResponse struct {
+	ID           uint           `json:"id"`
+	Version      int            `json:"version"`
+	Title        string         `json:"title"`
+	CreatedAt    time.Time      `json:"created_at"`
+	Author       AuthorResponse `json:"author"`
+	LinesAdded   int            `json:"lines_added"`
+	LinesRemoved int            `json:"lines_removed"`
+	RestoredFrom *uint          `json:"restored_from,omitempty"`
+}
+
+type RevisionDiffResponse struct {
+	From    int             `json:"from"`
+	To      int             `json:"to"`
+	Stats   textdiff.Stats  `json:"stats"`
+	Lines   []textdiff.Line `json:"lines"`
+	Unified string          `json:"unified"`
+}
+
+// --- 权限检查 ---
+
+// loadNode 校验个人节点的归属
+func (h *RevisionHandler) loadNode(c *gin.Context) (*model.Node, bool) {
+	userID := c.MustGet("userID").(uint)
+	var node model.Node
+	if err := h.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&node).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found or permission denied"})
+		return nil, false
+	}
+	return &node, true
+}
+
+// loadDomainNode 校验用户是圈子节点所在圈子的成员；manage 为 true 时要求圈主/管理员
+func (h *RevisionHandler) loadDomainNode(c *gin.Context, manage bool) (*model.DomainNode, bool) {
+	userID := c.MustGet("userID").(uint)
+	var node model.DomainNode
+	if err := h.DB.First(&node, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Domain node not found"})
+		return nil, false
+	}
+
+	query := h.DB.Where("domain_id = ? AND user_id = ?", node.DomainID, userID)
+	if manage {
+		query = query.Where("role IN ?", []string{"owner", "admin"})
+	}
+	var member model.DomainMember
+	if err := query.First(&member).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return nil, false
+	}
+	return &node, true
+}
+
+// --- 个人节点 ---
+
+// ListNodeRevisions GET /nodes/:id/revisions
+func (h *RevisionHandler) ListNodeRevisions(c *gin.Context) {
+	node, ok := h.loadNode(c)
+	if !ok {
+		return
+	}
+	h.listRevisions(c, model.RevisionKindNode, node.ID)
+}
+
+// GetNodeRevision GET /nodes/:id/revisions/:version
+func (h *RevisionHandler) GetNodeRevision(c *gin.Context) {
+	node, ok := h.loadNode(c)
+	if !ok {
+		return
+	}
+	h.getRevision(c, model.RevisionKindNode, node.ID)
+}
+
+// DiffNodeRevisions GET /nodes/:id/revisions/diff?from=1&to=3
+func (h *RevisionHandler) DiffNodeRevisions(c *gin.Context) {
+	node, ok := h.loadNode(c)
+	if !ok {
+		return
+	}
+	h.diffRevisions(c, model.RevisionKindNode, node.ID)
+}
+
+// RestoreNodeRevision POST /nodes/:id/revisions/:version/restore
+func (h *RevisionHandler) RestoreNodeRevision(c *gin.Context) {
+	node, ok := h.loadNode(c)
+	if !ok {
+		return
+	}
+	userID := c.MustGet("userID").(uint)
+
+	var rev model.NodeRevision
+	if !h.findRevision(c, model.RevisionKindNode, node.ID, &rev) {
+		return
+	}
+	if !IfMatch(c, node.Version) {
+		RespondVersionConflict(c, node.Version, node)
+		return
+	}
+
+	var newRev *model.NodeRevision
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{"title": rev.Title, "content": rev.Content}
+		if err := UpdateVersioned(tx, &model.Node{}, node.ID, node.Version, updates); err != nil {
+			return err
+		}
+		if err := tx.First(node, node.ID).Error; err != nil {
+			return err
+		}
+		if err := search.IndexNode(tx, node); err != nil {
+			return err
+		}
+		if err := InvalidateReferenceAudio(tx, model.RevisionKindNode, node.ID, node.NodeType, node.Content, node.Language); err != nil {
+			return err
+		}
+		if err := RealignTranslations(tx, model.RevisionKindNode, node.ID, node.NodeType, node.Content); err != nil {
+			return err
+		}
+		var err error
+		newRev, err = recordRevision(tx, model.RevisionKindNode, node.ID, userID, rev.Title, rev.Content, &rev.ID)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, ErrVersionConflict) {
+			h.DB.First(node, node.ID)
+			RespondVersionConflict(c, node.Version, node)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore revision"})
+		return
+	}
+
+	SetVersionETag(c, node.Version)
+	c.JSON(http.StatusOK, gin.H{"node": node, "revision": newRev})
+}
+
+// --- 圈子节点 ---
+
+// ListDomainNodeRevisions GET /domain-nodes/:id/revisions
+func (h *RevisionHandler) ListDomainNodeRevisions(c *gin.Context) {
+	node, ok := h.loadDomainNode(c, false)
+	if !ok {
+		return
+	}
+	h.listRevisions(c, model.RevisionKindDomainNode, node.ID)
+}
+
+// GetDomainNodeRevision GET /domain-nodes/:id/revisions/:version
+func (h *RevisionHandler) GetDomainNodeRevision(c *gin.Context) {
+	node, ok := h.loadDomainNode(c, false)
+	if !ok {
+		return
+	}
+	h.getRevision(c, model.RevisionKindDomainNode, node.ID)
+}
+
+// DiffDomainNodeRevisions GET /domain-nodes/:id/revisions/diff?from=1&to=3
+func (h *RevisionHandler) DiffDomainNodeRevisions(c *gin.Context) {
+	node, ok := h.loadDomainNode(c, false)
+	if !ok {
+		return
+	}
+	h.diffRevisions(c, model.RevisionKindDomainNode, node.ID)
+}
+
+// RestoreDomainNodeRevision POST /domain-nodes/:id/revisions/:version/restore
+// 只有圈主和管理员可以恢复圈子内容
+func (h *RevisionHandler) RestoreDomainNodeRevision(c *gin.Context) {
+	node, ok := h.loadDomainNode(c, true)
+	if !ok {
+		return
+	}
+	userID := c.MustGet("userID").(uint)
+
+	var rev model.NodeRevision
+	if !h.findRevision(c, model.RevisionKindDomainNode, node.ID, &rev) {
+		return
+	}
+	if !IfMatch(c, node.Version) {
+		RespondVersionConflict(c, node.Version, node)
+		return
+	}
+	// 协同会话的下一次快照会覆盖恢复的内容，须等所有人退出编辑后再恢复
+	if h.Collab != nil && h.Collab.Active(node.ID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Node is being edited collaboratively", "editors": h.Collab.Presence(node.ID)})
+		return
+	}
+
+	var newRev *model.NodeRevision
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{"title": rev.Title, "content": rev.Content}
+		if err := UpdateVersioned(tx, &model.DomainNode{}, node.ID, node.Version, updates); err != nil {
+			return err
+		}
+		if err := tx.First(node, node.ID).Error; err != nil {
+			return err
+		}
+		if err := search.IndexDomainNode(tx, node); err != nil {
+			return err
+		}
+		if err := InvalidateReferenceAudio(tx, model.RevisionKindDomainNode, node.ID, node.NodeType, node.Content, node.Language); err != nil {
+			return err
+		}
+		if err := RealignTranslations(tx, model.RevisionKindDomainNode, node.ID, node.NodeType, node.Content); err != nil {
+			return err
+		}
+		var err error
+		newRev, err = recordRevision(tx, model.RevisionKindDomainNode, node.ID, userID, rev.Title, rev.Content, &rev.ID)
+		if err != nil {
+			return err
+		}
+		return RecordDomainActivity(tx, node.DomainID, userID, model.ActivityNodeUpdated, node.ID, node.Title)
+	})
+	if err != nil {
+		if errors.Is(err, ErrVersionConflict) {
+			h.DB.First(node, node.ID)
+			RespondVersionConflict(c, node.Version, node)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore revision"})
+		return
+	}
+
+	SetVersionETag(c, node.Version)
+	c.JSON(http.StatusOK, gin.H{"node": node, "revision": newRev})
+}
+
+// --- 通用实现 ---
+
+func (h *RevisionHandler) listRevisions(c *gin.Context, kind string, nodeID uint) {
+	var revisions []model.NodeRevision
+	if err := h.DB.Omit("content", "diff").
+		Where("node_kind = ? AND node_id = ?", kind, nodeID).
+		Preload("Author").
+		Order("version DESC").
+		Find(&revisions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve revisions"})
+		return
+	}
+
+	response := make([]RevisionResponse, len(revisions))
+	for i, r := range revisions {
+		response[i] = RevisionResponse{
+			ID:           r.ID,
+			Version:      r.Version,
+			Title:        r.Title,
+			CreatedAt:    r.CreatedAt,
+			Author:       AuthorResponse{ID: r.Author.ID, Username: r.Author.Username, AvatarURL: r.Author.AvatarURL},
+			LinesAdded:   r.LinesAdded,
+			LinesRemoved: r.LinesRemoved,
+			RestoredFrom: r.RestoredFrom,
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *RevisionHandler) getRevision(c *gin.Context, kind string, nodeID uint) {
+	var rev model.NodeRevision
+	if !h.findRevision(c, kind, nodeID, &rev) {
+		return
+	}
+	c.JSON(http.StatusOK, rev)
+}
+
+// findRevision 按 URL 中的 :version 查找修订，找不到时直接写入错误响应
+func (h *RevisionHandler) findRevision(c *gin.Context, kind string, nodeID uint, rev *model.NodeRevision) bool {
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version"})
+		return false
+	}
+	if err := h.DB.Where("node_kind = ? AND node_id = ? AND version = ?", kind, nodeID, version).First(rev).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return false
+	}
+	return true
+}
+
+func (h *RevisionHandler) diffRevisions(c *gin.Context, kind string, nodeID uint) {
+	latest, err := LatestRevision(h.DB, kind, nodeID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "This node has no revisions yet"})
+		return
+	}
+
+	// to 默认为最新版本，from 默认为 to 的上一个版本
+	to := latest.Version
+	if s := c.Query("to"); s != "" {
+		if to, err = strconv.Atoi(s); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to version"})
+			return
+		}
+	}
+	from := to - 1
+	if s := c.Query("from"); s != "" {
+		if from, err = strconv.Atoi(s); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from version"})
+			return
+		}
+	}
+
+	var revisions []model.NodeRevision
+	if err := h.DB.Where("node_kind = ? AND node_id = ? AND version IN ?", kind, nodeID, []int{from, to}).Find(&revisions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve revisions"})
+		return
+	}
+	byVersion := make(map[int]model.NodeRevision, len(revisions))
+	for _, r := range revisions {
+		byVersion[r.Version] = r
+	}
+
+	// from 为 0 表示与空文本对比（即首个版本的完整内容）
+	fromRev, ok := byVersion[from]
+	if !ok && from != 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found", "version": from})
+		return
+	}
+	toRev, ok := byVersion[to]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found", "version": to})
+		return
+	}
+
+	lines, stats := textdiff.LineDiff(fromRev.Content, toRev.Content)
+	c.JSON(http.StatusOK, RevisionDiffResponse{
+		From:    from,
+		To:      to,
+		Stats:   stats,
+		Lines:   lines,
+		Unified: textdiff.Unified(fromRev.Content, toRev.Content, diffContextLines),
+	})
+}
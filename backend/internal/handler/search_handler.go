@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/shuind/language-learner/backend/internal/model"
+	"github.com/shuind/language-learner/backend/internal/search"
+)
+
+// SearchHandler 提供跨个人节点、圈子节点、文本与帖子的统一搜索
+type SearchHandler struct {
+	DB *gorm.DB
+}
+
+func NewSearchHandler(db *gorm.DB) *SearchHandler {
+	return &SearchHandler{DB: db}
+}
+
+// maxSearchLimit 是每页结果数的上限
+const maxSearchLimit = 50
+
+// Search GET /search?q=关键词&type=node,post&page=1&limit=20
+// 带 tags / due 筛选参数时只搜索个人节点
+func (h *SearchHandler) Search(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query cannot be empty"})
+		return
+	}
+
+	var types []string
+	if t := c.Query("type"); t != "" {
+		for _, typ := range strings.Split(t, ",") {
+			typ = strings.TrimSpace(typ)
+			if !isSearchType(typ) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown search type: " + typ})
+				return
+			}
+			types = append(types, typ)
+		}
+	}
+
+	filter, err := ParseNodeFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var scope func(*gorm.DB) *gorm.DB
+	if !filter.Empty() {
+		types = []string{model.SearchSourceNode}
+		scope = filter.Scope("d.source_id", userID)
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > maxSearchLimit {
+		limit = 20
+	}
+
+	results, total, err := search.Search(h.DB, search.Query{
+		UserID: userID,
+		Text:   q,
+		Types:  types,
+		Page:   page,
+		Limit:  limit,
+		Scope:  scope,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to perform search"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"total": total, "page": page, "results": results})
+}
+
+func isSearchType(typ string) bool {
+	for _, t := range search.AllTypes {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,278 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"github.com/shuind/language-learner/backend/internal/model"
+	"github.com/shuind/language-learner/backend/internal/nodetree"
+	"github.com/shuind/language-learner/backend/internal/utils"
+)
+
+// ShareHandler 处理个人节点的只读分享链接
+type ShareHandler struct {
+	DB *gorm.DB
+}
+
+func NewShareHandler(db *gorm.DB) *ShareHandler {
+	return &ShareHandler{DB: db}
+}
+
+// shareTokenBytes 是分享令牌的随机字节数（编码后 43 个字符）
+const shareTokenBytes = 32
+
+var (
+	// ErrShareNotFound 表示令牌不存在、已撤销或分享的节点已被删除
+	ErrShareNotFound = errors.New("share link not found")
+	// ErrShareExpired 表示分享链接已过期
+	ErrShareExpired = errors.New("share link has expired")
+	// ErrSharePassword 表示需要密码或密码错误
+	ErrSharePassword = errors.New("share link password required or incorrect")
+)
+
+type CreateShareLinkInput struct {
+	ExpiresAt      *time.Time `json:"expires_at"` // 为空表示永不过期
+	Password       string     `json:"password" binding:"omitempty,min=4,max=72"`
+	AllowRecording bool       `json:"allow_recording"`
+}
+
+type CloneSharedInput struct {
+	TargetParentID *uint `json:"target_parent_id"` // null 表示复制到根目录
+}
+
+// ShareLinkResponse 是分享者看到的链接信息
+type ShareLinkResponse struct {
+	model.ShareLink
+	HasPassword bool `json:"has_password"`
+}
+
+// SharedContentResponse 是访问者看到的分享内容
+type SharedContentResponse struct {
+	Title          string         `json:"title"`
+	Owner          AuthorResponse `json:"owner"`
+	AllowRecording bool           `json:"allow_recording"`
+	ExpiresAt      *time.Time     `json:"expires_at"`
+	Tree           *TreeNode      `json:"tree"`
+}
+
+// ResolveShareLink 校验令牌与密码，返回有效的分享链接
+func ResolveShareLink(db *gorm.DB, token, password string) (*model.ShareLink, error) {
+	var link model.ShareLink
+	if err := db.Where("token = ? AND revoked_at IS NULL", token).First(&link).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrShareNotFound
+		}
+		return nil, err
+	}
+	if link.ExpiresAt != nil && link.ExpiresAt.Before(time.Now()) {
+		return nil, ErrShareExpired
+	}
+	if link.PasswordHash != "" {
+		if password == "" || bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)) != nil {
+			return nil, ErrSharePassword
+		}
+	}
+	var count int64
+	if err := db.Model(&model.Node{}).Where("id = ? AND user_id = ?", link.NodeID, link.UserID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrShareNotFound
+	}
+	return &link, nil
+}
+
+// ShareCovers 判断 nodeID 是否位于分享链接的子树中
+func ShareCovers(db *gorm.DB, link *model.ShareLink, nodeID uint) (bool, error) {
+	return nodetree.IsSelfOrDescendant(db, nodetree.Nodes, link.NodeID, nodeID)
+}
+
+// RespondShareError 把 ResolveShareLink 的错误转换为 HTTP 响应
+func RespondShareError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrShareNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Share link not found"})
+	case errors.Is(err, ErrShareExpired):
+		c.JSON(http.StatusGone, gin.H{"error": "Share link has expired"})
+	case errors.Is(err, ErrSharePassword):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Password required or incorrect", "password_required": true})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load share link"})
+	}
+}
+
+// sharePassword 从请求头或查询参数中读取访问密码
+func sharePassword(c *gin.Context) string {
+	if p := c.GetHeader("X-Share-Password"); p != "" {
+		return p
+	}
+	return c.Query("password")
+}
+
+// ---------------------- 分享者 ----------------------
+
+// CreateShareLink POST /nodes/:id/share-links
+func (h *ShareHandler) CreateShareLink(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	var input CreateShareLinkInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.ExpiresAt != nil && input.ExpiresAt.Before(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+		return
+	}
+
+	var node model.Node
+	if err := h.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&node).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found or permission denied"})
+		return
+	}
+
+	token, err := utils.GenerateSecureToken(shareTokenBytes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate share token"})
+		return
+	}
+	link := model.ShareLink{
+		UserID:         userID,
+		NodeID:         node.ID,
+		Token:          token,
+		ExpiresAt:      input.ExpiresAt,
+		AllowRecording: input.AllowRecording,
+	}
+	if input.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+			return
+		}
+		link.PasswordHash = string(hash)
+	}
+	if err := h.DB.Create(&link).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create share link"})
+		return
+	}
+	c.JSON(http.StatusCreated, ShareLinkResponse{ShareLink: link, HasPassword: link.PasswordHash != ""})
+}
+
+// ListShareLinks GET /nodes/:id/share-links
+func (h *ShareHandler) ListShareLinks(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	var links []model.ShareLink
+	if err := h.DB.Where("node_id = ? AND user_id = ?", c.Param("id"), userID).
+		Order("created_at DESC").
+		Find(&links).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list share links"})
+		return
+	}
+	response := make([]ShareLinkResponse, len(links))
+	for i, l := range links {
+		response[i] = ShareLinkResponse{ShareLink: l, HasPassword: l.PasswordHash != ""}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// RevokeShareLink DELETE /share-links/:id
+func (h *ShareHandler) RevokeShareLink(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	res := h.DB.Model(&model.ShareLink{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", c.Param("id"), userID).
+		Update("revoked_at", time.Now())
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke share link"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Share link not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ---------------------- 访问者 ----------------------
+
+// GetSharedContent GET /shared/:token （无需登录）
+// 需要密码的链接通过 X-Share-Password 请求头或 password 查询参数提供密码
+func (h *ShareHandler) GetSharedContent(c *gin.Context) {
+	link, err := ResolveShareLink(h.DB, c.Param("token"), sharePassword(c))
+	if err != nil {
+		RespondShareError(c, err)
+		return
+	}
+
+	entries, err := nodetree.Subtree(h.DB, nodetree.Nodes, link.UserID, &link.NodeID, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load shared content"})
+		return
+	}
+	roots, err := buildTree(h.DB, nodetree.Nodes, entries, true)
+	if err != nil || len(roots) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load shared content"})
+		return
+	}
+
+	var owner model.User
+	h.DB.Select("id", "username", "avatar_url").First(&owner, link.UserID)
+	h.DB.Model(link).UpdateColumn("views_count", gorm.Expr("views_count + 1"))
+
+	c.JSON(http.StatusOK, SharedContentResponse{
+		Title:          roots[0].Title,
+		Owner:          AuthorResponse{ID: owner.ID, Username: owner.Username, AvatarURL: owner.AvatarURL},
+		AllowRecording: link.AllowRecording,
+		ExpiresAt:      link.ExpiresAt,
+		Tree:           roots[0],
+	})
+}
+
+// CloneSharedContent POST /shared/:token/clone （需要登录）
+// 把分享的整棵子树复制到当前用户的个人空间
+func (h *ShareHandler) CloneSharedContent(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	var input CloneSharedInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	link, err := ResolveShareLink(h.DB, c.Param("token"), sharePassword(c))
+	if err != nil {
+		RespondShareError(c, err)
+		return
+	}
+
+	var copied model.Node
+	var count int
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if input.TargetParentID != nil {
+			if err := checkTargetFolder(tx, userID, *input.TargetParentID); err != nil {
+				return err
+			}
+		}
+		newID, n, err := nodetree.CopySubtree(tx, nodetree.Nodes, link.NodeID, nodetree.CopyTarget{
+			Kind:        nodetree.Nodes,
+			OwnerID:     userID,
+			ParentID:    input.TargetParentID,
+			AfterCreate: RevisionRecorder(model.RevisionKindNode, userID),
+		})
+		if err != nil {
+			return err
+		}
+		count = n
+		return tx.First(&copied, newID).Error
+	})
+	if err != nil {
+		if errors.Is(err, errTargetFolder) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Target folder not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clone shared content"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"node": copied, "copied": count})
+}
@@ -0,0 +1,310 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/shuind/language-learner/backend/internal/model"
+	"github.com/shuind/language-learner/backend/internal/nodetree"
+)
+
+// TagHandler 处理个人标签、节点打标签以及保存的筛选（虚拟文件夹）
+type TagHandler struct {
+	DB *gorm.DB
+}
+
+func NewTagHandler(db *gorm.DB) *TagHandler {
+	return &TagHandler{DB: db}
+}
+
+type TagInput struct {
+	Name  string `json:"name" binding:"required,min=1,max=50"`
+	Color string `json:"color" binding:"omitempty,hexcolor"`
+}
+
+type SetNodeTagsInput struct {
+	TagIDs []uint `json:"tag_ids"` // 完整替换节点上的标签，空数组表示清除
+}
+
+type SavedFilterInput struct {
+	Name         string `json:"name" binding:"required,min=1,max=100"`
+	TagIDs       []uint `json:"tag_ids"`
+	Mode         string `json:"mode" binding:"omitempty,oneof=and or"`
+	DueForReview bool   `json:"due_for_review"`
+}
+
+// errUnknownTag 表示请求中包含不属于当前用户的标签
+var errUnknownTag = errors.New("unknown tag")
+
+// ---------------------- 标签 ----------------------
+
+// ListTags GET /tags
+func (h *TagHandler) ListTags(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	tags := make([]model.Tag, 0)
+	if err := h.DB.Where("user_id = ?", userID).Order("name").Find(&tags).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list tags"})
+		return
+	}
+	c.JSON(http.StatusOK, tags)
+}
+
+// CreateTag POST /tags
+func (h *TagHandler) CreateTag(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	var input TagInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tag := model.Tag{UserID: userID, Name: strings.TrimSpace(input.Name), Color: input.Color}
+	if h.tagNameTaken(userID, tag.Name, 0) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A tag with this name already exists"})
+		return
+	}
+	if err := h.DB.Create(&tag).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create tag"})
+		return
+	}
+	// Color 为空时使用数据库默认值，重新读取一次
+	h.DB.First(&tag, tag.ID)
+	c.JSON(http.StatusCreated, tag)
+}
+
+// UpdateTag PUT /tags/:id
+func (h *TagHandler) UpdateTag(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	var input TagInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var tag model.Tag
+	if err := h.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&tag).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return
+	}
+	name := strings.TrimSpace(input.Name)
+	if h.tagNameTaken(userID, name, tag.ID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A tag with this name already exists"})
+		return
+	}
+	tag.Name = name
+	if input.Color != "" {
+		tag.Color = input.Color
+	}
+	if err := h.DB.Save(&tag).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tag"})
+		return
+	}
+	c.JSON(http.StatusOK, tag)
+}
+
+// DeleteTag DELETE /tags/:id
+// 同时移除该标签与节点、保存的筛选之间的关联
+func (h *TagHandler) DeleteTag(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	var tag model.Tag
+	if err := h.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&tag).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM node_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM saved_filter_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&tag).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete tag"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// SetNodeTags PUT /nodes/:id/tags
+func (h *TagHandler) SetNodeTags(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	var input SetNodeTagsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var node model.Node
+	if err := h.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&node).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found or permission denied"})
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		tags, err := h.ownedTags(tx, userID, input.TagIDs)
+		if err != nil {
+			return err
+		}
+		return tx.Model(&node).Association("Tags").Replace(tags)
+	})
+	if err != nil {
+		if errors.Is(err, errUnknownTag) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "One or more tags not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update node tags"})
+		return
+	}
+
+	h.DB.Preload("Tags").First(&node, node.ID)
+	c.JSON(http.StatusOK, node)
+}
+
+// ---------------------- 保存的筛选 ----------------------
+
+// ListSavedFilters GET /saved-filters
+func (h *TagHandler) ListSavedFilters(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	filters := make([]model.SavedFilter, 0)
+	if err := h.DB.Where("user_id = ?", userID).Preload("Tags").Order("name").Find(&filters).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list saved filters"})
+		return
+	}
+	c.JSON(http.StatusOK, filters)
+}
+
+// CreateSavedFilter POST /saved-filters
+func (h *TagHandler) CreateSavedFilter(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	var input SavedFilterInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := model.SavedFilter{UserID: userID}
+	h.saveFilter(c, &filter, input, http.StatusCreated)
+}
+
+// UpdateSavedFilter PUT /saved-filters/:id
+func (h *TagHandler) UpdateSavedFilter(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	var input SavedFilterInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var filter model.SavedFilter
+	if err := h.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&filter).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Saved filter not found"})
+		return
+	}
+	h.saveFilter(c, &filter, input, http.StatusOK)
+}
+
+// DeleteSavedFilter DELETE /saved-filters/:id
+func (h *TagHandler) DeleteSavedFilter(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	var filter model.SavedFilter
+	if err := h.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&filter).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Saved filter not found"})
+		return
+	}
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&filter).Association("Tags").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(&filter).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete saved filter"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListSavedFilterNodes GET /saved-filters/:id/nodes
+// 像打开文件夹一样返回符合筛选条件的所有节点（不区分所在文件夹）
+func (h *TagHandler) ListSavedFilterNodes(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	var filter model.SavedFilter
+	if err := h.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).Preload("Tags").First(&filter).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Saved filter not found"})
+		return
+	}
+
+	nodes := make([]model.Node, 0)
+	if err := h.DB.Where("user_id = ?", userID).
+		Scopes(FilterFromSaved(&filter).Scope("id", userID)).
+		Preload("Tags").
+		Order(nodetree.Nodes.Order).
+		Find(&nodes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not retrieve nodes"})
+		return
+	}
+	c.JSON(http.StatusOK, nodes)
+}
+
+// ---------------------- 通用实现 ----------------------
+
+func (h *TagHandler) saveFilter(c *gin.Context, filter *model.SavedFilter, input SavedFilterInput, status int) {
+	userID := c.MustGet("userID").(uint)
+	filter.Name = strings.TrimSpace(input.Name)
+	filter.Mode = input.Mode
+	if filter.Mode == "" {
+		filter.Mode = model.TagModeAny
+	}
+	filter.DueForReview = input.DueForReview
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		tags, err := h.ownedTags(tx, userID, input.TagIDs)
+		if err != nil {
+			return err
+		}
+		if err := tx.Omit("Tags").Save(filter).Error; err != nil {
+			return err
+		}
+		return tx.Model(filter).Association("Tags").Replace(tags)
+	})
+	if err != nil {
+		if errors.Is(err, errUnknownTag) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "One or more tags not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save filter"})
+		return
+	}
+
+	h.DB.Preload("Tags").First(filter, filter.ID)
+	c.JSON(status, filter)
+}
+
+// ownedTags 读取属于当前用户的标签，任一 ID 无效时返回 errUnknownTag
+func (h *TagHandler) ownedTags(tx *gorm.DB, userID uint, ids []uint) ([]model.Tag, error) {
+	tags := make([]model.Tag, 0)
+	ids = uniqueIDs(ids)
+	if len(ids) == 0 {
+		return tags, nil
+	}
+	if err := tx.Where("id IN ? AND user_id = ?", ids, userID).Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	if len(tags) != len(ids) {
+		return nil, errUnknownTag
+	}
+	return tags, nil
+}
+
+func (h *TagHandler) tagNameTaken(userID uint, name string, exceptID uint) bool {
+	var count int64
+	h.DB.Model(&model.Tag{}).Where("user_id = ? AND name = ? AND id <> ?", userID, name, exceptID).Count(&count)
+	return count > 0
+}
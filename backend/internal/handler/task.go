@@ -2,7 +2,6 @@ package handler
 
 import (
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -11,6 +10,7 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/shuind/language-learner/backend/internal/model"
+	"github.com/shuind/language-learner/backend/internal/utils"
 )
 
 type TaskHandler struct {
@@ -310,14 +310,7 @@ func WeekStartMonday(t time.Time) time.Time {
 
 func (h *TaskHandler) WeeklyScore(c *gin.Context) {
 	uid := c.MustGet("userID").(uint)
-	tz := os.Getenv("APP_TZ")
-	if tz == "" {
-		tz = "Asia/Shanghai"
-	}
-	loc, err := time.LoadLocation(tz)
-	if err != nil {
-		loc = time.FixedZone("CST", 8*3600)
-	}
+	tz, loc := utils.AppLocation()
 
 	now := time.Now().In(loc)
 	weekStart := WeekStartMonday(now)
@@ -379,14 +372,7 @@ func (h *TaskHandler) ScoreTrend(c *gin.Context) {
 	uid := c.MustGet("userID").(uint)
 	period := c.DefaultQuery("period", "day") // day|week|month
 
-	tz := os.Getenv("APP_TZ")
-	if tz == "" {
-		tz = "Asia/Shanghai"
-	}
-	loc, err := time.LoadLocation(tz)
-	if err != nil {
-		loc = time.FixedZone("CST", 8*3600)
-	}
+	tz, loc := utils.AppLocation()
 
 	now := time.Now().In(loc)
 
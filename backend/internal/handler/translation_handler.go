@@ -0,0 +1,341 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/shuind/language-learner/backend/internal/lang"
+	"github.com/shuind/language-learner/backend/internal/model"
+	"github.com/shuind/language-learner/backend/internal/nodecontent"
+)
+
+// TranslationHandler 处理节点内容的逐句对照译文
+type TranslationHandler struct {
+	DB *gorm.DB
+}
+
+func NewTranslationHandler(db *gorm.DB) *TranslationHandler {
+	return &TranslationHandler{DB: db}
+}
+
+type TranslationItem struct {
+	Index       *int   `json:"index" binding:"required,min=0"`
+	Translation string `json:"translation" binding:"max=5000"` // 为空表示删除这一句的译文
+}
+
+type PutTranslationsInput struct {
+	Language string            `json:"language" binding:"required"`
+	Items    []TranslationItem `json:"items" binding:"required,min=1,max=1000,dive"`
+}
+
+// SegmentTranslationResponse 是一句话的一种译文
+type SegmentTranslationResponse struct {
+	Language    string `json:"language"`
+	Translation string `json:"translation"`
+	// Stale 为 true 表示原句在翻译之后被修改过，译文可能需要更新
+	Stale bool `json:"stale"`
+}
+
+// SegmentResponse 是一句原文及其译文
+type SegmentResponse struct {
+	lang.Segment
+	Translations []SegmentTranslationResponse `json:"translations"`
+}
+
+// SegmentsResponse 是节点内容的逐句对照
+type SegmentsResponse struct {
+	Language string            `json:"language"` // 原文的语言
+	Segments []SegmentResponse `json:"segments"`
+}
+
+// translationTarget 是被翻译的节点
+type translationTarget struct {
+	kind     string
+	nodeID   uint
+	language string
+	text     string
+}
+
+// ---------------------- 个人节点 ----------------------
+
+// GetNodeSegments GET /nodes/:id/segments?language=
+func (h *TranslationHandler) GetNodeSegments(c *gin.Context) {
+	if t, ok := h.nodeTarget(c); ok {
+		h.get(c, t)
+	}
+}
+
+// PutNodeTranslations PUT /nodes/:id/translations
+func (h *TranslationHandler) PutNodeTranslations(c *gin.Context) {
+	if t, ok := h.nodeTarget(c); ok {
+		h.put(c, t)
+	}
+}
+
+func (h *TranslationHandler) nodeTarget(c *gin.Context) (*translationTarget, bool) {
+	userID := c.MustGet("userID").(uint)
+	var node model.Node
+	if err := h.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&node).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found or permission denied"})
+		return nil, false
+	}
+	if node.NodeType == nodecontent.TypeFolder {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Folders have no content to translate"})
+		return nil, false
+	}
+	return &translationTarget{
+		kind:     model.RevisionKindNode,
+		nodeID:   node.ID,
+		language: node.Language,
+		text:     nodecontent.PlainText(node.NodeType, node.Content),
+	}, true
+}
+
+// ---------------------- 圈子节点 ----------------------
+
+// GetDomainNodeSegments GET /domain-nodes/:id/segments?language=
+// 圈子成员均可查看
+func (h *TranslationHandler) GetDomainNodeSegments(c *gin.Context) {
+	if t, ok := h.domainNodeTarget(c, false); ok {
+		h.get(c, t)
+	}
+}
+
+// PutDomainNodeTranslations PUT /domain-nodes/:id/translations
+// 只有圈主和管理员可以编辑译文
+func (h *TranslationHandler) PutDomainNodeTranslations(c *gin.Context) {
+	if t, ok := h.domainNodeTarget(c, true); ok {
+		h.put(c, t)
+	}
+}
+
+func (h *TranslationHandler) domainNodeTarget(c *gin.Context, edit bool) (*translationTarget, bool) {
+	userID := c.MustGet("userID").(uint)
+	var node model.DomainNode
+	if err := h.DB.First(&node, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Domain node not found"})
+		return nil, false
+	}
+	if node.NodeType == nodecontent.TypeFolder {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Folders have no content to translate"})
+		return nil, false
+	}
+	var member model.DomainMember
+	if err := h.DB.Where("domain_id = ? AND user_id = ?", node.DomainID, userID).First(&member).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: you are not a member of this domain"})
+		return nil, false
+	}
+	if edit && member.Role != "owner" && member.Role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return nil, false
+	}
+	return &translationTarget{
+		kind:     model.RevisionKindDomainNode,
+		nodeID:   node.ID,
+		language: node.Language,
+		text:     nodecontent.PlainText(node.NodeType, node.Content),
+	}, true
+}
+
+// ---------------------- 通用实现 ----------------------
+
+// get 返回逐句对照，可按 language 查询参数只返回一种语言的译文
+func (h *TranslationHandler) get(c *gin.Context, t *translationTarget) {
+	language, err := lang.Normalize(c.Query("language"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.respond(c, t, language)
+}
+
+func (h *TranslationHandler) respond(c *gin.Context, t *translationTarget, language string) {
+	query := h.DB.Where("node_kind = ? AND node_id = ?", t.kind, t.nodeID)
+	if language != "" {
+		query = query.Where("language = ?", language)
+	}
+	var rows []model.SegmentTranslation
+	if err := query.Order("language, segment_index").Find(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load translations"})
+		return
+	}
+
+	segments := lang.Segments(t.text)
+	resp := SegmentsResponse{Language: t.language, Segments: make([]SegmentResponse, len(segments))}
+	for i, s := range segments {
+		resp.Segments[i] = SegmentResponse{Segment: s, Translations: make([]SegmentTranslationResponse, 0)}
+	}
+	for target, items := range alignTranslations(segments, rows) {
+		for i, item := range items {
+			if item != nil {
+				resp.Segments[i].Translations = append(resp.Segments[i].Translations, SegmentTranslationResponse{
+					Language: target, Translation: item.Translation, Stale: item.Source != segments[i].Text,
+				})
+			}
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// alignTranslations 把已保存的译文对齐到当前的句子上，按语言分组返回，下标即句子序号。
+// 原文修改后句子可能移位：先按原句内容匹配，匹配不到时退回到原来的序号（标记为过期），
+// 两者都不成立的译文不再显示。
+func alignTranslations(segments []lang.Segment, rows []model.SegmentTranslation) map[string][]*model.SegmentTranslation {
+	byText := make(map[string][]int)
+	for _, s := range segments {
+		byText[s.Text] = append(byText[s.Text], s.Index)
+	}
+
+	aligned := make(map[string][]*model.SegmentTranslation)
+	for _, r := range rows {
+		if _, ok := aligned[r.Language]; !ok {
+			aligned[r.Language] = make([]*model.SegmentTranslation, len(segments))
+		}
+	}
+	unchanged := func(r *model.SegmentTranslation) bool {
+		return r.SegmentIndex < len(segments) && segments[r.SegmentIndex].Text == r.Source
+	}
+
+	// 1. 原句未变
+	for i := range rows {
+		if r := &rows[i]; unchanged(r) {
+			aligned[r.Language][r.SegmentIndex] = r
+		}
+	}
+	// 2. 原句移动到了别的位置
+	var pending []*model.SegmentTranslation
+	for i := range rows {
+		r := &rows[i]
+		if unchanged(r) {
+			continue
+		}
+		items, placed := aligned[r.Language], false
+		for _, idx := range byText[r.Source] {
+			if items[idx] == nil {
+				items[idx], placed = r, true
+				break
+			}
+		}
+		if !placed {
+			pending = append(pending, r)
+		}
+	}
+	// 3. 原句被修改：留在原来的序号上
+	for _, r := range pending {
+		items := aligned[r.Language]
+		if r.SegmentIndex < len(segments) && items[r.SegmentIndex] == nil {
+			items[r.SegmentIndex] = r
+		}
+	}
+	return aligned
+}
+
+// RealignTranslations 在节点内容修改后把已保存的译文移到对齐后的序号上，与 alignTranslations 的显示结果一致；
+// 无法对齐的译文已不再显示，一并删除。之后按序号保存译文时不会覆盖移位的句子。
+func RealignTranslations(tx *gorm.DB, kind string, nodeID uint, nodeType, content string) error {
+	var rows []model.SegmentTranslation
+	if err := tx.Where("node_kind = ? AND node_id = ?", kind, nodeID).Find(&rows).Error; err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	target := make(map[uint]int, len(rows))
+	for _, items := range alignTranslations(lang.Segments(nodecontent.PlainText(nodeType, content)), rows) {
+		for i, item := range items {
+			if item != nil {
+				target[item.ID] = i
+			}
+		}
+	}
+	var orphaned []uint
+	var moved []model.SegmentTranslation
+	for _, r := range rows {
+		idx, ok := target[r.ID]
+		switch {
+		case !ok:
+			orphaned = append(orphaned, r.ID)
+		case idx != r.SegmentIndex:
+			r.SegmentIndex = idx
+			moved = append(moved, r)
+		}
+	}
+	if len(orphaned) > 0 {
+		if err := tx.Delete(&model.SegmentTranslation{}, orphaned).Error; err != nil {
+			return err
+		}
+	}
+	// 句子互换位置时新旧序号会冲突，先移到临时的负序号上再写入目标序号
+	for _, r := range moved {
+		if err := tx.Model(&model.SegmentTranslation{}).Where("id = ?", r.ID).
+			Update("segment_index", -int(r.ID)).Error; err != nil {
+			return err
+		}
+	}
+	for _, r := range moved {
+		if err := tx.Model(&model.SegmentTranslation{}).Where("id = ?", r.ID).
+			Update("segment_index", r.SegmentIndex).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *TranslationHandler) put(c *gin.Context, t *translationTarget) {
+	userID := c.MustGet("userID").(uint)
+	var input PutTranslationsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	language, err := lang.Normalize(input.Language)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	segments := lang.Segments(t.text)
+	for _, item := range input.Items {
+		if *item.Index >= len(segments) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Segment index out of range", "segments": len(segments)})
+			return
+		}
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		for _, item := range input.Items {
+			if item.Translation == "" {
+				if err := tx.Where("node_kind = ? AND node_id = ? AND language = ? AND segment_index = ?", t.kind, t.nodeID, language, *item.Index).
+					Delete(&model.SegmentTranslation{}).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			row := model.SegmentTranslation{
+				NodeKind:     t.kind,
+				NodeID:       t.nodeID,
+				Language:     language,
+				SegmentIndex: *item.Index,
+				Source:       segments[*item.Index].Text,
+				Translation:  item.Translation,
+				AuthorID:     userID,
+			}
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "node_kind"}, {Name: "node_id"}, {Name: "language"}, {Name: "segment_index"}},
+				DoUpdates: clause.AssignmentColumns([]string{"source", "translation", "author_id", "updated_at"}),
+			}).Create(&row).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save translations"})
+		return
+	}
+	h.respond(c, t, language)
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"gorm.io/gorm"
+
+	"github.com/shuind/language-learner/backend/internal/model"
+	"github.com/shuind/language-learner/backend/internal/nodetree"
+)
+
+// TrashHandler 处理个人与圈子的回收站；彻底删除时一并删除对象存储中的音频文件
+type TrashHandler struct {
+	DB     *gorm.DB
+	Minio  *minio.Client
+	Bucket string
+}
+
+func NewTrashHandler(db *gorm.DB, minioClient *minio.Client, bucket string) *TrashHandler {
+	return &TrashHandler{DB: db, Minio: minioClient, Bucket: bucket}
+}
+
+func ownedBy(userID uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB { return db.Where("user_id = ?", userID) }
+}
+
+func inDomain(domainID uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB { return db.Where("domain_id = ?", domainID) }
+}
+
+// ---------------------- 个人回收站 ----------------------
+
+// ListMyTrash GET /trash
+func (h *TrashHandler) ListMyTrash(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	items, err := nodetree.ListTrash(h.DB, nodetree.Nodes, ownedBy(userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list trash"})
+		return
+	}
+	if items == nil {
+		items = make([]nodetree.TrashItem, 0)
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+// RestoreMyNode POST /trash/nodes/:id/restore
+func (h *TrashHandler) RestoreMyNode(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	nodeID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid node ID"})
+		return
+	}
+	h.restore(c, nodetree.Nodes, uint(nodeID), ownedBy(userID))
+}
+
+// PurgeMyNode DELETE /trash/nodes/:id
+func (h *TrashHandler) PurgeMyNode(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	nodeID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid node ID"})
+		return
+	}
+	h.purge(c, nodetree.Nodes, uint(nodeID), ownedBy(userID))
+}
+
+// ---------------------- 圈子回收站 (圈主) ----------------------
+
+// ListDomainTrash GET /domains/:domainId/trash
+func (h *TrashHandler) ListDomainTrash(c *gin.Context) {
+	domain := c.MustGet("domain").(model.Domain)
+	items, err := nodetree.ListTrash(h.DB, nodetree.DomainNodes, inDomain(domain.ID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list trash"})
+		return
+	}
+	if items == nil {
+		items = make([]nodetree.TrashItem, 0)
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+// RestoreDomainNode POST /domains/:domainId/trash/:nodeId/restore
+func (h *TrashHandler) RestoreDomainNode(c *gin.Context) {
+	domain := c.MustGet("domain").(model.Domain)
+	nodeID, err := strconv.ParseUint(c.Param("nodeId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid node ID"})
+		return
+	}
+	h.restore(c, nodetree.DomainNodes, uint(nodeID), inDomain(domain.ID))
+}
+
+// PurgeDomainNode DELETE /domains/:domainId/trash/:nodeId
+func (h *TrashHandler) PurgeDomainNode(c *gin.Context) {
+	domain := c.MustGet("domain").(model.Domain)
+	nodeID, err := strconv.ParseUint(c.Param("nodeId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid node ID"})
+		return
+	}
+	h.purge(c, nodetree.DomainNodes, uint(nodeID), inDomain(domain.ID))
+}
+
+// ---------------------- 通用实现 ----------------------
+
+func (h *TrashHandler) restore(c *gin.Context, kind nodetree.Kind, nodeID uint, scope func(*gorm.DB) *gorm.DB) {
+	var restored int64
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		restored, err = nodetree.RestoreSubtree(tx, kind, nodeID, scope)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, nodetree.ErrNotInTrash) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found in trash"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore item"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Restored successfully", "restored": restored})
+}
+
+func (h *TrashHandler) purge(c *gin.Context, kind nodetree.Kind, nodeID uint, scope func(*gorm.DB) *gorm.DB) {
+	var result *nodetree.PurgeResult
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		result, err = nodetree.PurgeSubtree(tx, kind, nodeID, scope)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, nodetree.ErrNotInTrash) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found in trash"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to purge item"})
+		return
+	}
+	nodetree.RemoveObjects(context.Background(), h.Minio, h.Bucket, result.Objects)
+	c.Status(http.StatusNoContent)
+}
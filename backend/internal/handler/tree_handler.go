@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/shuind/language-learner/backend/internal/model"
+	"github.com/shuind/language-learner/backend/internal/nodetree"
+)
+
+// TreeHandler 一次性返回整棵子树与面包屑路径，避免前端逐层请求
+type TreeHandler struct {
+	DB *gorm.DB
+}
+
+func NewTreeHandler(db *gorm.DB) *TreeHandler {
+	return &TreeHandler{DB: db}
+}
+
+// TreeNode 是树形响应中的一个节点
+type TreeNode struct {
+	ID          uint        `json:"id"`
+	ParentID    *uint       `json:"parent_id"`
+	NodeType    string      `json:"node_type"`
+	Title       string      `json:"title"`
+	Content     string      `json:"content,omitempty"`
+	Depth       int         `json:"depth"`
+	HasChildren bool        `json:"has_children"` // 为 true 且 children 为空时，说明受 depth 限制未展开
+	Children    []*TreeNode `json:"children"`
+}
+
+// Breadcrumb 是祖先路径中的一级
+type Breadcrumb struct {
+	ID       uint   `json:"id"`
+	NodeType string `json:"node_type"`
+	Title    string `json:"title"`
+}
+
+// treeRow 用于从两种节点表中读取相同的列
+type treeRow struct {
+	ID       uint
+	ParentID *uint
+	NodeType string
+	Title    string
+	Content  string
+}
+
+// ---------------------- 个人节点 ----------------------
+
+// GetMyTree GET /nodes/tree?root_id=&depth=&include_content=
+func (h *TreeHandler) GetMyTree(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	h.tree(c, nodetree.Nodes, userID)
+}
+
+// GetNodePath GET /nodes/:id/path
+func (h *TreeHandler) GetNodePath(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	var node model.Node
+	if err := h.DB.Select("id").Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&node).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found or permission denied"})
+		return
+	}
+	h.path(c, nodetree.Nodes, node.ID)
+}
+
+// ---------------------- 圈子节点 (成员可见) ----------------------
+
+// GetDomainTree GET /domains/:domainId/tree?root_id=&depth=&include_content=
+func (h *TreeHandler) GetDomainTree(c *gin.Context) {
+	domainID, ok := h.requireMember(c)
+	if !ok {
+		return
+	}
+	h.tree(c, nodetree.DomainNodes, domainID)
+}
+
+// GetDomainNodePath GET /domains/:domainId/nodes/:nodeId/path
+func (h *TreeHandler) GetDomainNodePath(c *gin.Context) {
+	domainID, ok := h.requireMember(c)
+	if !ok {
+		return
+	}
+	var node model.DomainNode
+	if err := h.DB.Select("id").Where("id = ? AND domain_id = ?", c.Param("nodeId"), domainID).First(&node).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found in this domain"})
+		return
+	}
+	h.path(c, nodetree.DomainNodes, node.ID)
+}
+
+func (h *TreeHandler) requireMember(c *gin.Context) (uint, bool) {
+	userID := c.MustGet("userID").(uint)
+	domainID, err := strconv.ParseUint(c.Param("domainId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid domain ID format"})
+		return 0, false
+	}
+	var member model.DomainMember
+	if err := h.DB.Where("domain_id = ? AND user_id = ?", domainID, userID).First(&member).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: you are not a member of this domain"})
+		return 0, false
+	}
+	return uint(domainID), true
+}
+
+// ---------------------- 通用实现 ----------------------
+
+// tree 返回 ownerID 名下的树；指定 root_id 时数组中只有该节点本身
+func (h *TreeHandler) tree(c *gin.Context, kind nodetree.Kind, ownerID uint) {
+	rootID, ok := parseRootID(c)
+	if !ok {
+		return
+	}
+	depth, err := strconv.Atoi(c.DefaultQuery("depth", "0"))
+	if err != nil || depth < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid depth"})
+		return
+	}
+	includeContent := c.Query("include_content") == "true"
+
+	entries, err := nodetree.Subtree(h.DB, kind, ownerID, rootID, depth)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load tree"})
+		return
+	}
+	if rootID != nil && len(entries) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found or permission denied"})
+		return
+	}
+
+	roots, err := buildTree(h.DB, kind, entries, includeContent)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load tree"})
+		return
+	}
+	c.JSON(http.StatusOK, roots)
+}
+
+// buildTree 按 entries 批量读取节点，并按同级默认排序组装成树
+func buildTree(db *gorm.DB, kind nodetree.Kind, entries []nodetree.Entry, includeContent bool) ([]*TreeNode, error) {
+	roots := make([]*TreeNode, 0)
+	if len(entries) == 0 {
+		return roots, nil
+	}
+
+	ids := make([]uint, len(entries))
+	byID := make(map[uint]*TreeNode, len(entries))
+	for i, e := range entries {
+		ids[i] = e.ID
+		byID[e.ID] = &TreeNode{ID: e.ID, Depth: e.Depth, HasChildren: e.HasChildren, Children: make([]*TreeNode, 0)}
+	}
+	minDepth := entries[0].Depth // entries 按深度升序
+
+	columns := "id, parent_id, node_type, title"
+	if includeContent {
+		columns += ", content"
+	}
+	var rows []treeRow
+	if err := db.Table(kind.Table).Select(columns).Where("id IN ?", ids).Order(kind.Order).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	// rows 已按同级排序，依次挂到父节点下即可保持顺序
+	for _, r := range rows {
+		n := byID[r.ID]
+		n.ParentID, n.NodeType, n.Title, n.Content = r.ParentID, r.NodeType, r.Title, r.Content
+		if n.Depth == minDepth {
+			roots = append(roots, n)
+		} else if parent, ok := byID[*r.ParentID]; ok {
+			parent.Children = append(parent.Children, n)
+		}
+	}
+	return roots, nil
+}
+
+// path 返回从根到 nodeID 的面包屑（包含节点自身）
+func (h *TreeHandler) path(c *gin.Context, kind nodetree.Kind, nodeID uint) {
+	ids, err := nodetree.AncestorIDs(h.DB, kind, nodeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load path"})
+		return
+	}
+
+	var rows []treeRow
+	if err := h.DB.Table(kind.Table).Select("id, node_type, title").Where("id IN ?", ids).Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load path"})
+		return
+	}
+	byID := make(map[uint]treeRow, len(rows))
+	for _, r := range rows {
+		byID[r.ID] = r
+	}
+
+	crumbs := make([]Breadcrumb, 0, len(ids))
+	for _, id := range ids {
+		r := byID[id]
+		crumbs = append(crumbs, Breadcrumb{ID: r.ID, NodeType: r.NodeType, Title: r.Title})
+	}
+	c.JSON(http.StatusOK, crumbs)
+}
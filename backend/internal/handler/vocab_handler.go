@@ -0,0 +1,459 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/shuind/language-learner/backend/internal/lang"
+	"github.com/shuind/language-learner/backend/internal/model"
+	"github.com/shuind/language-learner/backend/internal/nodecontent"
+	"github.com/shuind/language-learner/backend/internal/review"
+	"github.com/shuind/language-learner/backend/internal/vocab"
+)
+
+// VocabHandler 处理生词提取、离线查词与个人生词本。
+// 词典加载失败时 Dict 为 nil，依赖词典的接口返回 503
+type VocabHandler struct {
+	DB   *gorm.DB
+	Dict *vocab.Dictionary
+}
+
+func NewVocabHandler(db *gorm.DB) *VocabHandler {
+	dict, err := vocab.Default()
+	if err != nil {
+		log.Printf("ERROR: Failed to load dictionaries, vocabulary endpoints are disabled: %v", err)
+	}
+	return &VocabHandler{DB: db, Dict: dict}
+}
+
+const maxWordBookLimit = 100
+
+// VocabularyItem 是提取结果中的一个词，附带是否已在生词本中
+type VocabularyItem struct {
+	vocab.Candidate
+	WordBookEntryID *uint `json:"word_book_entry_id"`
+}
+
+// VocabularyResponse 是一段内容的生词列表
+type VocabularyResponse struct {
+	Language string           `json:"language"`
+	Words    []VocabularyItem `json:"words"`
+}
+
+type WordSourceInput struct {
+	Kind string `json:"kind" binding:"required,oneof=node domain_node text"`
+	ID   uint   `json:"id" binding:"required"`
+}
+
+type AddWordInput struct {
+	Word       string           `json:"word" binding:"required,max=100"`
+	Language   string           `json:"language" binding:"required"`
+	Reading    *string          `json:"reading" binding:"omitempty,max=255"`
+	Definition *string          `json:"definition"`
+	Note       string           `json:"note"`
+	Surface    string           `json:"surface" binding:"max=100"` // 在原文中点选的形式，用于定位例句
+	Source     *WordSourceInput `json:"source"`
+}
+
+type UpdateWordInput struct {
+	Reading    *string `json:"reading" binding:"omitempty,max=255"`
+	Definition *string `json:"definition"`
+	Note       *string `json:"note"`
+}
+
+// vocabSource 是提取生词的内容
+type vocabSource struct {
+	kind     string
+	id       uint
+	title    string
+	language string
+	text     string
+}
+
+// ---------------------- 提取与查词 ----------------------
+
+// GetNodeVocabulary GET /nodes/:id/vocabulary?language=
+func (h *VocabHandler) GetNodeVocabulary(c *gin.Context) {
+	if src, ok := h.source(c, model.RevisionKindNode, c.Param("id")); ok {
+		h.extract(c, src)
+	}
+}
+
+// GetDomainNodeVocabulary GET /domain-nodes/:id/vocabulary?language=
+func (h *VocabHandler) GetDomainNodeVocabulary(c *gin.Context) {
+	if src, ok := h.source(c, model.RevisionKindDomainNode, c.Param("id")); ok {
+		h.extract(c, src)
+	}
+}
+
+// GetTextVocabulary GET /texts/:id/vocabulary?language=
+func (h *VocabHandler) GetTextVocabulary(c *gin.Context) {
+	if src, ok := h.source(c, model.WordSourceText, c.Param("id")); ok {
+		h.extract(c, src)
+	}
+}
+
+// LookupWord GET /dictionary?word=&language=en|zh
+func (h *VocabHandler) LookupWord(c *gin.Context) {
+	if !h.requireDict(c) {
+		return
+	}
+	word := strings.TrimSpace(c.Query("word"))
+	if word == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "word is required"})
+		return
+	}
+	entry, ok := h.Dict.Lookup(lang.Base(c.DefaultQuery("language", vocab.English)), word)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Word not found in dictionary"})
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+// requireDict 在词典不可用时返回 503
+func (h *VocabHandler) requireDict(c *gin.Context) bool {
+	if h.Dict == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Dictionary is unavailable"})
+		return false
+	}
+	return true
+}
+
+// source 读取并校验当前用户可以访问的内容：个人节点须属于自己，圈子节点须是成员，文本库公开
+func (h *VocabHandler) source(c *gin.Context, kind string, id interface{}) (*vocabSource, bool) {
+	userID := c.MustGet("userID").(uint)
+	switch kind {
+	case model.RevisionKindNode:
+		var node model.Node
+		if err := h.DB.Where("id = ? AND user_id = ?", id, userID).First(&node).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Node not found or permission denied"})
+			return nil, false
+		}
+		return &vocabSource{kind, node.ID, node.Title, node.Language, nodecontent.PlainText(node.NodeType, node.Content)}, true
+	case model.RevisionKindDomainNode:
+		var node model.DomainNode
+		if err := h.DB.First(&node, id).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Domain node not found"})
+			return nil, false
+		}
+		var member model.DomainMember
+		if err := h.DB.Where("domain_id = ? AND user_id = ?", node.DomainID, userID).First(&member).Error; err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: you are not a member of this domain"})
+			return nil, false
+		}
+		return &vocabSource{kind, node.ID, node.Title, node.Language, nodecontent.PlainText(node.NodeType, node.Content)}, true
+	default:
+		var text model.Text
+		if err := h.DB.First(&text, id).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Text not found"})
+			return nil, false
+		}
+		return &vocabSource{kind, text.ID, text.Title, text.Language, text.Content}, true
+	}
+}
+
+func (h *VocabHandler) extract(c *gin.Context, src *vocabSource) {
+	if !h.requireDict(c) {
+		return
+	}
+	userID := c.MustGet("userID").(uint)
+	// 内容未设置语言时，可以通过查询参数指定
+	language := src.language
+	if language == "" {
+		var err error
+		if language, err = lang.Normalize(c.Query("language")); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	candidates, err := h.Dict.Extract(language, src.text)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 标出已经在生词本中的词
+	words := make([]string, len(candidates))
+	for i, cand := range candidates {
+		words[i] = cand.Word
+	}
+	var known []model.WordBookEntry
+	if len(words) > 0 {
+		if err := h.DB.Select("id", "language", "word").
+			Where("user_id = ? AND word IN ?", userID, words).
+			Find(&known).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load word book"})
+			return
+		}
+	}
+	knownIDs := make(map[string]uint, len(known))
+	for _, e := range known {
+		knownIDs[e.Language+":"+e.Word] = e.ID
+	}
+
+	resp := VocabularyResponse{Language: language, Words: make([]VocabularyItem, len(candidates))}
+	for i, cand := range candidates {
+		resp.Words[i] = VocabularyItem{Candidate: cand}
+		if id, ok := knownIDs[cand.Language+":"+cand.Word]; ok {
+			resp.Words[i].WordBookEntryID = &id
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ---------------------- 生词本 ----------------------
+
+// ListWordBook GET /word-book?page=&limit=&language=&q=
+func (h *VocabHandler) ListWordBook(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > maxWordBookLimit {
+		limit = 20
+	}
+
+	query := h.DB.Model(&model.WordBookEntry{}).Where("user_id = ?", userID)
+	if language := c.Query("language"); language != "" {
+		query = query.Where("language = ?", lang.Base(language))
+	}
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		like := "%" + escapeLike(q) + "%"
+		query = query.Where("word ILIKE ? OR definition ILIKE ? OR note ILIKE ?", like, like, like)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list word book"})
+		return
+	}
+	entries := make([]model.WordBookEntry, 0)
+	if err := query.Preload("Sources").Order("created_at DESC").
+		Offset((page - 1) * limit).Limit(limit).Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list word book"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"total": total, "page": page, "entries": entries})
+}
+
+// ListDueWords GET /word-book/due
+func (h *VocabHandler) ListDueWords(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	entries := make([]model.WordBookEntry, 0)
+	if err := h.DB.Preload("Sources").
+		Where("user_id = ? AND due_at <= ?", userID, time.Now()).
+		Order("due_at").Limit(maxWordBookLimit).
+		Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list due words"})
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// AddWord POST /word-book
+// 同一个词重复加入时不会新建词条，只追加新的出处
+func (h *VocabHandler) AddWord(c *gin.Context) {
+	if !h.requireDict(c) {
+		return
+	}
+	userID := c.MustGet("userID").(uint)
+	var input AddWordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	tag, err := lang.Normalize(input.Language)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	language := lang.Base(tag)
+	word := strings.TrimSpace(input.Word)
+	if language == vocab.English {
+		word = h.Dict.Lemma(word)
+	}
+	if strings.TrimSpace(word) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "word must not be blank"})
+		return
+	}
+
+	var source *model.WordBookSource
+	if input.Source != nil {
+		src, ok := h.source(c, input.Source.Kind, input.Source.ID)
+		if !ok {
+			return
+		}
+		surface := strings.TrimSpace(input.Surface)
+		if surface == "" {
+			surface = strings.TrimSpace(input.Word)
+		}
+		source = &model.WordBookSource{
+			SourceKind: src.kind,
+			SourceID:   src.id,
+			Title:      src.title,
+			Surface:    surface,
+			Context:    findContext(src.text, surface, word),
+		}
+	}
+
+	entry := model.WordBookEntry{UserID: userID, Language: language, Word: word}
+	created := false
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND language = ? AND word = ?", userID, language, word).
+			First(&entry).Error; err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			// 新词：释义默认取自词典
+			created = true
+			if dictEntry, ok := h.Dict.Lookup(language, word); ok {
+				entry.Reading = dictEntry.Reading
+				entry.Definition = strings.Join(dictEntry.Definitions, "\n")
+			}
+			if input.Reading != nil {
+				entry.Reading = *input.Reading
+			}
+			if input.Definition != nil {
+				entry.Definition = *input.Definition
+			}
+			entry.Note = input.Note
+			entry.ReviewState = review.NewState(time.Now())
+			if err := tx.Create(&entry).Error; err != nil {
+				return err
+			}
+		}
+		if source != nil {
+			source.EntryID = entry.ID
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(source).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Preload("Sources").First(&entry, entry.ID).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add word"})
+		return
+	}
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, entry)
+}
+
+// UpdateWord PUT /word-book/:id
+func (h *VocabHandler) UpdateWord(c *gin.Context) {
+	entry, ok := h.ownEntry(c)
+	if !ok {
+		return
+	}
+	var input UpdateWordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	updates := map[string]interface{}{}
+	if input.Reading != nil {
+		updates["reading"] = *input.Reading
+	}
+	if input.Definition != nil {
+		updates["definition"] = *input.Definition
+	}
+	if input.Note != nil {
+		updates["note"] = *input.Note
+	}
+	if len(updates) > 0 {
+		if err := h.DB.Model(entry).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update word"})
+			return
+		}
+	}
+	h.DB.Preload("Sources").First(entry, entry.ID)
+	c.JSON(http.StatusOK, entry)
+}
+
+// DeleteWord DELETE /word-book/:id
+func (h *VocabHandler) DeleteWord(c *gin.Context) {
+	entry, ok := h.ownEntry(c)
+	if !ok {
+		return
+	}
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("entry_id = ?", entry.ID).Delete(&model.WordBookSource{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(entry).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete word"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Word deleted"})
+}
+
+// ReviewWord POST /word-book/:id/review
+func (h *VocabHandler) ReviewWord(c *gin.Context) {
+	entry, ok := h.ownEntry(c)
+	if !ok {
+		return
+	}
+	var input ReviewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := review.Apply(&entry.ReviewState, *input.Quality, time.Now()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.DB.Save(entry).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record review"})
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+func (h *VocabHandler) ownEntry(c *gin.Context) (*model.WordBookEntry, bool) {
+	userID := c.MustGet("userID").(uint)
+	var entry model.WordBookEntry
+	if err := h.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&entry).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Word not found"})
+		return nil, false
+	}
+	return &entry, true
+}
+
+// findContext 返回原文中第一个包含该词的句子，先按原文形式查找，再按词条查找
+func findContext(text string, needles ...string) string {
+	segments := lang.Segments(text)
+	for _, needle := range needles {
+		needle = strings.ToLower(needle)
+		if needle == "" {
+			continue
+		}
+		for _, s := range segments {
+			if strings.Contains(strings.ToLower(s.Text), needle) {
+				return s.Text
+			}
+		}
+	}
+	return ""
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike 转义 LIKE 模式中的通配符
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
@@ -0,0 +1,53 @@
+// Package lang 处理节点与文本上的语言标签，以及按句切分内容以便逐句对照翻译、分段练习。
+package lang
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// ErrInvalidTag 表示语言标签不是合法的 BCP 47 标签
+var ErrInvalidTag = errors.New("language must be a valid BCP 47 tag, e.g. en, zh-CN, lzh")
+
+// characterBased 中的语言书写时词与词之间没有空格（或空格不可靠），识别与评分按单字进行
+var characterBased = map[string]bool{
+	"zh":  true,
+	"lzh": true, // 文言文
+	"yue": true,
+	"wuu": true,
+	"ja":  true,
+	"ko":  true,
+}
+
+// Normalize 校验并规范化语言标签，例如 "zh-cn" -> "zh-CN"；空字符串表示未指定
+func Normalize(tag string) (string, error) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		return "", nil
+	}
+	t, err := language.Parse(tag)
+	if err != nil {
+		return "", ErrInvalidTag
+	}
+	return t.String(), nil
+}
+
+// Base 返回主语言子标签，例如 "zh-Hant-TW" -> "zh"；未指定或不合法时返回空字符串
+func Base(tag string) string {
+	t, err := language.Parse(tag)
+	if err != nil {
+		return ""
+	}
+	b, conf := t.Base()
+	if conf == language.No {
+		return ""
+	}
+	return b.String()
+}
+
+// CharacterBased 判断该语言是否按单字切分
+func CharacterBased(tag string) bool {
+	return characterBased[Base(tag)]
+}
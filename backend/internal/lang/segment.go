@@ -0,0 +1,64 @@
+package lang
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Segment 是内容中的一句，Start / End 为按 rune 计的半开区间 [Start, End)
+type Segment struct {
+	Index int    `json:"index"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Text  string `json:"text"`
+}
+
+// sentenceEnd 中的标点总是结束一句
+const sentenceEnd = "。！？!?；;…\n"
+
+// closing 中的字符紧跟在句末标点之后时仍属于这一句
+const closing = "\"'”’」』）)]》"
+
+// Segments 按句切分文本，句首句尾的空白不计入句子。
+// 英文句点只有后面是空白、引号或文本结尾时才视为句末，避免切开小数中的点。
+func Segments(text string) []Segment {
+	runes := []rune(text)
+	segments := make([]Segment, 0)
+	start := 0
+	emit := func(end int) {
+		s, e := start, end
+		for s < e && unicode.IsSpace(runes[s]) {
+			s++
+		}
+		for e > s && unicode.IsSpace(runes[e-1]) {
+			e--
+		}
+		if s < e {
+			segments = append(segments, Segment{Index: len(segments), Start: s, End: e, Text: string(runes[s:e])})
+		}
+		start = end
+	}
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		end := strings.ContainsRune(sentenceEnd, r) ||
+			(r == '.' && (i+1 == len(runes) || unicode.IsSpace(runes[i+1]) || strings.ContainsRune(closing, runes[i+1])))
+		if !end {
+			continue
+		}
+		// 连续的句末标点和收尾引号、括号并入同一句
+		for i+1 < len(runes) && continuesSentence(runes[i+1]) {
+			i++
+		}
+		emit(i + 1)
+	}
+	emit(len(runes))
+	return segments
+}
+
+func continuesSentence(r rune) bool {
+	if r == '\n' {
+		return false
+	}
+	return r == '.' || strings.ContainsRune(sentenceEnd, r) || strings.ContainsRune(closing, r)
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/shuind/language-learner/backend/internal/model"
+)
+
+// DomainRoleMiddleware 要求当前用户是路由中圈子（:domainId 或 :id）的成员，且角色属于 roles；
+// roles 为空时任何成员都可以访问。通过后把圈子和成员记录分别存入 "domain" 与 "domainMember"。
+func DomainRoleMiddleware(db *gorm.DB, roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("userID").(uint)
+		domainIDStr := c.Param("domainId")
+		if domainIDStr == "" {
+			domainIDStr = c.Param("id")
+		}
+		domainID, err := strconv.ParseUint(domainIDStr, 10, 32)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid domain ID"})
+			return
+		}
+
+		var domain model.Domain
+		if err := db.First(&domain, domainID).Error; err != nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Domain not found"})
+			return
+		}
+		var member model.DomainMember
+		if err := db.Where("domain_id = ? AND user_id = ?", domain.ID, userID).First(&member).Error; err != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Access denied: you are not a member of this domain"})
+			return
+		}
+		if len(roles) > 0 && !slices.Contains(roles, member.Role) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Permission denied: your role in this domain does not allow this action"})
+			return
+		}
+
+		c.Set("domain", domain)
+		c.Set("domainMember", member)
+		c.Next()
+	}
+}
@@ -0,0 +1,16 @@
+package middleware
+
+import "github.com/gin-gonic/gin"
+
+// QueryTokenMiddleware 把 access_token 查询参数转换为 Authorization 请求头。
+// 浏览器建立 WebSocket 连接时无法自定义请求头，需放在 AuthMiddleware 之前使用。
+func QueryTokenMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			if token := c.Query("access_token"); token != "" {
+				c.Request.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+		c.Next()
+	}
+}
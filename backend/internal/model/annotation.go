@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// 标注的可见范围
+const (
+	AnnotationPrivate = "private" // 仅自己可见
+	AnnotationDomain  = "domain"  // 圈子成员可见，仅用于圈子节点
+)
+
+// Annotation 是节点内容中一段文字上的标注（高亮 + 笔记）
+// 锚点是某个修订版本内容中的字符区间 [Start, End)，按 rune 计；
+// 节点内容每次产生新修订时，锚点会随之重新定位到新修订上。
+type Annotation struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID uint `gorm:"not null;index" json:"user_id"`
+	// NodeKind + NodeID 共同定位到一个 Node 或 DomainNode，取值同 NodeRevision
+	NodeKind string `gorm:"type:varchar(20);not null;index:idx_annotation_node" json:"node_kind"`
+	NodeID   uint   `gorm:"not null;index:idx_annotation_node" json:"node_id"`
+	// DomainID 仅圈子节点上的标注有值，用于按圈子校验权限
+	DomainID *uint `gorm:"index" json:"domain_id,omitempty"`
+
+	// RevisionID 是锚点当前所对应的修订
+	RevisionID uint `gorm:"not null" json:"revision_id"`
+	Start      int  `gorm:"column:start_offset;not null" json:"start"`
+	End        int  `gorm:"column:end_offset;not null" json:"end"`
+	// Quote 是区间内的原文，用于展示以及锚点失效后重新查找
+	Quote string `gorm:"type:text;not null" json:"quote"`
+	// Orphaned 为 true 表示标注的文字已被删除，锚点不再有效
+	Orphaned bool `gorm:"not null;default:false" json:"orphaned"`
+
+	Note       string `gorm:"type:text" json:"note"`
+	Color      string `gorm:"type:varchar(20);not null;default:'#ffd54f'" json:"color"`
+	Visibility string `gorm:"type:varchar(10);not null;default:'private'" json:"visibility"`
+
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 成员在作业中的完成状态，由录音实时推导，不落库
+const (
+	AssignmentStatusPending = "pending" // 未完成，尚未截止
+	AssignmentStatusDone    = "done"    // 截止前完成
+	AssignmentStatusLate    = "late"    // 截止后才完成
+	AssignmentStatusMissing = "missing" // 已截止仍未完成
+)
+
+// Assignment 是圈主或管理员布置的朗读作业：要求成员在截止时间前为指定的圈子节点录音。
+// 布置之后的录音才计入完成；设置了 MinAccuracy 时，准确率达到要求的录音才算完成。
+type Assignment struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	DomainID    uint      `gorm:"not null;index" json:"domain_id"`
+	CreatedBy   uint      `gorm:"not null" json:"created_by"`
+	Title       string    `gorm:"type:varchar(255);not null" json:"title"`
+	Description string    `gorm:"type:text" json:"description"`
+	DueAt       time.Time `gorm:"not null;index" json:"due_at"`
+	// MinAccuracy 是计为完成所需的最低准确率（0-1），为空表示任意录音都算完成
+	MinAccuracy *float64 `json:"min_accuracy"`
+	// AllMembers 为 true 时面向布置者以外的全体成员（包括之后加入的），否则只面向 Assignees
+	AllMembers bool `gorm:"not null;default:false" json:"all_members"`
+
+	Nodes     []DomainNode         `gorm:"many2many:assignment_nodes" json:"nodes,omitempty"`
+	Assignees []AssignmentAssignee `gorm:"foreignKey:AssignmentID" json:"-"`
+}
+
+// AssignmentAssignee 是只面向部分成员的作业的指定成员
+type AssignmentAssignee struct {
+	AssignmentID uint `gorm:"primaryKey" json:"assignment_id"`
+	UserID       uint `gorm:"primaryKey;index" json:"user_id"`
+}
@@ -6,6 +6,20 @@ import (
 	"gorm.io/gorm"
 )
 
+// 录音评论的反馈类别
+const (
+	CommentCategoryPronunciation = "pronunciation" // 发音
+	CommentCategoryOmission      = "omission"      // 漏读
+	CommentCategoryFluency       = "fluency"       // 流利度
+)
+
+// 语音反馈音频的处理状态
+const (
+	CommentAudioProcessing = "processing"
+	CommentAudioCompleted  = "completed"
+	CommentAudioFailed     = "failed"
+)
+
 type Comment struct {
 	// gorm.Model 的字段通常也需要 json 标签
 	ID        uint           `json:"id" gorm:"primarykey"`
@@ -17,5 +31,17 @@ type Comment struct {
 	UserID      uint   `json:"user_id" gorm:"not null"`
 	Content     string `json:"content" gorm:"type:text;not null"`
 
+	// StartMs/EndMs 把评论定位到录音中的一段（毫秒），都为空表示针对整条录音；只给 StartMs 表示一个时间点
+	StartMs  *int   `json:"start_ms" gorm:"index"`
+	EndMs    *int   `json:"end_ms"`
+	Category string `json:"category" gorm:"type:varchar(20);not null;default:''"` // 取值见 CommentCategory*，可为空
+
+	// 语音反馈：圈主或管理员录制的讲解音频，Content 此时是可选的文字说明。
+	// 音频由 worker 上传，完成前 AudioURL 为空，AudioStatus 为 processing
+	AudioURL        string `json:"audio_url,omitempty" gorm:"type:varchar(512)"`
+	AudioObjectName string `json:"-" gorm:"type:varchar(255)"`
+	AudioDurationMs int    `json:"audio_duration_ms,omitempty" gorm:"not null;default:0"`
+	AudioStatus     string `json:"audio_status,omitempty" gorm:"type:varchar(20);not null;default:''"` // 取值见 CommentAudio*，文字评论为空
+
 	User User `json:"user" gorm:"foreignKey:UserID"` // 方便预加载作者信息
 }
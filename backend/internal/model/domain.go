@@ -18,6 +18,12 @@ type Domain struct {
 	Name        string `gorm:"type:varchar(100);not null" json:"name"`
 	Description string `gorm:"type:text" json:"description"`
 	JoinCode    string `gorm:"type:varchar(8);not null;unique" json:"join_code"`
+	// RequireApproval 为 true 时，通过加入码或公开邀请加入需要圈主或管理员审核
+	RequireApproval bool `gorm:"not null;default:false" json:"require_approval"`
+	// TermStartsAt 是当前学期的开始时间，排行榜的 term 窗口从此刻算起；为空时从圈子创建算起
+	TermStartsAt *time.Time `json:"term_starts_at"`
+	// AllowFork 为 true 时普通成员可以把圈子内容保存到自己的个人空间，圈主和管理员不受限制
+	AllowFork bool `gorm:"not null;default:false" json:"allow_fork"`
 }
 
 // (可选但推荐) 自定义表名
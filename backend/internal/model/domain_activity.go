@@ -0,0 +1,50 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 圈子动态的类型
+const (
+	ActivityNodePublished     = "node_published"     // 新内容发布或创建
+	ActivityNodeUpdated       = "node_updated"       // 内容被编辑或同步更新
+	ActivityAssignmentCreated = "assignment_created" // 布置了新作业
+	ActivityRecordingFeatured = "recording_featured" // 录音被设为精选
+	ActivityMemberJoined      = "member_joined"      // 新成员加入
+	ActivityAnnouncement      = "announcement"       // 发布了公告
+)
+
+// DomainActivity 是圈子动态流中的一条记录，按 ID 倒序分页。
+// SubjectID 指向对应类型的对象（节点、作业、录音、成员的用户 ID 或公告），
+// Title 是记录时对象的标题，对象之后被修改或删除时动态仍可展示。
+type DomainActivity struct {
+	ID        uint      `gorm:"primarykey;index:idx_domain_activity,priority:2" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	DomainID  uint   `gorm:"not null;index:idx_domain_activity,priority:1" json:"domain_id"`
+	ActorID   uint   `gorm:"not null" json:"actor_id"`
+	Type      string `gorm:"type:varchar(30);not null;index" json:"type"`
+	SubjectID uint   `gorm:"not null" json:"subject_id"`
+	Title     string `gorm:"type:varchar(255);not null;default:''" json:"title"`
+
+	Actor User `gorm:"foreignKey:ActorID" json:"-"`
+}
+
+// DomainAnnouncement 是圈主或管理员发布的公告，置顶的公告排在最前
+type DomainAnnouncement struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	DomainID uint       `gorm:"not null;index" json:"domain_id"`
+	AuthorID uint       `gorm:"not null" json:"author_id"`
+	Title    string     `gorm:"type:varchar(255);not null" json:"title"`
+	Content  string     `gorm:"type:text;not null" json:"content"`
+	Pinned   bool       `gorm:"not null;default:false" json:"pinned"`
+	PinnedAt *time.Time `json:"pinned_at"`
+
+	Author User `gorm:"foreignKey:AuthorID" json:"-"`
+}
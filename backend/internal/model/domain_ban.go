@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// DomainBan 是被移出圈子并禁止再次加入的用户，解除后才能重新用邀请码加入
+type DomainBan struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	DomainID uint   `gorm:"not null;uniqueIndex:idx_domain_ban" json:"domain_id"`
+	UserID   uint   `gorm:"not null;uniqueIndex:idx_domain_ban" json:"user_id"`
+	BannedBy uint   `gorm:"not null" json:"banned_by"`
+	Reason   string `gorm:"type:varchar(500)" json:"reason"`
+
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
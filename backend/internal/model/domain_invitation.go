@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// DomainInvitation 是圈子的邀请链接，可以设置有效期、使用次数上限和指定受邀用户，并可随时撤销
+type DomainInvitation struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	DomainID  uint   `gorm:"not null;index" json:"domain_id"`
+	CreatedBy uint   `gorm:"not null" json:"created_by"`
+	Token     string `gorm:"type:varchar(64);not null;uniqueIndex" json:"token"`
+
+	ExpiresAt *time.Time `json:"expires_at"` // 为空表示永不过期
+	MaxUses   *int       `json:"max_uses"`   // 为空表示不限次数
+	UsesCount int        `gorm:"not null;default:0" json:"uses_count"`
+	// TargetUserID 不为空时只有该用户可以使用，且无需审核
+	TargetUserID *uint      `gorm:"index" json:"target_user_id"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+}
+
+// 加入申请的状态
+const (
+	JoinRequestPending  = "pending"
+	JoinRequestApproved = "approved"
+	JoinRequestRejected = "rejected"
+)
+
+// DomainJoinRequest 是开启审核的圈子中等待圈主或管理员处理的加入申请
+type DomainJoinRequest struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	DomainID     uint   `gorm:"not null;index" json:"domain_id"`
+	UserID       uint   `gorm:"not null;index" json:"user_id"`
+	InvitationID *uint  `json:"invitation_id"` // 通过邀请链接申请时的来源
+	Message      string `gorm:"type:varchar(500)" json:"message"`
+
+	Status     string     `gorm:"type:varchar(20);not null;default:'pending';index" json:"status"`
+	ReviewedBy *uint      `json:"reviewed_by"`
+	ReviewedAt *time.Time `json:"reviewed_at"`
+
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
@@ -4,6 +4,13 @@ import (
 	"time"
 )
 
+// 圈子成员的角色：圈主只有一个，与 Domain.OwnerID 一致；管理员可以编辑内容、管理普通成员
+const (
+	DomainRoleOwner  = "owner"
+	DomainRoleAdmin  = "admin"
+	DomainRoleMember = "member"
+)
+
 type DomainMember struct {
 	// 我们不使用 gorm.Model，因为我们想自定义主键和字段
 	ID       uint      `gorm:"primaryKey"`
@@ -11,6 +18,8 @@ type DomainMember struct {
 	UserID   uint      `gorm:"not null;uniqueIndex:idx_domain_user"` // 复合唯一索引
 	Role     string    `gorm:"type:varchar(20);not null;default:'member'"`
 	JoinedAt time.Time `gorm:"default:now()"`
+	// LeaderboardOptIn 为 true 时成员出现在圈子排行榜中，默认不参与
+	LeaderboardOptIn bool `gorm:"not null;default:false"`
 
 	// 定义关联关系
 	Domain Domain `gorm:"foreignKey:DomainID"`
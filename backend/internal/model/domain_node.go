@@ -17,11 +17,22 @@ type DomainNode struct {
 	NodeType string `gorm:"column:node_type;type:varchar(10);not null" json:"node_type"`
 	Title    string `gorm:"column:title;type:varchar(255);not null" json:"title"`
 	Content  string `gorm:"column:content;type:text" json:"content"`
+	// Language 是内容的 BCP 47 语言标签（如 en、zh-CN、lzh），为空表示未指定
+	Language string `gorm:"column:language;type:varchar(35);not null;default:''" json:"language"`
+	// Position 是同级节点中的手动排序位置，数值越小越靠前，使用浮点数以便在两个节点之间插入
+	Position float64 `gorm:"column:position;not null;default:0" json:"position"`
+	// Version 在标题或内容每次修改后加一，用作 ETag 实现乐观并发控制
+	Version int `gorm:"column:version;not null;default:1" json:"version"`
 
 	CommentsCount int `gorm:"not null;default:0" json:"comments_count"`
 
+	// Tags 通过 domain_node_tags 中间表关联，Preload("Tags") 时才会返回
+	Tags []DomainTag `gorm:"many2many:domain_node_tags" json:"tags,omitempty"`
+
 	// 3. 明确定义时间戳和软删除字段
 	CreatedAt time.Time      `gorm:"column:created_at" json:"created_at"`
 	UpdatedAt time.Time      `gorm:"column:updated_at" json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"column:deleted_at;index" json:"-"`
+	// DeletedRootID 记录节点随哪一次删除（以被删除的根节点标识）进入回收站
+	DeletedRootID *uint `gorm:"column:deleted_root_id;index" json:"-"`
 }
@@ -13,8 +13,12 @@ type DomainNodeComment struct {
 	DomainNodeID uint   `gorm:"not null;index" json:"domain_node_id"`
 	UserID       uint   `gorm:"not null;index" json:"user_id"`
 	Content      string `gorm:"type:text;not null" json:"content"`
+	// AnnotationID 不为空时，评论针对的是节点中被标注的一段文字
+	AnnotationID *uint `gorm:"index" json:"annotation_id"`
 
 	// --- GORM 关联关系 ---
 	// `json:"user"`: 指定关联对象在 JSON 中的字段名
 	User User `gorm:"foreignKey:UserID" json:"user"`
+	// Preload("Annotation") 时返回所指向的文字区间
+	Annotation *Annotation `gorm:"foreignKey:AnnotationID" json:"annotation,omitempty"`
 }
@@ -9,15 +9,26 @@ type Node struct {
 	CreatedAt int64          `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt int64          `gorm:"autoUpdateTime" json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"` // 使用 json:"-" 在API中隐藏此字段
+	// DeletedRootID 记录节点随哪一次删除（以被删除的根节点标识）进入回收站
+	DeletedRootID *uint `gorm:"index" json:"-"`
 
 	// 自定义字段，并添加 gorm 和 json 标签
 	UserID   uint   `gorm:"not null;index" json:"user_id"`
 	ParentID *uint  `gorm:"index" json:"parent_id"`                     // 使用指针以允许 NULL 值
-	NodeType string `gorm:"type:varchar(10);not null" json:"node_type"` // 'folder' 或 'text'
+	NodeType string `gorm:"type:varchar(10);not null" json:"node_type"` // 'folder'、'text'、'card' 或 'qa'，见 nodecontent 包
 	Title    string `gorm:"type:varchar(255);not null" json:"title"`
 	Content  string `gorm:"type:text" json:"content"`
+	// Language 是内容的 BCP 47 语言标签（如 en、zh-CN、lzh），为空表示未指定
+	Language string `gorm:"type:varchar(35);not null;default:''" json:"language"`
+	// Position 是同级节点中的手动排序位置，数值越小越靠前，使用浮点数以便在两个节点之间插入
+	Position float64 `gorm:"not null;default:0" json:"position"`
+	// Version 在标题或内容每次修改后加一，用作 ETag 实现乐观并发控制
+	Version int `gorm:"not null;default:1" json:"version"`
 
 	// 关联关系仅用于 GORM，不需要 JSON 标签，它们不会被序列化
 	Parent   *Node  `gorm:"foreignKey:ParentID;references:ID"`
 	Children []Node `gorm:"foreignKey:ParentID;references:ID"`
+
+	// Tags 通过 node_tags 中间表关联，Preload("Tags") 时才会返回
+	Tags []Tag `gorm:"many2many:node_tags" json:"tags,omitempty"`
 }
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// NodeFork 记录成员把圈子内容保存到个人空间的来源，用于在副本上显示出处。
+// 圈子名称与标题在保存时留存一份，成员退出或圈子内容被删除后仍能显示。
+type NodeFork struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	UserID       uint   `gorm:"not null;index" json:"user_id"`
+	NodeID       uint   `gorm:"not null;uniqueIndex" json:"node_id"` // 个人空间中的副本根节点
+	DomainID     uint   `gorm:"not null;index" json:"domain_id"`
+	DomainNodeID uint   `gorm:"not null;index" json:"domain_node_id"` // 被保存的圈子节点
+	DomainName   string `gorm:"type:varchar(100);not null" json:"domain_name"`
+	SourceTitle  string `gorm:"type:varchar(255);not null" json:"source_title"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// ReviewState 是间隔重复（SM-2）的调度状态，嵌入到各类可复习的对象中
+type ReviewState struct {
+	EaseFactor     float64    `gorm:"not null;default:2.5" json:"ease_factor"`
+	IntervalDays   int        `gorm:"not null;default:0" json:"interval_days"`
+	Repetitions    int        `gorm:"not null;default:0" json:"repetitions"`
+	DueAt          time.Time  `gorm:"index" json:"due_at"`
+	LastReviewedAt *time.Time `json:"last_reviewed_at"`
+}
+
+// NodeReview 记录用户对某个个人节点的复习进度
+type NodeReview struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_node_review_user_node" json:"user_id"`
+	NodeID    uint      `gorm:"not null;uniqueIndex:idx_node_review_user_node" json:"node_id"`
+
+	ReviewState `gorm:"embedded"`
+}
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// 修订所属的节点类型
+const (
+	RevisionKindNode       = "node"        // 个人节点 (nodes 表)
+	RevisionKindDomainNode = "domain_node" // 圈子节点 (domain_nodes 表)
+)
+
+// NodeRevision 记录节点标题/内容的每一次变更
+// 修订只追加、不修改；恢复旧版本也会生成一条新的修订
+type NodeRevision struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// NodeKind + NodeID 共同定位到一个 Node 或 DomainNode
+	NodeKind string `gorm:"type:varchar(20);not null;uniqueIndex:idx_revision_node_version" json:"node_kind"`
+	NodeID   uint   `gorm:"not null;uniqueIndex:idx_revision_node_version" json:"node_id"`
+	// Version 在同一节点内从 1 开始递增
+	Version int `gorm:"not null;uniqueIndex:idx_revision_node_version" json:"version"`
+
+	AuthorID uint   `gorm:"not null;index" json:"author_id"`
+	Title    string `gorm:"type:varchar(255);not null" json:"title"`
+	Content  string `gorm:"type:text" json:"content"`
+
+	// Diff 是相对于上一版本内容的 unified diff，首个版本为空
+	Diff         string `gorm:"type:text" json:"diff"`
+	LinesAdded   int    `gorm:"not null;default:0" json:"lines_added"`
+	LinesRemoved int    `gorm:"not null;default:0" json:"lines_removed"`
+	// RestoredFrom 不为空时，表示这次修订是从某个旧版本恢复而来
+	RestoredFrom *uint `json:"restored_from,omitempty"`
+
+	Author User `gorm:"foreignKey:AuthorID" json:"-"`
+}
@@ -0,0 +1,80 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Rubric 是圈子内的评分量表，由若干评分项组成
+type Rubric struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	DomainID    uint   `gorm:"not null;index" json:"domain_id"`
+	CreatedBy   uint   `gorm:"not null" json:"created_by"`
+	Name        string `gorm:"type:varchar(100);not null" json:"name"`
+	Description string `gorm:"type:text" json:"description"`
+
+	Criteria []RubricCriterion `gorm:"foreignKey:RubricID" json:"criteria,omitempty"`
+}
+
+// RubricCriterion 是量表中的一个评分项，得分为 0 到 MaxPoints 的整数
+type RubricCriterion struct {
+	ID          uint   `gorm:"primarykey" json:"id"`
+	RubricID    uint   `gorm:"not null;index" json:"rubric_id"`
+	Name        string `gorm:"type:varchar(100);not null" json:"name"`
+	Description string `gorm:"type:text" json:"description"`
+	MaxPoints   int    `gorm:"not null" json:"max_points"`
+	Position    int    `gorm:"not null;default:0" json:"position"`
+}
+
+// 互评轮次的状态
+const (
+	PeerReviewOpen   = "open"
+	PeerReviewClosed = "closed"
+)
+
+// PeerReviewRound 是一轮互评：创建时为每位作者选出其在每个指定节点上的最新录音，
+// 再给每位普通成员分配 ReviewsPerMember 条他人的录音
+type PeerReviewRound struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	DomainID         uint       `gorm:"not null;index" json:"domain_id"`
+	RubricID         uint       `gorm:"not null" json:"rubric_id"`
+	CreatedBy        uint       `gorm:"not null" json:"created_by"`
+	Title            string     `gorm:"type:varchar(255);not null" json:"title"`
+	ReviewsPerMember int        `gorm:"not null" json:"reviews_per_member"`
+	Anonymous        bool       `gorm:"not null;default:false" json:"anonymous"` // 评审者看不到作者，作者也看不到评审者
+	DueAt            *time.Time `json:"due_at"`
+	Status           string     `gorm:"type:varchar(20);not null;default:'open'" json:"status"`
+
+	Nodes []DomainNode `gorm:"many2many:peer_review_round_nodes" json:"nodes,omitempty"`
+}
+
+// PeerReviewAssignment 是分配给某位评审者的一条录音及其评审结果
+type PeerReviewAssignment struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	RoundID     uint       `gorm:"not null;uniqueIndex:idx_peer_review_assignment" json:"round_id"`
+	ReviewerID  uint       `gorm:"not null;uniqueIndex:idx_peer_review_assignment;index" json:"reviewer_id"`
+	RecordingID uint       `gorm:"not null;uniqueIndex:idx_peer_review_assignment;index" json:"recording_id"`
+	Comment     string     `gorm:"type:text" json:"comment"`
+	SubmittedAt *time.Time `json:"submitted_at"`
+
+	Scores []PeerReviewScore `gorm:"foreignKey:AssignmentID" json:"scores,omitempty"`
+}
+
+// PeerReviewScore 是一次评审在某个评分项上的得分
+type PeerReviewScore struct {
+	ID           uint   `gorm:"primarykey" json:"id"`
+	AssignmentID uint   `gorm:"not null;uniqueIndex:idx_peer_review_score" json:"assignment_id"`
+	CriterionID  uint   `gorm:"not null;uniqueIndex:idx_peer_review_score" json:"criterion_id"`
+	Points       int    `gorm:"not null" json:"points"`
+	Comment      string `gorm:"type:text" json:"comment"`
+}
@@ -19,6 +19,8 @@ type Post struct {
 	UserID  uint   `json:"user_id"`
 	Title   string `json:"title" gorm:"type:varchar(255)"`
 	Content string `json:"content" gorm:"type:text;not null"`
+	// Version 在每次编辑后加一，用作 ETag 实现乐观并发控制
+	Version int `json:"version" gorm:"not null;default:1"`
 
 	// --- 新增: 为 PostCard 提供内容摘要 ---
 	Excerpt string `json:"excerpt" gorm:"type:text"` // 对应 PostCard 的摘要显示, 建议在创建帖子时自动生成并存储
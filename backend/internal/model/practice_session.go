@@ -0,0 +1,59 @@
+package model
+
+import "time"
+
+// 练习模式
+const (
+	PracticeModeShadowing = "shadowing" // 跟读：逐句听示范、录一遍、对比
+)
+
+// 练习会话的状态
+const (
+	PracticeSessionActive   = "active"
+	PracticeSessionFinished = "finished"
+)
+
+// PracticeSession 是一次针对节点的逐句练习，各句的录音作为子记录保存在 PracticeRecording 中，
+// 不会产生 Recording。
+type PracticeSession struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID uint `gorm:"not null;index" json:"user_id"`
+	// NodeKind + NodeID 共同定位到一个 Node 或 DomainNode，取值同 NodeRevision
+	NodeKind string `gorm:"type:varchar(20);not null;index:idx_practice_session_node" json:"node_kind"`
+	NodeID   uint   `gorm:"not null;index:idx_practice_session_node" json:"node_id"`
+	// RevisionID 是开始练习时的内容版本，句子按此版本切分，节点之后被编辑也不受影响
+	RevisionID uint `gorm:"not null" json:"revision_id"`
+
+	Mode         string `gorm:"type:varchar(20);not null;default:'shadowing'" json:"mode"`
+	Language     string `gorm:"type:varchar(35);not null;default:''" json:"language"`
+	Voice        string `gorm:"type:varchar(64);not null;default:''" json:"voice"` // 使用 TTS 示范时的音色
+	SegmentCount int    `gorm:"not null" json:"segment_count"`
+
+	Status     string     `gorm:"type:varchar(20);not null;default:'active'" json:"status"`
+	FinishedAt *time.Time `json:"finished_at"`
+}
+
+// PracticeRecording 是练习会话中某一句的一次录音及其与示范音频的对比结果
+type PracticeRecording struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	SessionID    uint `gorm:"not null;index" json:"session_id"`
+	SegmentIndex int  `gorm:"not null" json:"segment_index"`
+
+	AudioURL   string `gorm:"type:varchar(512)" json:"audio_url"`
+	ObjectName string `gorm:"type:varchar(255)" json:"-"`
+	DurationMs int    `gorm:"not null" json:"duration_ms"`
+
+	// ReferenceAudioID 是对比时使用的示范音频，没有示范音频时为空
+	ReferenceAudioID *uint  `json:"reference_audio_id"`
+	AiStatus         string `gorm:"type:varchar(20);not null" json:"ai_status"` // completed / failed
+	RecognizedText   string `gorm:"type:text" json:"recognized_text"`
+	// Accuracy 为识别失败时为空
+	Accuracy *float64 `json:"accuracy"`
+	// Alignment 是 JSON 编码的 scoring.Alignment
+	Alignment string `gorm:"type:text" json:"-"`
+}
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// Publication 记录一次从个人空间到圈子的发布：源子树与圈子中的副本保持关联，
+// 源节点修改后可以预览差异并再次同步到副本。
+type Publication struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID       uint `gorm:"not null;index" json:"user_id"`        // 发布者，即源节点的所有者
+	SourceNodeID uint `gorm:"not null;index" json:"source_node_id"` // 个人空间中的源子树根节点
+	DomainID     uint `gorm:"not null;index" json:"domain_id"`
+	// TargetParentID 是副本所在的圈子文件夹，为空表示圈子根目录
+	TargetParentID *uint     `json:"target_parent_id"`
+	CopyRootID     uint      `gorm:"not null;index" json:"copy_root_id"`
+	LastSyncedAt   time.Time `json:"last_synced_at"`
+
+	Domain Domain `gorm:"foreignKey:DomainID" json:"-"`
+}
+
+// PublicationNode 是发布中一个源节点与其圈子副本的对应关系
+type PublicationNode struct {
+	ID            uint `gorm:"primarykey" json:"id"`
+	PublicationID uint `gorm:"not null;uniqueIndex:idx_publication_source" json:"publication_id"`
+	SourceNodeID  uint `gorm:"not null;uniqueIndex:idx_publication_source" json:"source_node_id"`
+	DomainNodeID  uint `gorm:"not null;index" json:"domain_node_id"`
+	// SyncedHash 是上次同步时源节点内容的摘要，用于判断源节点是否又被修改
+	SyncedHash string `gorm:"type:varchar(64);not null" json:"-"`
+	// SyncedVersion 是上次同步后副本的版本号，副本版本不同说明圈子里有人改过副本
+	SyncedVersion int `gorm:"not null" json:"-"`
+	// SourceRemovedAt 是同步时发现源节点已被删除（或移出发布的子树）的时间，副本保留待圈主处理
+	SourceRemovedAt *time.Time `json:"source_removed_at"`
+}
@@ -18,11 +18,17 @@ type Recording struct {
 	TextID         *uint  `gorm:"index" json:"text_id"`
 	NodeID         *uint  `gorm:"index" json:"node_id"`
 	DomainNodeID   *uint  `gorm:"index" json:"domain_node_id"`
+	RevisionID     *uint  `gorm:"index" json:"revision_id"`   // 录音时朗读的内容版本，节点被编辑后评分仍以此为准
+	ShareLinkID    *uint  `gorm:"index" json:"share_link_id"` // 访问者通过分享链接为他人节点录音时的来源链接
 	Title          string `gorm:"type:varchar(255)" json:"title"`
 	Status         string `gorm:"type:varchar(20);default:'processing'" json:"status"`
 	AudioURL       string `gorm:"type:varchar(512)" json:"audio_url"`
 	AiStatus       string `gorm:"type:varchar(20);default:'pending'" json:"ai_status"`
 	RecognizedText string `gorm:"type:text" json:"recognized_text"`
+	// Accuracy 是识别结果与朗读内容对比得出的准确率（0-1），识别完成前为空
+	Accuracy *float64 `json:"accuracy"`
+	// DurationMs 是客户端上报的录音时长（按文件大小与上限截断），用于统计练习时间；未上报时为 0
+	DurationMs int `gorm:"not null;default:0" json:"duration_ms"`
 
 	// Preload("User") 会将查询到的 User 信息填充到这个字段
 	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
@@ -30,6 +36,9 @@ type Recording struct {
 	LikesCount       int  `gorm:"default:0" json:"likes_count"`
 	CommentsCount    int  `gorm:"default:0" json:"comments_count"`
 	IsDomainFeatured bool `gorm:"default:false" json:"is_domain_featured"`
+	// PeerReviewScore 是已提交互评的平均得分率（0-1），没有互评时为空
+	PeerReviewScore *float64 `json:"peer_review_score"`
+	PeerReviewCount int      `gorm:"default:0" json:"peer_review_count"`
 
 	// Preload("DomainNode") 会将查询到的 DomainNode 信息填充到这个字段
 	DomainNode DomainNode `gorm:"foreignKey:DomainNodeID" json:"domain_node,omitempty"`
@@ -0,0 +1,58 @@
+package model
+
+import "time"
+
+// 示范音频的生成状态
+const (
+	ReferenceAudioPending    = "pending"
+	ReferenceAudioProcessing = "processing"
+	ReferenceAudioCompleted  = "completed"
+	ReferenceAudioFailed     = "failed"
+)
+
+// 示范音频的来源
+const (
+	ReferenceAudioSourceTTS    = "tts"    // 由 TTS 合成
+	ReferenceAudioSourceUpload = "upload" // 节点所有者上传的录音
+)
+
+// ReferenceAudioWholeNode 作为 SegmentIndex 时表示整个节点的朗读，而不是其中一句
+const ReferenceAudioWholeNode = -1
+
+// ReferenceAudio 是节点的朗读示范，可以针对整个节点或 lang.Segments 切分出的某一句，
+// 由 TTS 合成或由节点所有者上传。合成的音频按 ContentHash 缓存在对象存储中，
+// 文字、语言、音色都相同的请求共用同一个文件；节点内容修改后，与新内容不再对应的记录会被删除。
+type ReferenceAudio struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// NodeKind + NodeID 共同定位到一个 Node 或 DomainNode，取值同 NodeRevision
+	NodeKind     string `gorm:"type:varchar(20);not null;uniqueIndex:idx_reference_audio" json:"node_kind"`
+	NodeID       uint   `gorm:"not null;uniqueIndex:idx_reference_audio" json:"node_id"`
+	SegmentIndex int    `gorm:"not null;default:-1;uniqueIndex:idx_reference_audio" json:"segment_index"`
+	Source       string `gorm:"type:varchar(10);not null;default:'tts';uniqueIndex:idx_reference_audio" json:"source"`
+	// ContentHash 是 tts.CacheKey(Text, Language, Voice)
+	ContentHash string `gorm:"type:char(64);not null;uniqueIndex:idx_reference_audio;index" json:"content_hash"`
+
+	Text     string `gorm:"type:text;not null" json:"text"` // 被朗读的文字
+	Language string `gorm:"type:varchar(35);not null;default:''" json:"language"`
+	Voice    string `gorm:"type:varchar(64);not null;default:''" json:"voice"` // 为空表示默认音色
+
+	Status      string `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	AudioURL    string `gorm:"type:varchar(255)" json:"audio_url"`
+	ObjectName  string `gorm:"type:varchar(255)" json:"-"`
+	ContentType string `gorm:"type:varchar(50)" json:"content_type"`
+	DurationMs  int    `gorm:"not null;default:0" json:"duration_ms"` // 0 表示未知
+	// Timings 仅用于上传的整段录音：各句在音频中的起止时间，JSON 编码的 []SegmentTiming
+	Timings string `gorm:"type:text" json:"-"`
+	Error   string `gorm:"type:text" json:"error,omitempty"`
+
+	RequestedBy uint `gorm:"not null" json:"requested_by"`
+}
+
+// SegmentTiming 是一句话在示范音频中的起止时间（毫秒）
+type SegmentTiming struct {
+	StartMs int `json:"start_ms"`
+	EndMs   int `json:"end_ms"`
+}
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// 搜索文档的来源类型
+const (
+	SearchSourceNode       = "node"
+	SearchSourceDomainNode = "domain_node"
+	SearchSourceText       = "text"
+	SearchSourcePost       = "post"
+)
+
+// SearchDocument 是全文搜索索引中的一条记录，每个可搜索的对象对应一条
+// 权限与软删除状态在查询时通过来源表判断，因此这里只冗余检索和展示需要的字段。
+type SearchDocument struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	SourceType string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_search_source" json:"source_type"`
+	SourceID   uint      `gorm:"not null;uniqueIndex:idx_search_source" json:"source_id"`
+	OwnerID    *uint     `gorm:"index" json:"owner_id"`  // 个人节点、帖子的作者
+	DomainID   *uint     `gorm:"index" json:"domain_id"` // 圈子节点所在圈子
+	Title      string    `gorm:"type:varchar(255)" json:"title"`
+	Body       string    `gorm:"type:text" json:"-"`                                      // 用于生成高亮摘要的纯文本
+	Tokens     string    `gorm:"type:tsvector;index:idx_search_tokens,type:gin" json:"-"` // Go 端分词后的词元及位置
+}
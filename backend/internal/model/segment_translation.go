@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// SegmentTranslation 是节点内容中一句话的译文，与 lang.Segments 切分出的句子按序号对齐
+// 同一句可以有多种语言的译文。
+type SegmentTranslation struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// NodeKind + NodeID 共同定位到一个 Node 或 DomainNode，取值同 NodeRevision
+	NodeKind     string `gorm:"type:varchar(20);not null;uniqueIndex:idx_segment_translation" json:"node_kind"`
+	NodeID       uint   `gorm:"not null;uniqueIndex:idx_segment_translation" json:"node_id"`
+	Language     string `gorm:"type:varchar(35);not null;uniqueIndex:idx_segment_translation" json:"language"` // 译文的语言
+	SegmentIndex int    `gorm:"not null;uniqueIndex:idx_segment_translation" json:"segment_index"`             // 内容修改时由 handler.RealignTranslations 更新
+
+	// Source 是翻译时的原句，原文修改后据此重新对齐或判断译文是否过期
+	Source      string `gorm:"type:text;not null" json:"source"`
+	Translation string `gorm:"type:text;not null" json:"translation"`
+	AuthorID    uint   `gorm:"not null" json:"author_id"`
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// ShareLink 是个人节点（及其子树）的只读分享链接
+type ShareLink struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID uint   `gorm:"not null;index" json:"user_id"`
+	NodeID uint   `gorm:"not null;index" json:"node_id"`
+	Token  string `gorm:"type:varchar(64);not null;uniqueIndex" json:"token"`
+
+	ExpiresAt      *time.Time `json:"expires_at"`                                    // 为空表示永不过期
+	PasswordHash   string     `gorm:"type:varchar(255)" json:"-"`                    // 为空表示无需密码
+	AllowRecording bool       `gorm:"not null;default:false" json:"allow_recording"` // 是否允许访问者针对分享内容录音
+	RevokedAt      *time.Time `json:"revoked_at"`
+	ViewsCount     int        `gorm:"not null;default:0" json:"views_count"`
+}
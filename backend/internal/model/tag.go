@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// 标签组合方式
+const (
+	TagModeAny = "or"  // 命中任一标签
+	TagModeAll = "and" // 同时命中所有标签
+)
+
+// Tag 是用户为个人节点定义的标签
+type Tag struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_tag_user_name" json:"user_id"`
+	Name      string    `gorm:"type:varchar(50);not null;uniqueIndex:idx_tag_user_name" json:"name"`
+	Color     string    `gorm:"type:varchar(20);not null;default:'#909399'" json:"color"`
+}
+
+// DomainTag 是圈子内容上的标签，发布个人节点时按名称从个人标签映射而来
+type DomainTag struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	DomainID  uint      `gorm:"not null;uniqueIndex:idx_domain_tag_name" json:"domain_id"`
+	Name      string    `gorm:"type:varchar(50);not null;uniqueIndex:idx_domain_tag_name" json:"name"`
+	Color     string    `gorm:"type:varchar(20);not null;default:'#909399'" json:"color"`
+}
+
+// SavedFilter 是保存下来的标签筛选条件，在前端作为“虚拟文件夹”展示
+type SavedFilter struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	UserID       uint      `gorm:"not null;index" json:"user_id"`
+	Name         string    `gorm:"type:varchar(100);not null" json:"name"`
+	Mode         string    `gorm:"type:varchar(3);not null;default:'or'" json:"mode"` // or / and
+	DueForReview bool      `gorm:"not null;default:false" json:"due_for_review"`      // 只包含已到复习时间的节点
+
+	Tags []Tag `gorm:"many2many:saved_filter_tags" json:"tags"`
+}
@@ -16,4 +16,5 @@ type Text struct {
 	Title      string `json:"title" gorm:"not null"`
 	Content    string `json:"content" gorm:"type:text;not null"`
 	Difficulty int    `json:"difficulty" gorm:"default:1"`
+	Language   string `json:"language" gorm:"type:varchar(35);not null;default:''"` // BCP 47 语言标签
 }
@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+// WordSourceText 表示生词来自公共文本库中的文本；个人节点与圈子节点沿用 NodeRevision 的 node_kind 取值
+const WordSourceText = "text"
+
+// WordBookEntry 是用户生词本中的一个词，按语言和词条（英文原形 / 中文词语）去重
+type WordBookEntry struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID   uint   `gorm:"not null;uniqueIndex:idx_word_book_user_word" json:"user_id"`
+	Language string `gorm:"type:varchar(10);not null;uniqueIndex:idx_word_book_user_word" json:"language"`
+	Word     string `gorm:"type:varchar(100);not null;uniqueIndex:idx_word_book_user_word" json:"word"`
+	// Reading 与 Definition 在加入时从词典带入，之后用户可以自行修改
+	Reading    string `gorm:"type:varchar(255)" json:"reading"`
+	Definition string `gorm:"type:text" json:"definition"`
+	Note       string `gorm:"type:text" json:"note"`
+
+	ReviewState `gorm:"embedded"`
+
+	// Sources 是这个词出现过的内容，Preload("Sources") 时才会返回
+	Sources []WordBookSource `gorm:"foreignKey:EntryID" json:"sources,omitempty"`
+}
+
+// WordBookSource 记录生词出自哪个节点或文本，以及所在的句子
+type WordBookSource struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	EntryID uint `gorm:"not null;uniqueIndex:idx_word_book_source" json:"entry_id"`
+	// SourceKind 为 node、domain_node 或 text
+	SourceKind string `gorm:"type:varchar(20);not null;uniqueIndex:idx_word_book_source;index:idx_word_book_source_target" json:"source_kind"`
+	SourceID   uint   `gorm:"not null;uniqueIndex:idx_word_book_source;index:idx_word_book_source_target" json:"source_id"`
+	Title      string `gorm:"type:varchar(255)" json:"title"`
+	// Surface 是词在原文中的形式（例如 went 的原形为 go），Context 是它所在的句子
+	Surface string `gorm:"type:varchar(100)" json:"surface"`
+	Context string `gorm:"type:text" json:"context"`
+}
@@ -0,0 +1,162 @@
+// Package nodecontent 定义各节点类型的内容格式。
+//
+// folder 没有内容，text 的内容是纯文本；card 与 qa 的结构化字段编码为 JSON 对象，
+// 同样存放在 Content 中，因此修订历史、复制、发布等流程不需要区分节点类型。
+// 搜索、导出与评分通过本包取得各类型对应的纯文本。
+package nodecontent
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// 节点类型
+const (
+	TypeFolder = "folder"
+	TypeText   = "text"
+	TypeCard   = "card" // 闪卡：正面提示，背面为需要记住的内容
+	TypeQA     = "qa"   // 问答：根据提示口头作答，评分只对照期望答案
+)
+
+var (
+	ErrCardFields  = errors.New("card nodes require both front and back")
+	ErrQAFields    = errors.New("qa nodes require both prompt and answer")
+	ErrInvalidJSON = errors.New("content must be a JSON object for card and qa nodes")
+)
+
+// Card 是 card 节点的内容
+type Card struct {
+	Front string `json:"front"`
+	Back  string `json:"back"`
+}
+
+// QA 是 qa 节点的内容
+type QA struct {
+	Prompt string `json:"prompt"`
+	Answer string `json:"answer"`
+}
+
+// Patch 是创建或更新 card / qa 节点时提交的结构化字段，为空的字段保持原值
+type Patch struct {
+	Front  *string `json:"front"`
+	Back   *string `json:"back"`
+	Prompt *string `json:"prompt"`
+	Answer *string `json:"answer"`
+}
+
+// Empty 判断是否没有提交任何结构化字段
+func (p Patch) Empty() bool {
+	return p.Front == nil && p.Back == nil && p.Prompt == nil && p.Answer == nil
+}
+
+// Recitable 判断该类型的节点能否录音朗读、加入复习计划
+func Recitable(nodeType string) bool {
+	return nodeType == TypeText || nodeType == TypeCard || nodeType == TypeQA
+}
+
+// Build 以 base 为原内容、应用 patch 后生成要保存的 Content，并校验必填字段；
+// 文件夹的内容始终为空
+func Build(nodeType, base string, patch Patch) (string, error) {
+	switch nodeType {
+	case TypeFolder:
+		return "", nil
+	case TypeCard:
+		var card Card
+		if err := decode(base, &card); err != nil {
+			return "", err
+		}
+		set(&card.Front, patch.Front)
+		set(&card.Back, patch.Back)
+		if strings.TrimSpace(card.Front) == "" || strings.TrimSpace(card.Back) == "" {
+			return "", ErrCardFields
+		}
+		return encode(card)
+	case TypeQA:
+		var qa QA
+		if err := decode(base, &qa); err != nil {
+			return "", err
+		}
+		set(&qa.Prompt, patch.Prompt)
+		set(&qa.Answer, patch.Answer)
+		if strings.TrimSpace(qa.Prompt) == "" || strings.TrimSpace(qa.Answer) == "" {
+			return "", ErrQAFields
+		}
+		return encode(qa)
+	default:
+		return base, nil
+	}
+}
+
+// DecodeCard 解析 card 节点的内容，格式不正确时把整段内容视为正面
+func DecodeCard(content string) Card {
+	var card Card
+	if decode(content, &card) != nil {
+		card.Front = content
+	}
+	return card
+}
+
+// DecodeQA 解析 qa 节点的内容，格式不正确时把整段内容视为提示
+func DecodeQA(content string) QA {
+	var qa QA
+	if decode(content, &qa) != nil {
+		qa.Prompt = content
+	}
+	return qa
+}
+
+// PlainText 返回用于搜索与导出的纯文本
+func PlainText(nodeType, content string) string {
+	switch nodeType {
+	case TypeCard:
+		card := DecodeCard(content)
+		return join(card.Front, card.Back)
+	case TypeQA:
+		qa := DecodeQA(content)
+		return join(qa.Prompt, qa.Answer)
+	default:
+		return content
+	}
+}
+
+// Reference 返回录音评分时对照的参考文本：
+// text 为全文，card 为背面，qa 只对照期望答案
+func Reference(nodeType, content string) string {
+	switch nodeType {
+	case TypeCard:
+		return DecodeCard(content).Back
+	case TypeQA:
+		return DecodeQA(content).Answer
+	default:
+		return content
+	}
+}
+
+func decode(content string, v interface{}) error {
+	if strings.TrimSpace(content) == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(content), v); err != nil {
+		return ErrInvalidJSON
+	}
+	return nil
+}
+
+func encode(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+func set(field *string, value *string) {
+	if value != nil {
+		*field = *value
+	}
+}
+
+func join(a, b string) string {
+	if a == "" || b == "" {
+		return a + b
+	}
+	return a + "\n" + b
+}
@@ -0,0 +1,148 @@
+package nodetree
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/shuind/language-learner/backend/internal/model"
+	"github.com/shuind/language-learner/backend/internal/search"
+)
+
+// CopyTarget 描述副本写入的位置，源树与目标树可以是不同的 Kind
+// （个人节点发布到圈子、圈子内容复制回个人空间等）
+type CopyTarget struct {
+	Kind      Kind
+	OwnerID   uint   // 目标树的归属：个人节点为 user_id，圈子节点为 domain_id
+	ParentID  *uint  // nil 表示复制到根目录
+	RootTitle string // 非空时替换副本根节点的标题，例如“(副本)”后缀
+
+	// AfterCreate 在每个副本节点创建后调用（例如记录首个修订版本），可为 nil
+	AfterCreate func(tx *gorm.DB, id uint, title, content string) error
+	// IDMap 非 nil 时写入源节点 ID 到副本 ID 的对应关系，例如用于建立发布关联
+	IDMap map[uint]uint
+}
+
+// copyRow 是从源表读取的节点数据
+type copyRow struct {
+	ID       uint
+	ParentID *uint
+	NodeType string
+	Title    string
+	Content  string
+	Language string
+	Position float64
+}
+
+// CopySubtree 把 src 中以 rootID 为根的整棵子树复制到 dst，返回副本根节点 ID 与复制的节点数
+// 子树会先被完整读出再写入，因此即使目标位于源子树内部也不会重复复制新建的节点。
+// 节点上的标签和逐句译文会一并复制，跨归属复制时标签按名称映射。
+func CopySubtree(tx *gorm.DB, src Kind, rootID uint, dst CopyTarget) (uint, int, error) {
+	ids, err := SubtreeIDs(tx, src, rootID)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(ids) == 0 {
+		return 0, 0, gorm.ErrRecordNotFound
+	}
+
+	var rows []copyRow
+	if err := tx.Table(src.Table).
+		Select("id, parent_id, node_type, title, content, language, position").
+		Where("id IN ?", ids).
+		Order(src.Order).
+		Scan(&rows).Error; err != nil {
+		return 0, 0, err
+	}
+
+	var root copyRow
+	children := make(map[uint][]copyRow, len(rows))
+	for _, r := range rows {
+		if r.ID == rootID {
+			root = r
+			continue
+		}
+		if r.ParentID != nil {
+			children[*r.ParentID] = append(children[*r.ParentID], r)
+		}
+	}
+	if dst.RootTitle != "" {
+		root.Title = dst.RootTitle
+	}
+	// 副本根节点追加到目标文件夹末尾，其余节点沿用源树中的相对顺序
+	if root.Position, err = NextPosition(tx, dst.Kind, dst.OwnerID, dst.ParentID); err != nil {
+		return 0, 0, err
+	}
+
+	idMap := make(map[uint]uint, len(rows)) // 源节点 ID -> 副本 ID
+	var copyNode func(r copyRow, parentID *uint) (uint, error)
+	copyNode = func(r copyRow, parentID *uint) (uint, error) {
+		newID, err := createNode(tx, dst.Kind, dst.OwnerID, parentID, r)
+		if err != nil {
+			return 0, err
+		}
+		idMap[r.ID] = newID
+		if dst.AfterCreate != nil {
+			if err := dst.AfterCreate(tx, newID, r.Title, r.Content); err != nil {
+				return 0, err
+			}
+		}
+		for _, child := range children[r.ID] {
+			if _, err := copyNode(child, &newID); err != nil {
+				return 0, err
+			}
+		}
+		return newID, nil
+	}
+
+	newRootID, err := copyNode(root, dst.ParentID)
+	if err != nil {
+		return 0, 0, err
+	}
+	if dst.IDMap != nil {
+		for srcID, copyID := range idMap {
+			dst.IDMap[srcID] = copyID
+		}
+	}
+	if err := copyTags(tx, src, dst.Kind, dst.OwnerID, idMap); err != nil {
+		return 0, 0, err
+	}
+	if err := copyTranslations(tx, src, dst.Kind, idMap); err != nil {
+		return 0, 0, err
+	}
+	return newRootID, len(idMap), nil
+}
+
+func createNode(tx *gorm.DB, kind Kind, ownerID uint, parentID *uint, r copyRow) (uint, error) {
+	switch kind {
+	case Nodes:
+		node := model.Node{
+			UserID:   ownerID,
+			ParentID: parentID,
+			NodeType: r.NodeType,
+			Title:    r.Title,
+			Content:  r.Content,
+			Language: r.Language,
+			Position: r.Position,
+		}
+		if err := tx.Create(&node).Error; err != nil {
+			return 0, err
+		}
+		return node.ID, search.IndexNode(tx, &node)
+	case DomainNodes:
+		node := model.DomainNode{
+			DomainID: ownerID,
+			ParentID: parentID,
+			NodeType: r.NodeType,
+			Title:    r.Title,
+			Content:  r.Content,
+			Language: r.Language,
+			Position: r.Position,
+		}
+		if err := tx.Create(&node).Error; err != nil {
+			return 0, err
+		}
+		return node.ID, search.IndexDomainNode(tx, &node)
+	}
+	return 0, fmt.Errorf("nodetree: unsupported kind %q", kind.Table)
+}
@@ -0,0 +1,71 @@
+// Package nodetree 封装个人节点 (nodes) 与圈子节点 (domain_nodes) 这两棵树的通用操作：
+// 子树与祖先路径查询、子树复制、递归软删除、回收站恢复与彻底清除。
+package nodetree
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Kind 描述一种节点树所在的表，以及录音、修订中引用它的方式
+type Kind struct {
+	Table           string // 节点表名
+	OwnerColumn     string // 整棵树的归属列：个人节点按用户，圈子节点按圈子
+	RecordingColumn string // recordings 表中指向该节点的列
+	RevisionKind    string // node_revisions.node_kind 的取值
+	SearchSource    string // search_documents.source_type 的取值
+	TagTable        string // 该树使用的标签表，标签与节点同属 OwnerColumn 指定的归属
+	TagLinkTable    string // 节点与标签的多对多中间表
+	TagLinkNode     string // 中间表中指向节点的列
+	TagLinkTag      string // 中间表中指向标签的列
+	Order           string // 同级节点的排序：先按手动位置，位置相同（旧数据）时沿用原来的类型+标题排序
+}
+
+var (
+	Nodes = Kind{
+		Table:           "nodes",
+		OwnerColumn:     "user_id",
+		RecordingColumn: "node_id",
+		RevisionKind:    "node",
+		SearchSource:    "node",
+		TagTable:        "tags",
+		TagLinkTable:    "node_tags",
+		TagLinkNode:     "node_id",
+		TagLinkTag:      "tag_id",
+		Order:           "position, node_type DESC, title ASC",
+	}
+	DomainNodes = Kind{
+		Table:           "domain_nodes",
+		OwnerColumn:     "domain_id",
+		RecordingColumn: "domain_node_id",
+		RevisionKind:    "domain_node",
+		SearchSource:    "domain_node",
+		TagTable:        "domain_tags",
+		TagLinkTable:    "domain_node_tags",
+		TagLinkNode:     "domain_node_id",
+		TagLinkTag:      "domain_tag_id",
+		Order:           "position, node_type, title",
+	}
+)
+
+// MaxDepth 是树查询的最大深度，同时防止脏数据中的环导致递归查询不终止
+const MaxDepth = 64
+
+// SubtreeIDs 使用递归 CTE 返回 rootID 及其所有未删除的子孙节点 ID（包含 rootID 本身）
+func SubtreeIDs(tx *gorm.DB, kind Kind, rootID uint) ([]uint, error) {
+	sql := fmt.Sprintf(`
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM %[1]s WHERE id = ? AND deleted_at IS NULL
+			UNION ALL
+			SELECT n.id FROM %[1]s n JOIN subtree s ON n.parent_id = s.id
+			WHERE n.deleted_at IS NULL
+		)
+		SELECT id FROM subtree`, kind.Table)
+
+	var ids []uint
+	if err := tx.Raw(sql, rootID).Scan(&ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -0,0 +1,73 @@
+package nodetree
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"gorm.io/gorm"
+
+	"github.com/shuind/language-learner/backend/internal/model"
+)
+
+// collectObjects 收集即将被清除的节点所引用的对象存储文件：
+// 录音、录音上的语音反馈、上传的示范音频与跟读录音。TTS 合成的示范音频按内容寻址、可能被其他节点共用，不在此列。
+func collectObjects(tx *gorm.DB, kind Kind, ids []uint, result *PurgeResult) error {
+	var urls []string
+	if err := tx.Model(&model.Recording{}).Unscoped().
+		Where(kind.RecordingColumn+" IN ? AND audio_url <> ''", ids).
+		Pluck("audio_url", &urls).Error; err != nil {
+		return err
+	}
+	for _, u := range urls {
+		if name := objectNameFromURL(u); name != "" {
+			result.Objects = append(result.Objects, name)
+		}
+	}
+
+	var names []string
+	recordings := tx.Model(&model.Recording{}).Unscoped().Select("id").Where(kind.RecordingColumn+" IN ?", ids)
+	if err := tx.Model(&model.Comment{}).Unscoped().
+		Where("recording_id IN (?) AND audio_object_name <> ''", recordings).
+		Pluck("audio_object_name", &names).Error; err != nil {
+		return err
+	}
+	result.Objects = append(result.Objects, names...)
+
+	names = nil
+	if err := tx.Model(&model.ReferenceAudio{}).
+		Where("node_kind = ? AND node_id IN ? AND source = ? AND object_name <> ''", kind.RevisionKind, ids, model.ReferenceAudioSourceUpload).
+		Pluck("object_name", &names).Error; err != nil {
+		return err
+	}
+	result.Objects = append(result.Objects, names...)
+
+	names = nil
+	sessions := tx.Model(&model.PracticeSession{}).Select("id").Where("node_kind = ? AND node_id IN ?", kind.RevisionKind, ids)
+	if err := tx.Model(&model.PracticeRecording{}).
+		Where("session_id IN (?) AND object_name <> ''", sessions).
+		Pluck("object_name", &names).Error; err != nil {
+		return err
+	}
+	result.Objects = append(result.Objects, names...)
+	return nil
+}
+
+// objectNameFromURL 从录音的公开地址中取出对象名（地址的最后一段），与 TranscribeRecordingHandler 一致
+func objectNameFromURL(url string) string {
+	return url[strings.LastIndex(url, "/")+1:]
+}
+
+// RemoveObjects 删除对象存储中的文件，在清除的事务提交之后调用；
+// 删除失败只记录日志，数据库中的记录已经不存在了
+func RemoveObjects(ctx context.Context, client *minio.Client, bucket string, names []string) {
+	if client == nil {
+		return
+	}
+	for _, name := range names {
+		if err := client.RemoveObject(ctx, bucket, name, minio.RemoveObjectOptions{}); err != nil {
+			log.Printf("WARN: Failed to remove purged object %s: %v", name, err)
+		}
+	}
+}
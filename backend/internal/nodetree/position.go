@@ -0,0 +1,140 @@
+package nodetree
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// PositionGap 是相邻兄弟节点之间的初始间隔
+// 排序使用浮点数：插入到两个节点之间时取中点，只需更新被移动的那一行；
+// 间隔耗尽时才对整个兄弟集合重新编号。
+const PositionGap = 1024.0
+
+// minPositionGap 低于该间隔时认为精度不足，需要重新编号
+const minPositionGap = 1e-6
+
+// ErrNotSiblings 表示参与排序的节点不全属于同一个兄弟集合
+var ErrNotSiblings = errors.New("nodes are not siblings in the same folder")
+
+// siblings 限定到同一归属下、同一父节点的未删除节点
+func siblings(tx *gorm.DB, kind Kind, ownerID uint, parentID *uint) *gorm.DB {
+	q := tx.Table(kind.Table).Where(kind.OwnerColumn+" = ? AND deleted_at IS NULL", ownerID)
+	if parentID == nil {
+		return q.Where("parent_id IS NULL")
+	}
+	return q.Where("parent_id = ?", *parentID)
+}
+
+// NextPosition 返回追加到兄弟集合末尾时应使用的位置
+func NextPosition(tx *gorm.DB, kind Kind, ownerID uint, parentID *uint) (float64, error) {
+	var max *float64
+	if err := siblings(tx, kind, ownerID, parentID).Select("MAX(position)").Scan(&max).Error; err != nil {
+		return 0, err
+	}
+	if max == nil {
+		return PositionGap, nil
+	}
+	return *max + PositionGap, nil
+}
+
+// Reorder 按 ids 的顺序为兄弟集合重新编号，ids 必须全部属于该集合
+// 未出现在 ids 中的兄弟节点保持原位置，排在重新编号的节点之后。
+func Reorder(tx *gorm.DB, kind Kind, ownerID uint, parentID *uint, ids []uint) error {
+	var count int64
+	if err := siblings(tx, kind, ownerID, parentID).Where("id IN ?", ids).Count(&count).Error; err != nil {
+		return err
+	}
+	if count != int64(len(ids)) {
+		return ErrNotSiblings
+	}
+
+	// 先把其余兄弟节点整体后移，为重新编号的节点腾出 [gap, len*gap] 区间
+	span := PositionGap * float64(len(ids)+1)
+	if err := siblings(tx, kind, ownerID, parentID).Where("id NOT IN ?", ids).
+		Update("position", gorm.Expr("position + ?", span)).Error; err != nil {
+		return err
+	}
+	for i, id := range ids {
+		if err := tx.Table(kind.Table).Where("id = ?", id).
+			Update("position", PositionGap*float64(i+1)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MoveAfter 把 nodeID 放到同级节点 afterID 之后；afterID 为 nil 时放到最前面
+// 通常只更新 nodeID 一行，返回新位置。
+func MoveAfter(tx *gorm.DB, kind Kind, ownerID uint, parentID *uint, nodeID uint, afterID *uint) (float64, error) {
+	type slot struct {
+		ID       uint
+		Position float64
+	}
+	var ordered []slot
+	if err := siblings(tx, kind, ownerID, parentID).
+		Select("id, position").
+		Order(kind.Order).
+		Scan(&ordered).Error; err != nil {
+		return 0, err
+	}
+
+	// 去掉被移动的节点本身，找到插入点
+	rest := make([]slot, 0, len(ordered))
+	found := false
+	for _, s := range ordered {
+		if s.ID == nodeID {
+			found = true
+			continue
+		}
+		rest = append(rest, s)
+	}
+	if !found {
+		return 0, ErrNotSiblings
+	}
+	idx := 0 // 插入到 rest[idx] 之前
+	if afterID != nil {
+		idx = -1
+		for i, s := range rest {
+			if s.ID == *afterID {
+				idx = i + 1
+				break
+			}
+		}
+		if idx < 0 {
+			return 0, ErrNotSiblings
+		}
+	}
+
+	var pos float64
+	switch {
+	case len(rest) == 0:
+		pos = PositionGap
+	case idx == 0:
+		pos = rest[0].Position - PositionGap
+	case idx == len(rest):
+		pos = rest[len(rest)-1].Position + PositionGap
+	default:
+		prev, next := rest[idx-1].Position, rest[idx].Position
+		if next-prev < minPositionGap {
+			// 间隔耗尽（或旧数据位置相同），整体重新编号
+			ids := make([]uint, 0, len(rest)+1)
+			for i, s := range rest {
+				if i == idx {
+					ids = append(ids, nodeID)
+				}
+				ids = append(ids, s.ID)
+			}
+			if err := Reorder(tx, kind, ownerID, parentID, ids); err != nil {
+				return 0, err
+			}
+			return PositionGap * float64(idx+1), nil
+		}
+		pos = (prev + next) / 2
+	}
+
+	if err := tx.Table(kind.Table).Where("id = ?", nodeID).Update("position", pos).Error; err != nil {
+		return 0, err
+	}
+	return pos, nil
+}
@@ -0,0 +1,86 @@
+package nodetree
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Entry 是子树查询结果中的一个节点位置
+type Entry struct {
+	ID          uint
+	ParentID    *uint
+	Depth       int
+	HasChildren bool // 该节点下是否还有未删除的子节点（用于深度截断时提示前端可继续展开）
+}
+
+// Subtree 使用递归 CTE 一次性查出一棵子树
+// rootID 为 nil 时从归属者的所有根节点开始，根节点深度为 1；否则 rootID 自身深度为 0。
+// maxDepth 限制返回的最大深度，<= 0 或超过 MaxDepth 时按 MaxDepth 处理。
+func Subtree(tx *gorm.DB, kind Kind, ownerID uint, rootID *uint, maxDepth int) ([]Entry, error) {
+	if maxDepth <= 0 || maxDepth > MaxDepth {
+		maxDepth = MaxDepth
+	}
+
+	start := "parent_id IS NULL"
+	startDepth := 1
+	args := []interface{}{startDepth, ownerID}
+	if rootID != nil {
+		start = "id = ?"
+		startDepth = 0
+		args = []interface{}{startDepth, ownerID, *rootID}
+	}
+	args = append(args, maxDepth)
+
+	sql := fmt.Sprintf(`
+		WITH RECURSIVE subtree AS (
+			SELECT id, parent_id, ?::int AS depth FROM %[1]s
+			WHERE %[2]s = ? AND deleted_at IS NULL AND %[3]s
+			UNION ALL
+			SELECT n.id, n.parent_id, s.depth + 1 FROM %[1]s n JOIN subtree s ON n.parent_id = s.id
+			WHERE n.deleted_at IS NULL AND s.depth < ?
+		)
+		SELECT s.id, s.parent_id, s.depth,
+			EXISTS (SELECT 1 FROM %[1]s c WHERE c.parent_id = s.id AND c.deleted_at IS NULL) AS has_children
+		FROM subtree s
+		ORDER BY s.depth`, kind.Table, kind.OwnerColumn, start)
+
+	var entries []Entry
+	if err := tx.Raw(sql, args...).Scan(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// AncestorIDs 返回从根节点到 nodeID 的路径（包含 nodeID 自身），用于面包屑导航
+func AncestorIDs(tx *gorm.DB, kind Kind, nodeID uint) ([]uint, error) {
+	sql := fmt.Sprintf(`
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id, 0 AS depth FROM %[1]s WHERE id = ? AND deleted_at IS NULL
+			UNION ALL
+			SELECT n.id, n.parent_id, a.depth + 1 FROM %[1]s n JOIN ancestors a ON n.id = a.parent_id
+			WHERE n.deleted_at IS NULL AND a.depth < ?
+		)
+		SELECT id FROM ancestors ORDER BY depth DESC`, kind.Table)
+
+	var ids []uint
+	if err := tx.Raw(sql, nodeID, MaxDepth).Scan(&ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// IsSelfOrDescendant 判断 candidateID 是否就是 nodeID 或位于 nodeID 的子树中
+// 移动节点前用它防止把节点移到自己的子孙下面形成环
+func IsSelfOrDescendant(tx *gorm.DB, kind Kind, nodeID, candidateID uint) (bool, error) {
+	path, err := AncestorIDs(tx, kind, candidateID)
+	if err != nil {
+		return false, err
+	}
+	for _, id := range path {
+		if id == nodeID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,72 @@
+package nodetree
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/shuind/language-learner/backend/internal/model"
+)
+
+// tagLink 是源节点上的一个标签
+type tagLink struct {
+	NodeID uint
+	Name   string
+	Color  string
+}
+
+// copyTags 把源节点上的标签复制到对应的副本节点
+// 标签按名称映射到目标归属下（例如发布到圈子时映射为圈子标签），不存在时以相同颜色新建。
+func copyTags(tx *gorm.DB, src Kind, dst Kind, dstOwnerID uint, idMap map[uint]uint) error {
+	srcIDs := make([]uint, 0, len(idMap))
+	for id := range idMap {
+		srcIDs = append(srcIDs, id)
+	}
+
+	var links []tagLink
+	if err := tx.Table(src.TagLinkTable+" AS l").
+		Select("l."+src.TagLinkNode+" AS node_id, t.name, t.color").
+		Joins("JOIN "+src.TagTable+" t ON t.id = l."+src.TagLinkTag).
+		Where("l."+src.TagLinkNode+" IN ?", srcIDs).
+		Scan(&links).Error; err != nil {
+		return err
+	}
+
+	tagIDs := make(map[string]uint)
+	for _, l := range links {
+		tagID, ok := tagIDs[l.Name]
+		if !ok {
+			var err error
+			if tagID, err = ensureTag(tx, dst, dstOwnerID, l.Name, l.Color); err != nil {
+				return err
+			}
+			tagIDs[l.Name] = tagID
+		}
+		if err := tx.Exec(
+			"INSERT INTO "+dst.TagLinkTable+" ("+dst.TagLinkNode+", "+dst.TagLinkTag+") VALUES (?, ?) ON CONFLICT DO NOTHING",
+			idMap[l.NodeID], tagID,
+		).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureTag 返回归属下同名标签的 ID，不存在时创建
+func ensureTag(tx *gorm.DB, kind Kind, ownerID uint, name, color string) (uint, error) {
+	switch kind {
+	case Nodes:
+		tag := model.Tag{UserID: ownerID, Name: name, Color: color}
+		if err := tx.Where(model.Tag{UserID: ownerID, Name: name}).FirstOrCreate(&tag).Error; err != nil {
+			return 0, err
+		}
+		return tag.ID, nil
+	case DomainNodes:
+		tag := model.DomainTag{DomainID: ownerID, Name: name, Color: color}
+		if err := tx.Where(model.DomainTag{DomainID: ownerID, Name: name}).FirstOrCreate(&tag).Error; err != nil {
+			return 0, err
+		}
+		return tag.ID, nil
+	}
+	return 0, fmt.Errorf("nodetree: unsupported kind %q", kind.Table)
+}
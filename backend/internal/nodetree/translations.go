@@ -0,0 +1,29 @@
+package nodetree
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/shuind/language-learner/backend/internal/model"
+)
+
+// copyTranslations 把源节点的逐句译文复制到对应的副本节点，作者保持不变
+func copyTranslations(tx *gorm.DB, src Kind, dst Kind, idMap map[uint]uint) error {
+	srcIDs := make([]uint, 0, len(idMap))
+	for id := range idMap {
+		srcIDs = append(srcIDs, id)
+	}
+
+	var rows []model.SegmentTranslation
+	if err := tx.Where("node_kind = ? AND node_id IN ?", src.RevisionKind, srcIDs).Find(&rows).Error; err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	for i := range rows {
+		rows[i].ID = 0
+		rows[i].NodeKind = dst.RevisionKind
+		rows[i].NodeID = idMap[rows[i].NodeID]
+	}
+	return tx.CreateInBatches(&rows, 200).Error
+}
@@ -0,0 +1,296 @@
+package nodetree
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/shuind/language-learner/backend/internal/model"
+	"github.com/shuind/language-learner/backend/internal/search"
+)
+
+// ErrNotInTrash 表示要恢复或清除的节点不在回收站中
+var ErrNotInTrash = errors.New("node is not in the trash")
+
+// TrashItem 是回收站中的一条记录：一次删除操作的根节点
+type TrashItem struct {
+	ID         uint      `json:"id"`
+	ParentID   *uint     `json:"parent_id"`
+	NodeType   string    `json:"node_type"`
+	Title      string    `json:"title"`
+	DeletedAt  time.Time `json:"deleted_at"`
+	ItemsCount int64     `json:"items_count"` // 随本次删除一起进入回收站的节点数（含自身）
+}
+
+// SoftDeleteSubtree 在同一事务中软删除 rootID 的整棵子树及其下所有录音
+// 所有被删除的节点都记下 deleted_root_id = rootID，录音与节点使用同一个删除时间，
+// 这样恢复时可以精确还原“这一次”删除的内容，而不会误恢复之前单独删除的录音。
+func SoftDeleteSubtree(tx *gorm.DB, kind Kind, rootID uint) (int64, error) {
+	ids, err := SubtreeIDs(tx, kind, rootID)
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, gorm.ErrRecordNotFound
+	}
+
+	// Postgres 的时间精度为微秒，提前截断以保证后续按时间精确匹配
+	now := time.Now().Truncate(time.Microsecond)
+	res := tx.Table(kind.Table).Where("id IN ?", ids).Updates(map[string]interface{}{
+		"deleted_at":      now,
+		"deleted_root_id": rootID,
+	})
+	if res.Error != nil {
+		return 0, res.Error
+	}
+
+	if err := tx.Model(&model.Recording{}).
+		Where(kind.RecordingColumn+" IN ?", ids).
+		Update("deleted_at", now).Error; err != nil {
+		return 0, err
+	}
+	return res.RowsAffected, nil
+}
+
+// ListTrash 列出回收站中的删除根节点，scope 用于限定归属（如 user_id 或 domain_id）
+func ListTrash(tx *gorm.DB, kind Kind, scope func(*gorm.DB) *gorm.DB) ([]TrashItem, error) {
+	var items []TrashItem
+	// 早于回收站功能被删除的节点没有 deleted_root_id，把它们视为单独的删除根
+	err := tx.Table(kind.Table + " AS t").
+		Scopes(scope).
+		Select(`t.id, t.parent_id, t.node_type, t.title, t.deleted_at,
+			(SELECT COUNT(*) FROM ` + kind.Table + ` c WHERE c.deleted_root_id = t.id) AS items_count`).
+		Where("t.deleted_at IS NOT NULL").
+		Where("t.deleted_root_id = t.id OR t.deleted_root_id IS NULL").
+		Order("t.deleted_at DESC").
+		Scan(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		if items[i].ItemsCount == 0 {
+			items[i].ItemsCount = 1
+		}
+	}
+	return items, nil
+}
+
+// trashRoot 是从回收站中取出的删除根节点
+type trashRoot struct {
+	ID        uint
+	ParentID  *uint
+	DeletedAt time.Time
+}
+
+func findTrashRoot(tx *gorm.DB, kind Kind, rootID uint, scope func(*gorm.DB) *gorm.DB) (*trashRoot, error) {
+	var root trashRoot
+	res := tx.Table(kind.Table).
+		Scopes(scope).
+		Select("id, parent_id, deleted_at").
+		Where("id = ? AND deleted_at IS NOT NULL", rootID).
+		Where("deleted_root_id = id OR deleted_root_id IS NULL").
+		Limit(1).
+		Scan(&root)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrNotInTrash
+	}
+	return &root, nil
+}
+
+// batchIDs 返回与 rootID 同一次删除的所有节点
+func batchIDs(tx *gorm.DB, kind Kind, rootID uint) ([]uint, error) {
+	var ids []uint
+	err := tx.Table(kind.Table).
+		Where("id = ? OR deleted_root_id = ?", rootID, rootID).
+		Where("deleted_at IS NOT NULL").
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// RestoreSubtree 把一次删除的整棵子树及其录音从回收站恢复
+// 如果原父节点已经不存在（例如之后也被删除），子树会被恢复到根目录。
+func RestoreSubtree(tx *gorm.DB, kind Kind, rootID uint, scope func(*gorm.DB) *gorm.DB) (int64, error) {
+	root, err := findTrashRoot(tx, kind, rootID, scope)
+	if err != nil {
+		return 0, err
+	}
+	ids, err := batchIDs(tx, kind, rootID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Model(&model.Recording{}).Unscoped().
+		Where(kind.RecordingColumn+" IN ?", ids).
+		Where("deleted_at = ?", root.DeletedAt).
+		Update("deleted_at", nil).Error; err != nil {
+		return 0, err
+	}
+
+	res := tx.Table(kind.Table).Where("id IN ?", ids).Updates(map[string]interface{}{
+		"deleted_at":      nil,
+		"deleted_root_id": nil,
+	})
+	if res.Error != nil {
+		return 0, res.Error
+	}
+
+	if root.ParentID != nil {
+		var alive int64
+		tx.Table(kind.Table).Where("id = ? AND deleted_at IS NULL", *root.ParentID).Count(&alive)
+		if alive == 0 {
+			if err := tx.Table(kind.Table).Where("id = ?", root.ID).Update("parent_id", nil).Error; err != nil {
+				return 0, err
+			}
+		}
+	}
+	return res.RowsAffected, nil
+}
+
+// PurgeResult 是一次彻底删除的结果
+type PurgeResult struct {
+	Count int64
+	// Objects 是不再被引用的对象存储文件，须在事务提交后用 RemoveObjects 删除
+	Objects []string
+}
+
+// PurgeSubtree 彻底删除一次删除的整棵子树，连同录音及其评论、点赞和修订历史
+func PurgeSubtree(tx *gorm.DB, kind Kind, rootID uint, scope func(*gorm.DB) *gorm.DB) (*PurgeResult, error) {
+	if _, err := findTrashRoot(tx, kind, rootID, scope); err != nil {
+		return nil, err
+	}
+	ids, err := batchIDs(tx, kind, rootID)
+	if err != nil {
+		return nil, err
+	}
+	return purgeIDs(tx, kind, ids)
+}
+
+// PurgeExpired 清除删除时间早于 cutoff 的所有回收站内容，由定时任务调用
+func PurgeExpired(tx *gorm.DB, kind Kind, cutoff time.Time) (*PurgeResult, error) {
+	var ids []uint
+	if err := tx.Table(kind.Table).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return &PurgeResult{}, nil
+	}
+	return purgeIDs(tx, kind, ids)
+}
+
+func purgeIDs(tx *gorm.DB, kind Kind, ids []uint) (*PurgeResult, error) {
+	result := &PurgeResult{}
+	if err := collectObjects(tx, kind, ids, result); err != nil {
+		return nil, err
+	}
+
+	var recordingIDs []uint
+	if err := tx.Model(&model.Recording{}).Unscoped().
+		Where(kind.RecordingColumn+" IN ?", ids).
+		Pluck("id", &recordingIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(recordingIDs) > 0 {
+		if err := tx.Unscoped().Where("recording_id IN ?", recordingIDs).Delete(&model.Comment{}).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Where("recording_id IN ?", recordingIDs).Delete(&model.Like{}).Error; err != nil {
+			return nil, err
+		}
+		reviews := tx.Model(&model.PeerReviewAssignment{}).Select("id").Where("recording_id IN ?", recordingIDs)
+		if err := tx.Where("assignment_id IN (?)", reviews).Delete(&model.PeerReviewScore{}).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Where("recording_id IN ?", recordingIDs).Delete(&model.PeerReviewAssignment{}).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Unscoped().Where("id IN ?", recordingIDs).Delete(&model.Recording{}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Where("node_kind = ? AND node_id IN ?", kind.RevisionKind, ids).Delete(&model.NodeRevision{}).Error; err != nil {
+		return nil, err
+	}
+	if kind == DomainNodes {
+		if err := tx.Unscoped().Where("domain_node_id IN ?", ids).Delete(&model.DomainNodeComment{}).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Exec("DELETE FROM assignment_nodes WHERE domain_node_id IN ?", ids).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Exec("DELETE FROM peer_review_round_nodes WHERE domain_node_id IN ?", ids).Error; err != nil {
+			return nil, err
+		}
+		// 副本根节点被清除时整个发布关联失效；其余节点保留对应关系，再次同步时视为副本已删除而不会重新创建
+		pubs := tx.Model(&model.Publication{}).Select("id").Where("copy_root_id IN ?", ids)
+		if err := tx.Where("publication_id IN (?)", pubs).Delete(&model.PublicationNode{}).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Where("copy_root_id IN ?", ids).Delete(&model.Publication{}).Error; err != nil {
+			return nil, err
+		}
+	}
+	// 评论可能引用标注，需在评论之后删除
+	if err := tx.Where("node_kind = ? AND node_id IN ?", kind.RevisionKind, ids).Delete(&model.Annotation{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("node_kind = ? AND node_id IN ?", kind.RevisionKind, ids).Delete(&model.SegmentTranslation{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("source_kind = ? AND source_id IN ?", kind.RevisionKind, ids).Delete(&model.WordBookSource{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("node_kind = ? AND node_id IN ?", kind.RevisionKind, ids).Delete(&model.ReferenceAudio{}).Error; err != nil {
+		return nil, err
+	}
+	sessions := tx.Model(&model.PracticeSession{}).Select("id").Where("node_kind = ? AND node_id IN ?", kind.RevisionKind, ids)
+	if err := tx.Where("session_id IN (?)", sessions).Delete(&model.PracticeRecording{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("node_kind = ? AND node_id IN ?", kind.RevisionKind, ids).Delete(&model.PracticeSession{}).Error; err != nil {
+		return nil, err
+	}
+
+	if err := search.Remove(tx, kind.SearchSource, ids); err != nil {
+		return nil, err
+	}
+	if err := tx.Exec("DELETE FROM "+kind.TagLinkTable+" WHERE "+kind.TagLinkNode+" IN ?", ids).Error; err != nil {
+		return nil, err
+	}
+	if kind == Nodes {
+		if err := tx.Where("node_id IN ?", ids).Delete(&model.NodeReview{}).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Where("node_id IN ?", ids).Delete(&model.NodeFork{}).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Where("node_id IN ?", ids).Delete(&model.ShareLink{}).Error; err != nil {
+			return nil, err
+		}
+		// 源根节点被清除时整个发布失效；其余源节点只删除各自的对应关系，圈子里的副本保留
+		pubs := tx.Model(&model.Publication{}).Select("id").Where("source_node_id IN ?", ids)
+		if err := tx.Where("publication_id IN (?) OR source_node_id IN ?", pubs, ids).Delete(&model.PublicationNode{}).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Where("source_node_id IN ?", ids).Delete(&model.Publication{}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	// 旧版本只删除单个节点，可能留下指向它的子节点；清除前把它们挂回根目录，避免外键冲突
+	if err := tx.Exec("UPDATE "+kind.Table+" SET parent_id = NULL WHERE parent_id IN ? AND id NOT IN ?", ids, ids).Error; err != nil {
+		return nil, err
+	}
+	res := tx.Exec("DELETE FROM "+kind.Table+" WHERE id IN ? AND deleted_at IS NOT NULL", ids)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	result.Count = res.RowsAffected
+	return result, nil
+}
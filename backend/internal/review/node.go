@@ -0,0 +1,21 @@
+package review
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/shuind/language-learner/backend/internal/model"
+)
+
+// RecordNode 记录用户对一个节点的一次复习，首次复习时创建调度记录
+func RecordNode(tx *gorm.DB, userID, nodeID uint, quality int, now time.Time) (model.NodeReview, error) {
+	rec := model.NodeReview{UserID: userID, NodeID: nodeID, ReviewState: NewState(now)}
+	if err := tx.Where("user_id = ? AND node_id = ?", userID, nodeID).FirstOrInit(&rec).Error; err != nil {
+		return rec, err
+	}
+	if err := Apply(&rec.ReviewState, quality, now); err != nil {
+		return rec, err
+	}
+	return rec, tx.Save(&rec).Error
+}
@@ -0,0 +1,64 @@
+// Package review 实现 SM-2 间隔重复算法，用于安排节点、卡片和生词的复习时间。
+package review
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/shuind/language-learner/backend/internal/model"
+)
+
+const (
+	// DefaultEaseFactor 是新条目的初始难度系数
+	DefaultEaseFactor = 2.5
+	// minEaseFactor 是难度系数的下限
+	minEaseFactor = 1.3
+	// PassingQuality 及以上的评分视为记住了
+	PassingQuality = 3
+)
+
+// ErrInvalidQuality 表示评分不在 0-5 之间
+var ErrInvalidQuality = errors.New("quality must be between 0 and 5")
+
+// NewState 返回一个立即到期的初始状态
+func NewState(now time.Time) model.ReviewState {
+	return model.ReviewState{EaseFactor: DefaultEaseFactor, DueAt: now}
+}
+
+// Apply 根据本次回忆质量（0 完全忘记 - 5 毫不费力）更新调度状态
+func Apply(s *model.ReviewState, quality int, now time.Time) error {
+	if quality < 0 || quality > 5 {
+		return ErrInvalidQuality
+	}
+	if s.EaseFactor == 0 {
+		s.EaseFactor = DefaultEaseFactor
+	}
+
+	if quality < PassingQuality {
+		// 没记住：从头开始，明天再复习
+		s.Repetitions = 0
+		s.IntervalDays = 1
+	} else {
+		s.Repetitions++
+		switch s.Repetitions {
+		case 1:
+			s.IntervalDays = 1
+		case 2:
+			s.IntervalDays = 6
+		default:
+			s.IntervalDays = int(math.Round(float64(s.IntervalDays) * s.EaseFactor))
+		}
+	}
+
+	q := float64(5 - quality)
+	s.EaseFactor += 0.1 - q*(0.08+q*0.02)
+	if s.EaseFactor < minEaseFactor {
+		s.EaseFactor = minEaseFactor
+	}
+
+	reviewed := now
+	s.LastReviewedAt = &reviewed
+	s.DueAt = now.AddDate(0, 0, s.IntervalDays)
+	return nil
+}
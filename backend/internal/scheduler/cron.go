@@ -2,14 +2,19 @@
 package scheduler
 
 import (
+	"context"
 	"log"
 	"os"
+	"strconv"
 	"time"
 
+	"github.com/minio/minio-go/v7"
 	"github.com/robfig/cron/v3"
 	"gorm.io/gorm"
 
 	"github.com/shuind/language-learner/backend/internal/model"
+	"github.com/shuind/language-learner/backend/internal/nodetree"
+	"github.com/shuind/language-learner/backend/internal/utils"
 )
 
 type Config struct {
@@ -21,6 +26,11 @@ type Config struct {
 	Timezone string
 	// 手动覆盖归档任务的 Cron 表达式（优先级最高），例如 "0 * * * * *"
 	ArchiveSpecOverride string
+	// 回收站保留天数，超过后彻底清除；为 0 时读取 TRASH_RETENTION_DAYS，默认 30 天
+	TrashRetentionDays int
+	// 彻底清除时删除音频文件所用的对象存储，为 nil 时只删除数据库记录
+	Minio  *minio.Client
+	Bucket string
 }
 
 // Start 启动调度器，返回停止函数
@@ -34,17 +44,9 @@ func Start(cfg Config) (stop func()) {
 	}
 
 	// 时区
-	tz := cfg.Timezone
-	if tz == "" {
-		tz = os.Getenv("APP_TZ")
-		if tz == "" {
-			tz = "Asia/Shanghai"
-		}
-	}
-	loc, err := time.LoadLocation(tz)
-	if err != nil {
-		logger.Printf("[CRON] invalid timezone %q, fallback Asia/Shanghai: %v", tz, err)
-		loc, _ = time.LoadLocation("Asia/Shanghai")
+	_, loc := utils.AppLocation()
+	if cfg.Timezone != "" {
+		_, loc = utils.LoadLocation(cfg.Timezone)
 	}
 
 	// 选择表达式
@@ -65,7 +67,7 @@ func Start(cfg Config) (stop func()) {
 	c := cron.New(cron.WithSeconds(), cron.WithLocation(loc))
 
 	// === 任务：归档已完成的任务 ===
-	_, err = c.AddFunc(spec, func() {
+	_, err := c.AddFunc(spec, func() {
 		now := time.Now().In(loc)
 		logger.Printf("[CRON] Archiving completed tasks... (%s)", now.Format(time.RFC3339))
 		res := cfg.DB.Model(&model.TaskItem{}).
@@ -82,6 +84,36 @@ func Start(cfg Config) (stop func()) {
 		logger.Fatalf("[CRON] failed to register archive job: %v", err)
 	}
 
+	// === 任务：清理回收站中过期的节点 ===
+	retention := cfg.TrashRetentionDays
+	if retention <= 0 {
+		retention, _ = strconv.Atoi(os.Getenv("TRASH_RETENTION_DAYS"))
+		if retention <= 0 {
+			retention = 30
+		}
+	}
+	_, err = c.AddFunc("0 30 3 * * *", func() { // 每天 03:30:00
+		cutoff := time.Now().In(loc).AddDate(0, 0, -retention)
+		logger.Printf("[CRON] Purging trash deleted before %s...", cutoff.Format(time.RFC3339))
+		for _, kind := range []nodetree.Kind{nodetree.Nodes, nodetree.DomainNodes} {
+			var result *nodetree.PurgeResult
+			err := cfg.DB.Transaction(func(tx *gorm.DB) error {
+				var err error
+				result, err = nodetree.PurgeExpired(tx, kind, cutoff)
+				return err
+			})
+			if err != nil {
+				logger.Printf("[CRON] purge %s failed: %v", kind.Table, err)
+				continue
+			}
+			nodetree.RemoveObjects(context.Background(), cfg.Minio, cfg.Bucket, result.Objects)
+			logger.Printf("[CRON] purged %s: %d (objects: %d)", kind.Table, result.Count, len(result.Objects))
+		}
+	})
+	if err != nil {
+		logger.Fatalf("[CRON] failed to register trash purge job: %v", err)
+	}
+
 	c.Start()
 	logger.Printf("[CRON] started (spec=%q, tz=%s)", spec, loc)
 
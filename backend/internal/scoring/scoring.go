@@ -0,0 +1,120 @@
+// Package scoring 对比录音的识别结果与参考文本，计算朗读准确率。
+package scoring
+
+import (
+	"unicode"
+
+	"golang.org/x/text/width"
+
+	"github.com/shuind/language-learner/backend/internal/lang"
+	"github.com/shuind/language-learner/backend/internal/textdiff"
+)
+
+// Result 是一次评分的结果
+type Result struct {
+	Accuracy float64 `json:"accuracy"` // 0-1
+	Matched  int     `json:"matched"`  // 读对的词元数
+	Missed   int     `json:"missed"`   // 参考文本中漏读或读错的词元数
+	Extra    int     `json:"extra"`    // 识别结果中多出的词元数
+}
+
+// Comparison 是逐词对比的明细
+type Comparison struct {
+	Result
+	ReferenceTokens  int      // 参考文本的词元数
+	RecognizedTokens int      // 识别结果的词元数
+	MissingWords     []string // 参考文本中漏读或读错的词元，按出现顺序
+	ExtraWords       []string // 识别结果中多出的词元，按出现顺序
+}
+
+// Score 按词元逐一对比：准确率 = 读对的词元数 / 参考文本与识别结果中较长一方的词元数，
+// 这样漏读和多读都会拉低分数。language 决定切分词元的方式，见 Tokenize。
+func Score(language, reference, recognized string) Result {
+	return Compare(language, reference, recognized).Result
+}
+
+// Compare 与 Score 相同，同时返回漏读和多读的词元
+func Compare(language, reference, recognized string) Comparison {
+	ref, hyp := Tokenize(language, reference), Tokenize(language, recognized)
+	c := Comparison{ReferenceTokens: len(ref), RecognizedTokens: len(hyp)}
+	for _, e := range textdiff.Diff(ref, hyp) {
+		switch e.Kind {
+		case textdiff.OpEqual:
+			c.Matched++
+		case textdiff.OpDelete:
+			c.Missed++
+			c.MissingWords = append(c.MissingWords, e.Token)
+		case textdiff.OpInsert:
+			c.Extra++
+			c.ExtraWords = append(c.ExtraWords, e.Token)
+		}
+	}
+	if total := max(len(ref), len(hyp)); total > 0 {
+		c.Accuracy = float64(c.Matched) / float64(total)
+	}
+	return c
+}
+
+// Quality 把准确率换算为 SM-2 的回忆质量（0-5）
+func (r Result) Quality() int {
+	switch {
+	case r.Accuracy >= 0.95:
+		return 5
+	case r.Accuracy >= 0.85:
+		return 4
+	case r.Accuracy >= 0.7:
+		return 3
+	case r.Accuracy >= 0.5:
+		return 2
+	case r.Accuracy >= 0.25:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Tokenize 把文本切分为评分用的词元，标点和空白被忽略，全角字符先转为半角：
+//   - 中文、日文、韩文等按单字切分（韩文按音节），夹杂的拉丁字母与数字按单词切分；
+//   - 其余语言按单词切分并转为小写，词内的撇号保留（don't），连字符拆开（well-known）；
+//     夹杂的汉字、假名仍按单字切分。未指定语言时同样按此处理。
+func Tokenize(language, s string) []string {
+	s = width.Fold.String(s)
+	characterBased := lang.CharacterBased(language)
+
+	var tokens []string
+	var word []rune
+	flush := func() {
+		// 去掉词首词尾的撇号，它们通常是引号
+		for len(word) > 0 && word[len(word)-1] == '\'' {
+			word = word[:len(word)-1]
+		}
+		for len(word) > 0 && word[0] == '\'' {
+			word = word[1:]
+		}
+		if len(word) > 0 {
+			tokens = append(tokens, string(word))
+		}
+		word = word[:0]
+	}
+	for _, r := range s {
+		if r == '’' {
+			r = '\''
+		}
+		switch {
+		case isIdeographic(r) || characterBased && unicode.Is(unicode.Hangul, r):
+			flush()
+			tokens = append(tokens, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) || r == '\'' && len(word) > 0:
+			word = append(word, unicode.ToLower(r))
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+// isIdeographic 判断字符是否属于按单字书写的文字
+func isIdeographic(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r)
+}
@@ -0,0 +1,64 @@
+package scoring
+
+// Alignment 是跟读时一句录音与示范音频的对比结果
+type Alignment struct {
+	// 识别失败时只有时长对比，Accuracy 为空
+	Accuracy     *float64 `json:"accuracy"`
+	MissingWords []string `json:"missing_words"`
+	ExtraWords   []string `json:"extra_words"`
+
+	ReferenceDurationMs int `json:"reference_duration_ms"` // 0 表示示范音频时长未知
+	AttemptDurationMs   int `json:"attempt_duration_ms"`
+	// DurationRatio = 录音时长 / 示范时长，大于 1 表示读得比示范慢
+	DurationRatio *float64 `json:"duration_ratio"`
+
+	// 语速以每秒词元数计（中文等按字），PaceDiff = 录音语速 / 示范语速 - 1，
+	// 正数表示比示范快，负数表示比示范慢
+	ReferencePace *float64 `json:"reference_pace"`
+	AttemptPace   *float64 `json:"attempt_pace"`
+	PaceDiff      *float64 `json:"pace_diff"`
+}
+
+// Align 对比一句跟读录音：用识别结果与原句比较漏读的词，用时长比较节奏。
+// 示范语速按原句的词元数计算，录音语速按识别出的词元数计算。
+func Align(language, reference, recognized string, referenceMs, attemptMs int) Alignment {
+	c := Compare(language, reference, recognized)
+	a := AlignDuration(referenceMs, attemptMs)
+	a.Accuracy = &c.Accuracy
+	a.MissingWords, a.ExtraWords = nonNil(c.MissingWords), nonNil(c.ExtraWords)
+	if referenceMs > 0 && c.ReferenceTokens > 0 {
+		pace := float64(c.ReferenceTokens) / (float64(referenceMs) / 1000)
+		a.ReferencePace = &pace
+	}
+	if attemptMs > 0 && c.RecognizedTokens > 0 {
+		pace := float64(c.RecognizedTokens) / (float64(attemptMs) / 1000)
+		a.AttemptPace = &pace
+	}
+	if a.ReferencePace != nil && a.AttemptPace != nil {
+		diff := *a.AttemptPace / *a.ReferencePace - 1
+		a.PaceDiff = &diff
+	}
+	return a
+}
+
+// AlignDuration 只比较时长，用于录音无法识别的情况
+func AlignDuration(referenceMs, attemptMs int) Alignment {
+	a := Alignment{
+		MissingWords:        []string{},
+		ExtraWords:          []string{},
+		ReferenceDurationMs: referenceMs,
+		AttemptDurationMs:   attemptMs,
+	}
+	if referenceMs > 0 && attemptMs > 0 {
+		ratio := float64(attemptMs) / float64(referenceMs)
+		a.DurationRatio = &ratio
+	}
+	return a
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
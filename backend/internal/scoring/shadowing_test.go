@@ -0,0 +1,133 @@
+package scoring
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func ptr(v float64) *float64 { return &v }
+
+func equalPtr(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return math.Abs(*a-*b) < 1e-9
+}
+
+func fmtPtr(p *float64) interface{} {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+func TestAlign(t *testing.T) {
+	tests := []struct {
+		name                    string
+		language                string
+		reference, recognized   string
+		referenceMs, attemptMs  int
+		accuracy                float64
+		missing, extra          []string
+		ratio, refPace, attPace *float64
+		paceDiff                *float64
+	}{
+		{
+			name: "perfect at the same pace", language: "en",
+			reference: "I like green tea.", recognized: "i like green tea",
+			referenceMs: 2000, attemptMs: 2000,
+			accuracy: 1, missing: []string{}, extra: []string{},
+			ratio: ptr(1), refPace: ptr(2), attPace: ptr(2), paceDiff: ptr(0),
+		},
+		{
+			name: "missing words read slower", language: "en",
+			reference: "I like green tea.", recognized: "I like tea",
+			referenceMs: 2000, attemptMs: 3000,
+			accuracy: 0.75, missing: []string{"green"}, extra: []string{},
+			ratio: ptr(1.5), refPace: ptr(2), attPace: ptr(1), paceDiff: ptr(-0.5),
+		},
+		{
+			name: "extra words read faster", language: "en",
+			reference: "Good morning.", recognized: "good good morning",
+			referenceMs: 1000, attemptMs: 1000,
+			accuracy: 2.0 / 3, missing: []string{}, extra: []string{"good"},
+			ratio: ptr(1), refPace: ptr(2), attPace: ptr(3), paceDiff: ptr(0.5),
+		},
+		{
+			name: "chinese is paced by character", language: "zh",
+			reference: "今天天气不错。", recognized: "今天天气",
+			referenceMs: 3000, attemptMs: 1000,
+			accuracy: 4.0 / 6, missing: []string{"不", "错"}, extra: []string{},
+			ratio: ptr(1.0 / 3), refPace: ptr(2), attPace: ptr(4), paceDiff: ptr(1),
+		},
+		{
+			name: "unknown reference duration", language: "en",
+			reference: "Hello world.", recognized: "hello world",
+			referenceMs: 0, attemptMs: 1000,
+			accuracy: 1, missing: []string{}, extra: []string{},
+			ratio: nil, refPace: nil, attPace: ptr(2), paceDiff: nil,
+		},
+		{
+			name: "nothing recognised", language: "en",
+			reference: "Hello world.", recognized: "",
+			referenceMs: 1000, attemptMs: 1000,
+			accuracy: 0, missing: []string{"hello", "world"}, extra: []string{},
+			ratio: ptr(1), refPace: ptr(2), attPace: nil, paceDiff: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := Align(tt.language, tt.reference, tt.recognized, tt.referenceMs, tt.attemptMs)
+			if a.Accuracy == nil || math.Abs(*a.Accuracy-tt.accuracy) > 1e-9 {
+				t.Errorf("Accuracy = %v, want %v", fmtPtr(a.Accuracy), tt.accuracy)
+			}
+			if !reflect.DeepEqual(a.MissingWords, tt.missing) {
+				t.Errorf("MissingWords = %q, want %q", a.MissingWords, tt.missing)
+			}
+			if !reflect.DeepEqual(a.ExtraWords, tt.extra) {
+				t.Errorf("ExtraWords = %q, want %q", a.ExtraWords, tt.extra)
+			}
+			if a.ReferenceDurationMs != tt.referenceMs || a.AttemptDurationMs != tt.attemptMs {
+				t.Errorf("durations = %d/%d, want %d/%d", a.ReferenceDurationMs, a.AttemptDurationMs, tt.referenceMs, tt.attemptMs)
+			}
+			for _, f := range []struct {
+				name      string
+				got, want *float64
+			}{
+				{"DurationRatio", a.DurationRatio, tt.ratio},
+				{"ReferencePace", a.ReferencePace, tt.refPace},
+				{"AttemptPace", a.AttemptPace, tt.attPace},
+				{"PaceDiff", a.PaceDiff, tt.paceDiff},
+			} {
+				if !equalPtr(f.got, f.want) {
+					t.Errorf("%s = %v, want %v", f.name, fmtPtr(f.got), fmtPtr(f.want))
+				}
+			}
+		})
+	}
+}
+
+func TestAlignDuration(t *testing.T) {
+	tests := []struct {
+		referenceMs, attemptMs int
+		ratio                  *float64
+	}{
+		{2000, 1000, ptr(0.5)},
+		{1000, 2500, ptr(2.5)},
+		{0, 1000, nil},
+		{1000, 0, nil},
+	}
+	for _, tt := range tests {
+		a := AlignDuration(tt.referenceMs, tt.attemptMs)
+		if !equalPtr(a.DurationRatio, tt.ratio) {
+			t.Errorf("AlignDuration(%d, %d).DurationRatio = %v, want %v", tt.referenceMs, tt.attemptMs, fmtPtr(a.DurationRatio), fmtPtr(tt.ratio))
+		}
+		if a.Accuracy != nil || a.PaceDiff != nil {
+			t.Errorf("AlignDuration(%d, %d) should only compare durations", tt.referenceMs, tt.attemptMs)
+		}
+		if a.MissingWords == nil || a.ExtraWords == nil {
+			t.Errorf("AlignDuration(%d, %d) word lists should be empty, not nil", tt.referenceMs, tt.attemptMs)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package search
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+
+	"github.com/shuind/language-learner/backend/internal/model"
+)
+
+// backfillBatchSize 是补建索引时每批处理的记录数
+const backfillBatchSize = 200
+
+// missing 限定到尚未写入索引的记录
+func missing(sourceType, table string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("NOT EXISTS (SELECT 1 FROM search_documents d WHERE d.source_type = ? AND d.source_id = "+table+".id)", sourceType)
+	}
+}
+
+// Backfill 为还没有索引的已有数据补建索引，启动时在后台调用
+// 已经写入的记录会被跳过，所以重复执行是安全的。
+func Backfill(db *gorm.DB) error {
+	var indexed int
+
+	var nodes []model.Node
+	if err := db.Scopes(missing(model.SearchSourceNode, "nodes")).FindInBatches(&nodes, backfillBatchSize, func(tx *gorm.DB, _ int) error {
+		for i := range nodes {
+			if err := IndexNode(db, &nodes[i]); err != nil {
+				return err
+			}
+		}
+		indexed += len(nodes)
+		return nil
+	}).Error; err != nil {
+		return err
+	}
+
+	var domainNodes []model.DomainNode
+	if err := db.Scopes(missing(model.SearchSourceDomainNode, "domain_nodes")).FindInBatches(&domainNodes, backfillBatchSize, func(tx *gorm.DB, _ int) error {
+		for i := range domainNodes {
+			if err := IndexDomainNode(db, &domainNodes[i]); err != nil {
+				return err
+			}
+		}
+		indexed += len(domainNodes)
+		return nil
+	}).Error; err != nil {
+		return err
+	}
+
+	var texts []model.Text
+	if err := db.Scopes(missing(model.SearchSourceText, "texts")).FindInBatches(&texts, backfillBatchSize, func(tx *gorm.DB, _ int) error {
+		for i := range texts {
+			if err := IndexText(db, &texts[i]); err != nil {
+				return err
+			}
+		}
+		indexed += len(texts)
+		return nil
+	}).Error; err != nil {
+		return err
+	}
+
+	var posts []model.Post
+	if err := db.Scopes(missing(model.SearchSourcePost, "posts")).FindInBatches(&posts, backfillBatchSize, func(tx *gorm.DB, _ int) error {
+		for i := range posts {
+			if err := IndexPost(db, &posts[i]); err != nil {
+				return err
+			}
+		}
+		indexed += len(posts)
+		return nil
+	}).Error; err != nil {
+		return err
+	}
+
+	if indexed > 0 {
+		log.Printf("Search index backfilled %d documents", indexed)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package search
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/shuind/language-learner/backend/internal/model"
+	"github.com/shuind/language-learner/backend/internal/nodecontent"
+)
+
+const (
+	// maxPosition 是 tsvector 允许的最大位置
+	maxPosition = 16383
+	// maxPositionsPerLexeme 是 tsvector 中每个词元最多保存的位置数
+	maxPositionsPerLexeme = 256
+)
+
+// Document 是写入索引的一条内容
+type Document struct {
+	SourceType string
+	SourceID   uint
+	OwnerID    *uint
+	DomainID   *uint
+	Title      string
+	Body       string
+}
+
+// Index 写入或更新一条索引，应与来源数据的写入处于同一事务中
+func Index(tx *gorm.DB, doc Document) error {
+	return tx.Exec(`
+		INSERT INTO search_documents (source_type, source_id, owner_id, domain_id, title, body, tokens, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?::tsvector, NOW())
+		ON CONFLICT (source_type, source_id) DO UPDATE SET
+			owner_id = EXCLUDED.owner_id,
+			domain_id = EXCLUDED.domain_id,
+			title = EXCLUDED.title,
+			body = EXCLUDED.body,
+			tokens = EXCLUDED.tokens,
+			updated_at = EXCLUDED.updated_at`,
+		doc.SourceType, doc.SourceID, doc.OwnerID, doc.DomainID, doc.Title, doc.Body, buildVector(doc.Title, doc.Body),
+	).Error
+}
+
+// Remove 删除指定来源的索引（例如节点被彻底清除时）
+func Remove(tx *gorm.DB, sourceType string, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return tx.Where("source_type = ? AND source_id IN ?", sourceType, ids).Delete(&model.SearchDocument{}).Error
+}
+
+// IndexNode 索引个人节点
+func IndexNode(tx *gorm.DB, n *model.Node) error {
+	owner := n.UserID
+	return Index(tx, Document{
+		SourceType: model.SearchSourceNode,
+		SourceID:   n.ID,
+		OwnerID:    &owner,
+		Title:      n.Title,
+		Body:       nodecontent.PlainText(n.NodeType, n.Content),
+	})
+}
+
+// IndexDomainNode 索引圈子节点
+func IndexDomainNode(tx *gorm.DB, n *model.DomainNode) error {
+	domain := n.DomainID
+	return Index(tx, Document{
+		SourceType: model.SearchSourceDomainNode,
+		SourceID:   n.ID,
+		DomainID:   &domain,
+		Title:      n.Title,
+		Body:       nodecontent.PlainText(n.NodeType, n.Content),
+	})
+}
+
+// IndexText 索引公共文本库中的文本
+func IndexText(tx *gorm.DB, t *model.Text) error {
+	return Index(tx, Document{
+		SourceType: model.SearchSourceText,
+		SourceID:   t.ID,
+		Title:      t.Title,
+		Body:       t.Content,
+	})
+}
+
+// IndexPost 索引论坛帖子；草稿也会写入索引，但只有作者本人能搜到
+func IndexPost(tx *gorm.DB, p *model.Post) error {
+	owner := p.UserID
+	return Index(tx, Document{
+		SourceType: model.SearchSourcePost,
+		SourceID:   p.ID,
+		OwnerID:    &owner,
+		Title:      p.Title,
+		Body:       stripTags(p.Content),
+	})
+}
+
+var tagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// stripTags 去掉富文本中的 HTML 标签，只保留可搜索的文字
+func stripTags(s string) string {
+	if !strings.Contains(s, "<") {
+		return s
+	}
+	return tagPattern.ReplaceAllString(s, " ")
+}
+
+// buildVector 生成 tsvector 的文本表示，如 'foo':1A 'bar':2A,5
+// 标题词元带 A 权重以提高排序；位置连续递增，使中文二元词元可以用短语运算符 <-> 匹配。
+func buildVector(title, body string) string {
+	positions := make(map[string][]string)
+	pos := 0
+	add := func(text, weight string) {
+		for _, slot := range slots(text) {
+			if pos < maxPosition {
+				pos++
+			}
+			for _, tok := range slot {
+				if len(positions[tok]) < maxPositionsPerLexeme {
+					positions[tok] = append(positions[tok], fmt.Sprintf("%d%s", pos, weight))
+				}
+			}
+		}
+	}
+	add(title, "A")
+	pos++ // 标题与正文之间留空，避免跨越两者的短语匹配
+	add(body, "")
+
+	lexemes := make([]string, 0, len(positions))
+	for tok := range positions {
+		lexemes = append(lexemes, tok)
+	}
+	sort.Strings(lexemes)
+
+	var b strings.Builder
+	for i, tok := range lexemes {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(quoteLexeme(tok))
+		b.WriteByte(':')
+		b.WriteString(strings.Join(positions[tok], ","))
+	}
+	return b.String()
+}
+
+// quoteLexeme 按 tsvector/tsquery 的语法给词元加引号
+func quoteLexeme(tok string) string {
+	tok = strings.ReplaceAll(tok, `\`, `\\`)
+	tok = strings.ReplaceAll(tok, `'`, `''`)
+	return "'" + tok + "'"
+}
+
+// buildQuery 把用户输入转换为 tsquery 文本，无可用词元时返回空字符串
+// 中文片段的二元词元之间用 <-> 要求相邻，各片段、各拉丁词之间用 & 连接；
+// 最后一个拉丁词按前缀匹配，便于边输入边搜索。
+func buildQuery(q string) string {
+	segs := segments(q)
+	parts := make([]string, 0, len(segs))
+	for i, seg := range segs {
+		if seg.cjk {
+			tokens := bigrams(seg.runes)
+			quoted := make([]string, len(tokens))
+			for j, tok := range tokens {
+				quoted[j] = quoteLexeme(tok)
+			}
+			if len(quoted) == 1 {
+				parts = append(parts, quoted[0])
+			} else {
+				parts = append(parts, "("+strings.Join(quoted, " <-> ")+")")
+			}
+			continue
+		}
+		lexeme := quoteLexeme(string(seg.runes))
+		if i == len(segs)-1 {
+			lexeme += ":*"
+		}
+		parts = append(parts, lexeme)
+	}
+	return strings.Join(parts, " & ")
+}
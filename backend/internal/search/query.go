@@ -0,0 +1,208 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+
+	"github.com/shuind/language-learner/backend/internal/model"
+)
+
+const (
+	// snippetRunes 是摘要的目标长度（字符数）
+	snippetRunes = 120
+	// snippetLead 是摘要中第一处命中之前保留的上下文长度
+	snippetLead = 30
+)
+
+// AllTypes 是可以搜索的全部来源类型
+var AllTypes = []string{
+	model.SearchSourceNode,
+	model.SearchSourceDomainNode,
+	model.SearchSourceText,
+	model.SearchSourcePost,
+}
+
+// Query 是一次搜索请求
+type Query struct {
+	UserID uint
+	Text   string
+	Types  []string // 为空时搜索全部类型
+	Page   int
+	Limit  int
+	// Scope 可追加额外的筛选条件，例如按标签限定个人节点（d.source_id 为来源 ID）
+	Scope func(*gorm.DB) *gorm.DB
+}
+
+// Result 是一条搜索结果
+type Result struct {
+	Type      string    `json:"type"`
+	ID        uint      `json:"id"`
+	DomainID  *uint     `json:"domain_id,omitempty"`
+	Title     string    `json:"title"`
+	Snippet   string    `json:"snippet"` // 已做 HTML 转义，命中的词用 <mark> 包裹
+	Rank      float64   `json:"rank"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// hit 是数据库返回的原始行
+type hit struct {
+	SourceType string
+	SourceID   uint
+	DomainID   *uint
+	Title      string
+	Body       string
+	Rank       float64
+	UpdatedAt  time.Time
+}
+
+// Search 执行搜索，返回当前页结果与命中总数
+// 可见范围：自己的个人节点、所加入圈子的节点、公共文本、已发布的帖子以及自己的草稿。
+// 软删除的来源在这里被过滤掉，因此删除与回收站恢复不需要同步索引。
+func Search(db *gorm.DB, q Query) ([]Result, int64, error) {
+	results := make([]Result, 0)
+	tsquery := buildQuery(q.Text)
+	if tsquery == "" {
+		return results, 0, nil
+	}
+	types := q.Types
+	if len(types) == 0 {
+		types = AllTypes
+	}
+
+	base := db.Table("search_documents AS d").
+		Where("d.tokens @@ ?::tsquery", tsquery).
+		Where("d.source_type IN ?", types).
+		Where(`(
+			(d.source_type = 'node' AND d.owner_id = ? AND EXISTS (
+				SELECT 1 FROM nodes n WHERE n.id = d.source_id AND n.deleted_at IS NULL))
+			OR (d.source_type = 'domain_node' AND EXISTS (
+				SELECT 1 FROM domain_nodes n JOIN domain_members m ON m.domain_id = n.domain_id
+				WHERE n.id = d.source_id AND n.deleted_at IS NULL AND m.user_id = ?))
+			OR (d.source_type = 'text' AND EXISTS (
+				SELECT 1 FROM texts t WHERE t.id = d.source_id AND t.deleted_at IS NULL))
+			OR (d.source_type = 'post' AND EXISTS (
+				SELECT 1 FROM posts p WHERE p.id = d.source_id AND p.deleted_at IS NULL
+				AND (p.status = 'published' OR p.user_id = ?)))
+		)`, q.UserID, q.UserID, q.UserID)
+	if q.Scope != nil {
+		base = base.Scopes(q.Scope)
+	}
+
+	var total int64
+	if err := base.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if total == 0 {
+		return results, 0, nil
+	}
+
+	var hits []hit
+	err := base.Session(&gorm.Session{}).
+		Select("d.source_type, d.source_id, d.domain_id, d.title, d.body, d.updated_at, ts_rank(d.tokens, ?::tsquery) AS rank", tsquery).
+		Order("rank DESC, d.updated_at DESC").
+		Offset((q.Page - 1) * q.Limit).
+		Limit(q.Limit).
+		Scan(&hits).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	terms := highlightTerms(q.Text)
+	for _, h := range hits {
+		results = append(results, Result{
+			Type:      h.SourceType,
+			ID:        h.SourceID,
+			DomainID:  h.DomainID,
+			Title:     h.Title,
+			Snippet:   Snippet(h.Body, terms),
+			Rank:      h.Rank,
+			UpdatedAt: h.UpdatedAt,
+		})
+	}
+	return results, total, nil
+}
+
+// highlightTerms 返回摘要中需要高亮的词：拉丁词与完整的中文片段，
+// 中文片段同时附带其二元词，整段未连续出现时仍能标出部分命中。
+func highlightTerms(q string) [][]rune {
+	var terms [][]rune
+	for _, seg := range segments(q) {
+		terms = append(terms, seg.runes)
+		if seg.cjk && len(seg.runes) > 2 {
+			for _, bg := range bigrams(seg.runes) {
+				terms = append(terms, []rune(bg))
+			}
+		}
+	}
+	return terms
+}
+
+// Snippet 从正文中截取包含第一处命中的片段，并用 <mark> 标出所有命中
+func Snippet(body string, terms [][]rune) string {
+	text := []rune(body)
+	lower := make([]rune, len(text))
+	for i, r := range text {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	marked := make([]bool, len(text))
+	first := -1
+	for _, term := range terms {
+		for i := 0; i+len(term) <= len(lower); i++ {
+			if !hasPrefixAt(lower, term, i) {
+				continue
+			}
+			for j := i; j < i+len(term); j++ {
+				marked[j] = true
+			}
+			if first < 0 || i < first {
+				first = i
+			}
+		}
+	}
+
+	start := 0
+	if first > snippetLead {
+		start = first - snippetLead
+	}
+	end := start + snippetRunes
+	if end > len(text) {
+		end = len(text)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	inMark := false
+	for i := start; i < end; i++ {
+		if marked[i] && !inMark {
+			b.WriteString("<mark>")
+			inMark = true
+		} else if !marked[i] && inMark {
+			b.WriteString("</mark>")
+			inMark = false
+		}
+		b.WriteString(html.EscapeString(string(text[i])))
+	}
+	if inMark {
+		b.WriteString("</mark>")
+	}
+	if end < len(text) {
+		b.WriteString("…")
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func hasPrefixAt(s, prefix []rune, at int) bool {
+	for j, r := range prefix {
+		if s[at+j] != r {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,98 @@
+// Package search 实现站内全文搜索：Go 端分词（中日韩文字使用单字 + 二元切分），
+// 词元写入 search_documents 表的 tsvector 列，由 Postgres 的 GIN 索引负责匹配与排序。
+package search
+
+import (
+	"unicode"
+)
+
+// maxTokenRunes 限制单个拉丁词元的长度，避免超长的无意义字符串进入索引
+const maxTokenRunes = 64
+
+// isCJK 判断字符是否属于需要按字切分的文字（汉字、假名、谚文）
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r)
+}
+
+// isWordRune 判断字符是否属于拉丁等以空格分词的文字
+func isWordRune(r rune) bool {
+	return (unicode.IsLetter(r) || unicode.IsDigit(r)) && !isCJK(r)
+}
+
+// segment 是一段连续的同类文字：一个拉丁词，或一段不间断的中日韩文字
+type segment struct {
+	runes []rune // 已转为小写
+	cjk   bool
+}
+
+// segments 把文本切分为拉丁词与中日韩片段，标点和空白作为分隔符丢弃
+func segments(s string) []segment {
+	var segs []segment
+	var cur []rune
+	curCJK := false
+	flush := func() {
+		if len(cur) > 0 {
+			if !curCJK && len(cur) > maxTokenRunes {
+				cur = cur[:maxTokenRunes]
+			}
+			segs = append(segs, segment{runes: cur, cjk: curCJK})
+			cur = nil
+		}
+	}
+	for _, r := range s {
+		switch {
+		case isCJK(r):
+			if !curCJK {
+				flush()
+			}
+			curCJK = true
+			cur = append(cur, r)
+		case isWordRune(r):
+			if curCJK {
+				flush()
+			}
+			curCJK = false
+			cur = append(cur, unicode.ToLower(r))
+		default:
+			flush()
+		}
+	}
+	flush()
+	return segs
+}
+
+// slots 返回索引用的词元，每个位置可能有多个词元
+// 中日韩片段的第 i 个位置同时包含单字 c[i] 与二元词 c[i]c[i+1]：
+// 多字查询用相邻的二元词做短语匹配，单字查询直接命中单字词元。
+func slots(s string) [][]string {
+	var out [][]string
+	for _, seg := range segments(s) {
+		if !seg.cjk {
+			out = append(out, []string{string(seg.runes)})
+			continue
+		}
+		for i := range seg.runes {
+			slot := []string{string(seg.runes[i])}
+			if i+1 < len(seg.runes) {
+				slot = append(slot, string(seg.runes[i:i+2]))
+			}
+			out = append(out, slot)
+		}
+	}
+	return out
+}
+
+// bigrams 返回中日韩片段的查询词元：单字片段返回单字，否则返回相邻二元词
+func bigrams(runes []rune) []string {
+	if len(runes) == 1 {
+		return []string{string(runes)}
+	}
+	out := make([]string, 0, len(runes)-1)
+	for i := 0; i+1 < len(runes); i++ {
+		out = append(out, string(runes[i:i+2]))
+	}
+	return out
+}
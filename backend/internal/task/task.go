@@ -16,3 +16,23 @@ type AudioProcessingPayload struct {
 	ContentType string `json:"content_type"`
 	FileExt     string `json:"file_ext"`
 }
+
+// AudioProcessingQueue 是处理上传音频的任务队列：录音与语音反馈
+const AudioProcessingQueue = "audio_processing"
+
+// VoiceFeedbackTask 请求 worker 上传一条语音反馈的音频并更新对应的评论。
+// 与录音任务共用 AudioProcessingQueue，worker 以 CommentID 是否为 0 区分两者
+type VoiceFeedbackTask struct {
+	RecordingID uint   `json:"recording_id"`
+	CommentID   uint   `json:"comment_id"`
+	FileContent []byte `json:"file_content"`
+	ContentType string `json:"content_type"`
+}
+
+// ReferenceAudioQueue 是生成朗读示范音频的任务队列
+const ReferenceAudioQueue = "tts_generation"
+
+// ReferenceAudioTask 请求 worker 为一条 model.ReferenceAudio 合成音频
+type ReferenceAudioTask struct {
+	ReferenceAudioID uint `json:"reference_audio_id"`
+}
@@ -0,0 +1,60 @@
+// Package testdb 为需要数据库的测试提供一个独立的 PostgreSQL schema。
+// 测试数据库由 TEST_DATABASE_URL 指定（格式同 DATABASE_URL），未设置时跳过测试。
+package testdb
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Open 创建一个临时 schema 并迁移 models，测试结束后删除该 schema
+func Open(t testing.TB, models ...interface{}) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	config := &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}
+
+	admin, err := gorm.Open(postgres.Open(dsn), config)
+	if err != nil {
+		t.Fatalf("connect test database: %v", err)
+	}
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+
+	// search_path 作为连接参数传给服务器，同一连接池中的每个连接都使用这个 schema
+	sep := " "
+	if strings.Contains(dsn, "://") {
+		sep = "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+	}
+	db, err := gorm.Open(postgres.Open(dsn+sep+"search_path="+schema), config)
+	if err != nil {
+		t.Fatalf("connect test schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
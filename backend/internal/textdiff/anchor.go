@@ -0,0 +1,109 @@
+package textdiff
+
+import "strings"
+
+// OffsetMap 把旧文本中的字符位置（按 rune 计）映射到新文本中，用于修改内容后重新定位标注
+type OffsetMap struct {
+	newPos []int  // newPos[i] 是旧文本第 i 个字符在新文本中的位置；被删除时为其原本所在的位置
+	kept   []bool // kept[i] 表示旧文本第 i 个字符在新文本中仍然存在
+}
+
+// NewOffsetMap 逐字对比两段文本，建立位置映射
+func NewOffsetMap(oldText, newText string) *OffsetMap {
+	a, b := splitRunes(oldText), splitRunes(newText)
+	m := &OffsetMap{newPos: make([]int, len(a)+1), kept: make([]bool, len(a))}
+
+	var edits []Edit
+	if len(a)+len(b) > maxEditDistance {
+		edits = diffRunesByLine(oldText, newText)
+	} else {
+		edits = Diff(a, b)
+	}
+	i, j := 0, 0
+	for _, e := range edits {
+		switch e.Kind {
+		case OpEqual:
+			m.newPos[i], m.kept[i] = j, true
+			i++
+			j++
+		case OpDelete:
+			m.newPos[i] = j
+			i++
+		case OpInsert:
+			j++
+		}
+	}
+	m.newPos[len(a)] = j
+	return m
+}
+
+// Range 映射半开区间 [start, end)：取区间内第一个和最后一个仍然存在的字符，
+// 区间内新插入的文字会被包含进来。区间内的字符全部被删除时 ok 为 false。
+func (m *OffsetMap) Range(start, end int) (newStart, newEnd int, ok bool) {
+	if start < 0 || end > len(m.kept) || start >= end {
+		return 0, 0, false
+	}
+	first, last := -1, -1
+	for i := start; i < end; i++ {
+		if m.kept[i] {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first < 0 {
+		return 0, 0, false
+	}
+	return m.newPos[first], m.newPos[last] + 1, true
+}
+
+// diffRunesByLine 用于较长的文本：先逐行对比，再只在改动的行块内逐字对比，
+// 这样编辑距离按改动的行块计算，大段粘贴不会让整篇文本退化为整体替换
+func diffRunesByLine(oldText, newText string) []Edit {
+	var edits []Edit
+	var deleted, inserted strings.Builder
+	flush := func() {
+		if deleted.Len() > 0 || inserted.Len() > 0 {
+			edits = append(edits, Diff(splitRunes(deleted.String()), splitRunes(inserted.String()))...)
+			deleted.Reset()
+			inserted.Reset()
+		}
+	}
+	for _, e := range Diff(splitLinesKeepEnds(oldText), splitLinesKeepEnds(newText)) {
+		switch e.Kind {
+		case OpEqual:
+			flush()
+			for _, r := range e.Token {
+				edits = append(edits, Edit{Kind: OpEqual, Token: string(r)})
+			}
+		case OpDelete:
+			deleted.WriteString(e.Token)
+		case OpInsert:
+			inserted.WriteString(e.Token)
+		}
+	}
+	flush()
+	return edits
+}
+
+// splitLinesKeepEnds 按行切分并保留换行符，拼接回去与原文完全一致
+func splitLinesKeepEnds(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func splitRunes(s string) []string {
+	runes := []rune(s)
+	tokens := make([]string, len(runes))
+	for i, r := range runes {
+		tokens[i] = string(r)
+	}
+	return tokens
+}
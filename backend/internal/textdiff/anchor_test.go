@@ -0,0 +1,46 @@
+package textdiff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOffsetMapShortText(t *testing.T) {
+	m := NewOffsetMap("hello world", "hello brave world")
+	start, end, ok := m.Range(6, 11) // "world"
+	if !ok || start != 12 || end != 17 {
+		t.Fatalf("Range(6, 11) = %d, %d, %v; want 12, 17, true", start, end, ok)
+	}
+	if _, _, ok := NewOffsetMap("abc", "xyz").Range(0, 3); ok {
+		t.Fatal("range of fully replaced text should not be kept")
+	}
+}
+
+func TestOffsetMapLongTextUsesLineDiff(t *testing.T) {
+	// 超过 maxEditDistance 的文本：中间粘贴一大段，后面的标注仍然能定位
+	var old strings.Builder
+	for i := 0; i < 200; i++ {
+		old.WriteString("line of text number\n")
+	}
+	oldText := old.String() + "anchor here\n"
+	paste := strings.Repeat("pasted content\n", 300)
+	newText := old.String()[:20*100] + paste + old.String()[20*100:] + "anchor here\n"
+
+	m := NewOffsetMap(oldText, newText)
+	anchor := len([]rune(old.String()))
+	start, end, ok := m.Range(anchor, anchor+6)
+	if !ok {
+		t.Fatal("anchor after the pasted block was lost")
+	}
+	if got := string([]rune(newText)[start:end]); got != "anchor" {
+		t.Fatalf("anchor mapped to %q", got)
+	}
+}
+
+func TestSplitLinesKeepEnds(t *testing.T) {
+	for _, s := range []string{"", "a", "a\n", "a\nb", "a\n\nb\n"} {
+		if got := strings.Join(splitLinesKeepEnds(s), ""); got != s {
+			t.Errorf("splitLinesKeepEnds(%q) joined = %q", s, got)
+		}
+	}
+}
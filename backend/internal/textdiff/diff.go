@@ -0,0 +1,258 @@
+// Package textdiff 提供基于 Myers 算法的文本差异计算，
+// 用于节点内容的修订历史（逐行对比）以及标注锚点的重新定位（逐字对比）。
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+type OpKind string
+
+const (
+	OpEqual  OpKind = "equal"
+	OpInsert OpKind = "insert"
+	OpDelete OpKind = "delete"
+)
+
+// Edit 是编辑脚本中的一步，Token 为一行或一个字符
+type Edit struct {
+	Kind  OpKind
+	Token string
+}
+
+// maxEditDistance 限制 Myers 算法的搜索深度，超过后退化为“整体替换”。
+// trace 占用约 D^2 个 int，1000 时最多约 8MB；保存路径上每次修改都会计算差异，不能放得太大
+const maxEditDistance = 1000
+
+// Diff 计算把 a 变成 b 的最短编辑脚本
+func Diff(a, b []string) []Edit {
+	// 1. 先去掉公共前缀和后缀，绝大多数修改只涉及很小的区间
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	edits := make([]Edit, 0, len(a)+len(b))
+	for _, t := range a[:prefix] {
+		edits = append(edits, Edit{Kind: OpEqual, Token: t})
+	}
+	edits = append(edits, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, t := range a[len(a)-suffix:] {
+		edits = append(edits, Edit{Kind: OpEqual, Token: t})
+	}
+	return edits
+}
+
+// myers 是 Myers O(ND) 差分算法，trace 只保存每一轮 [-d, d] 区间，内存为 O(D^2)
+func myers(a, b []string) []Edit {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil
+	}
+
+	max := n + m
+	if max > maxEditDistance {
+		max = maxEditDistance
+	}
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+
+	for d := 0; d <= max; d++ {
+		snapshot := make([]int, 2*d+1)
+		copy(snapshot, v[offset-d:offset+d+1])
+		trace = append(trace, snapshot)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b)
+			}
+		}
+	}
+
+	// 编辑距离过大：整体删除再整体插入
+	edits := make([]Edit, 0, n+m)
+	for _, t := range a {
+		edits = append(edits, Edit{Kind: OpDelete, Token: t})
+	}
+	for _, t := range b {
+		edits = append(edits, Edit{Kind: OpInsert, Token: t})
+	}
+	return edits
+}
+
+func backtrack(trace [][]int, a, b []string) []Edit {
+	x, y := len(a), len(b)
+	var reversed []Edit
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		snapshot := trace[d]
+		at := func(k int) int { return snapshot[k+d] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := 0
+		if d > 0 {
+			prevX = at(prevK)
+		}
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			reversed = append(reversed, Edit{Kind: OpEqual, Token: a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, Edit{Kind: OpInsert, Token: b[y-1]})
+			} else {
+				reversed = append(reversed, Edit{Kind: OpDelete, Token: a[x-1]})
+			}
+			x, y = prevX, prevY
+		}
+	}
+
+	edits := make([]Edit, len(reversed))
+	for i, e := range reversed {
+		edits[len(reversed)-1-i] = e
+	}
+	return edits
+}
+
+// SplitLines 按行切分文本（不保留换行符）
+func SplitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+}
+
+// Line 是逐行对比结果中的一行，OldLine/NewLine 从 1 开始，0 表示该侧不存在
+type Line struct {
+	Op      OpKind `json:"op"`
+	Text    string `json:"text"`
+	OldLine int    `json:"old_line,omitempty"`
+	NewLine int    `json:"new_line,omitempty"`
+}
+
+// Stats 汇总一次修改增删的行数
+type Stats struct {
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+}
+
+// LineDiff 逐行对比两段文本
+func LineDiff(oldText, newText string) ([]Line, Stats) {
+	edits := Diff(SplitLines(oldText), SplitLines(newText))
+	lines := make([]Line, 0, len(edits))
+	var stats Stats
+	oldNo, newNo := 0, 0
+	for _, e := range edits {
+		switch e.Kind {
+		case OpEqual:
+			oldNo++
+			newNo++
+			lines = append(lines, Line{Op: OpEqual, Text: e.Token, OldLine: oldNo, NewLine: newNo})
+		case OpDelete:
+			oldNo++
+			stats.Removed++
+			lines = append(lines, Line{Op: OpDelete, Text: e.Token, OldLine: oldNo})
+		case OpInsert:
+			newNo++
+			stats.Added++
+			lines = append(lines, Line{Op: OpInsert, Text: e.Token, NewLine: newNo})
+		}
+	}
+	return lines, stats
+}
+
+// Unified 生成 unified diff 格式的文本，context 为每个改动块前后保留的行数
+func Unified(oldText, newText string, context int) string {
+	lines, stats := LineDiff(oldText, newText)
+	if stats.Added == 0 && stats.Removed == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(lines); {
+		// 找到下一个改动
+		if lines[i].Op == OpEqual {
+			i++
+			continue
+		}
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		// 向后扩展，直到连续 2*context 行都没有改动
+		end := i
+		for end < len(lines) {
+			if lines[end].Op != OpEqual {
+				end++
+				continue
+			}
+			run := end
+			for run < len(lines) && lines[run].Op == OpEqual {
+				run++
+			}
+			if run == len(lines) || run-end > 2*context {
+				end += min(context, run-end)
+				break
+			}
+			end = run
+		}
+
+		oldStart, newStart, oldCount, newCount := 0, 0, 0, 0
+		for _, l := range lines[start:end] {
+			if l.OldLine != 0 {
+				if oldStart == 0 {
+					oldStart = l.OldLine
+				}
+				oldCount++
+			}
+			if l.NewLine != 0 {
+				if newStart == 0 {
+					newStart = l.NewLine
+				}
+				newCount++
+			}
+		}
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+		for _, l := range lines[start:end] {
+			switch l.Op {
+			case OpEqual:
+				sb.WriteString(" ")
+			case OpDelete:
+				sb.WriteString("-")
+			case OpInsert:
+				sb.WriteString("+")
+			}
+			sb.WriteString(l.Text)
+			sb.WriteString("\n")
+		}
+		i = end
+	}
+	return sb.String()
+}
@@ -0,0 +1,71 @@
+package tts
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+// Request 是 Fake 收到的一次合成请求
+type Request struct {
+	Text     string
+	Language string
+	Voice    string
+}
+
+// Fake 不依赖任何服务，返回一段静音 WAV，时长随字数增长；用于测试与本地开发。
+// Err 不为空时每次调用都返回该错误。
+type Fake struct {
+	Err error
+
+	mu       sync.Mutex
+	requests []Request
+}
+
+// fakeSampleRate 是静音 WAV 的采样率（8kHz、8 位单声道）
+const fakeSampleRate = 8000
+
+func (f *Fake) Synthesize(ctx context.Context, text, language, voice string) (*Audio, error) {
+	f.mu.Lock()
+	f.requests = append(f.requests, Request{Text: text, Language: language, Voice: voice})
+	f.mu.Unlock()
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	// 每个字符约 0.1 秒
+	samples := fakeSampleRate / 10 * max(len([]rune(text)), 1)
+	return &Audio{
+		Data:        silentWAV(samples),
+		ContentType: "audio/wav",
+		Duration:    time.Duration(samples) * time.Second / fakeSampleRate,
+	}, nil
+}
+
+// Requests 返回目前为止收到的请求
+func (f *Fake) Requests() []Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Request(nil), f.requests...)
+}
+
+// silentWAV 生成指定采样数的 8 位单声道 PCM 静音
+func silentWAV(samples int) []byte {
+	buf := make([]byte, 44+samples)
+	copy(buf[0:], "RIFF")
+	binary.LittleEndian.PutUint32(buf[4:], uint32(36+samples))
+	copy(buf[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(buf[16:], 16)             // fmt 块大小
+	binary.LittleEndian.PutUint16(buf[20:], 1)              // PCM
+	binary.LittleEndian.PutUint16(buf[22:], 1)              // 单声道
+	binary.LittleEndian.PutUint32(buf[24:], fakeSampleRate) // 采样率
+	binary.LittleEndian.PutUint32(buf[28:], fakeSampleRate) // 字节率
+	binary.LittleEndian.PutUint16(buf[32:], 1)              // 块对齐
+	binary.LittleEndian.PutUint16(buf[34:], 8)              // 位深
+	copy(buf[36:], "data")
+	binary.LittleEndian.PutUint32(buf[40:], uint32(samples))
+	for i := 44; i < len(buf); i++ {
+		buf[i] = 0x80 // 8 位 PCM 的零点
+	}
+	return buf
+}
@@ -0,0 +1,114 @@
+// Package tts 封装文本转语音服务，用于生成朗读示范音频。
+package tts
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Audio 是合成的音频
+type Audio struct {
+	Data        []byte
+	ContentType string
+	Duration    time.Duration // 未知时为 0
+}
+
+// Synthesizer 把文字合成为语音
+// language 是 BCP 47 语言标签，voice 为空时使用服务端的默认音色。
+type Synthesizer interface {
+	Synthesize(ctx context.Context, text, language, voice string) (*Audio, error)
+}
+
+// CacheKey 返回合成结果的缓存键：相同的文字、语言与音色总是得到相同的音频
+func CacheKey(text, language, voice string) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{"v1", language, voice, text}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Extension 返回音频格式对应的文件扩展名
+func Extension(contentType string) string {
+	switch strings.TrimSpace(strings.Split(contentType, ";")[0]) {
+	case "audio/mpeg", "audio/mp3":
+		return "mp3"
+	case "audio/ogg":
+		return "ogg"
+	case "audio/webm":
+		return "webm"
+	default:
+		return "wav"
+	}
+}
+
+// FromEnv 按环境变量选择实现：TTS_PROVIDER=fake 时使用 Fake（本地开发），
+// 否则调用 TTS_API_URL 指向的 HTTP 服务
+func FromEnv() Synthesizer {
+	if os.Getenv("TTS_PROVIDER") == "fake" {
+		return &Fake{}
+	}
+	url := os.Getenv("TTS_API_URL")
+	if url == "" {
+		url = "http://host.docker.internal:5002/synthesize"
+	}
+	return NewHTTPSynthesizer(url)
+}
+
+// HTTPSynthesizer 调用本地部署的 TTS 服务：
+// POST JSON {"text", "language", "voice"}，响应体即为音频，格式由 Content-Type 给出
+type HTTPSynthesizer struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewHTTPSynthesizer 创建一个 HTTP 合成客户端，长文本合成较慢，超时设为 120 秒
+func NewHTTPSynthesizer(url string) *HTTPSynthesizer {
+	return &HTTPSynthesizer{URL: url, Client: &http.Client{Timeout: 120 * time.Second}}
+}
+
+func (s *HTTPSynthesizer) Synthesize(ctx context.Context, text, language, voice string) (*Audio, error) {
+	body, err := json.Marshal(map[string]string{"text": text, "language": language, "voice": voice})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call TTS service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TTS response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("TTS service returned an error. Status: %d, Body: %s", resp.StatusCode, string(data))
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("TTS service returned empty audio")
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "audio/") {
+		contentType = "audio/wav"
+	}
+	// 时长优先取服务返回的 X-Audio-Duration-Ms，否则尝试从 WAV 头部计算
+	duration, _ := WAVDuration(data)
+	if ms, err := strconv.Atoi(resp.Header.Get("X-Audio-Duration-Ms")); err == nil && ms > 0 {
+		duration = time.Duration(ms) * time.Millisecond
+	}
+	return &Audio{Data: data, ContentType: contentType, Duration: duration}, nil
+}
@@ -0,0 +1,37 @@
+package tts
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// WAVDuration 从 RIFF/WAVE 头部计算音频时长，不是 WAV 或头部不完整时返回 false
+func WAVDuration(data []byte) (time.Duration, bool) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return 0, false
+	}
+	var byteRate uint32
+	for pos := 12; pos+8 <= len(data); {
+		id := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		body := pos + 8
+		switch id {
+		case "fmt ":
+			if body+12 > len(data) {
+				return 0, false
+			}
+			byteRate = binary.LittleEndian.Uint32(data[body+8 : body+12])
+		case "data":
+			if byteRate == 0 {
+				return 0, false
+			}
+			// 流式输出的 WAV 可能把 data 大小写成 0 或 0xFFFFFFFF，此时以实际长度为准
+			if size <= 0 || body+size > len(data) {
+				size = len(data) - body
+			}
+			return time.Duration(int64(size) * int64(time.Second) / int64(byteRate)), true
+		}
+		pos = body + size + size%2 // 块按偶数字节对齐
+	}
+	return 0, false
+}
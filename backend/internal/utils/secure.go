@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"math/big"
+)
+
+// GenerateSecureToken 使用 crypto/rand 生成 nBytes 字节的随机数，并编码为 URL 安全的字符串
+// 用于分享链接、邀请码等需要不可猜测的场景；GenerateRandomString 不适合这类用途。
+func GenerateSecureToken(nBytes int) (string, error) {
+	b := make([]byte, nBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// GenerateSecureCode 使用 crypto/rand 生成指定长度的字母数字串，用于需要手动输入的短码（如圈子加入码）
+func GenerateSecureCode(length int) (string, error) {
+	b := make([]byte, length)
+	n := big.NewInt(int64(len(charset)))
+	for i := range b {
+		idx, err := rand.Int(rand.Reader, n)
+		if err != nil {
+			return "", err
+		}
+		b[i] = charset[idx.Int64()]
+	}
+	return string(b), nil
+}
@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"os"
+	"time"
+)
+
+// DefaultTimezone 是未设置 APP_TZ 时使用的业务时区
+const DefaultTimezone = "Asia/Shanghai"
+
+// AppLocation 返回 APP_TZ 指定的业务时区，用于按天、周、月统计和定时任务
+func AppLocation() (string, *time.Location) {
+	return LoadLocation(os.Getenv("APP_TZ"))
+}
+
+// LoadLocation 加载时区 tz，为空时使用 DefaultTimezone。
+// 名称无效或系统缺少时区数据时退回东八区，返回的名称同时用于 SQL 的 AT TIME ZONE，因此也退回 DefaultTimezone
+func LoadLocation(tz string) (string, *time.Location) {
+	if tz == "" {
+		tz = DefaultTimezone
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return DefaultTimezone, time.FixedZone("CST", 8*3600)
+	}
+	return tz, loc
+}
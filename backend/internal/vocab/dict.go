@@ -0,0 +1,242 @@
+// Package vocab 从节点内容中提取生词：英文还原为词元（原形），中文按词典分词，
+// 并在离线词典中查询释义。
+//
+// 词典采用 CC-CEDICT（中文）与 ECDICT（英文）的文件格式。程序内置的只是几十个词条的样例，
+// 仅供开发和测试；正式部署必须通过 VOCAB_CEDICT_PATH、VOCAB_ECDICT_PATH 加载完整的词典文件，
+// 否则绝大多数词查不到释义，中文也无法正确分词。词典来源与许可见 data/README.md。
+package vocab
+
+import (
+	"bufio"
+	"embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+//go:embed data/cedict.u8 data/ecdict.csv
+var embedded embed.FS
+
+// 词条所属的语言，即 lang.Base 的结果
+const (
+	English = "en"
+	Chinese = "zh"
+)
+
+// Entry 是词典中的一个词条
+type Entry struct {
+	Word        string   `json:"word"`
+	Traditional string   `json:"traditional,omitempty"` // 仅中文词条
+	Reading     string   `json:"reading,omitempty"`     // 中文为拼音，英文为音标
+	Definitions []string `json:"definitions"`
+}
+
+// Dictionary 是加载到内存中的离线词典
+type Dictionary struct {
+	english    map[string]*Entry
+	lemmas     map[string]string // 屈折形式 -> 原形，来自 ECDICT 的 exchange 字段
+	chinese    map[string]*Entry // 简体与繁体都作为键
+	maxWordLen int               // 最长中文词条的字数，用于分词
+}
+
+// maxSegmentWordLen 限制分词时尝试的最大词长，成语和俗语一般不超过这个长度
+const maxSegmentWordLen = 8
+
+var (
+	defaultDict *Dictionary
+	defaultErr  error
+	defaultOnce sync.Once
+)
+
+// Default 返回全局词典：优先加载环境变量指定的完整词典，未配置或无法打开时退回内置的样例词典。
+// 加载失败时返回错误，由调用方决定如何降级，不会终止进程
+func Default() (*Dictionary, error) {
+	defaultOnce.Do(func() {
+		defaultDict, defaultErr = loadDefault()
+		if defaultErr == nil {
+			log.Printf("Dictionaries loaded: %d English entries, %d Chinese entries.", len(defaultDict.english), len(defaultDict.chinese))
+		}
+	})
+	return defaultDict, defaultErr
+}
+
+func loadDefault() (*Dictionary, error) {
+	cedict, err := openDictFile("VOCAB_CEDICT_PATH", "data/cedict.u8")
+	if err != nil {
+		return nil, fmt.Errorf("open CEDICT dictionary: %w", err)
+	}
+	defer cedict.Close()
+	ecdict, err := openDictFile("VOCAB_ECDICT_PATH", "data/ecdict.csv")
+	if err != nil {
+		return nil, fmt.Errorf("open ECDICT dictionary: %w", err)
+	}
+	defer ecdict.Close()
+	return Load(cedict, ecdict)
+}
+
+func openDictFile(env, embeddedPath string) (io.ReadCloser, error) {
+	if path := os.Getenv(env); path != "" {
+		f, err := os.Open(path)
+		if err == nil {
+			return f, nil
+		}
+		log.Printf("WARN: Cannot open %s=%s, falling back to the built-in sample dictionary: %v", env, path, err)
+	} else {
+		log.Printf("WARN: %s is not set, using the built-in sample dictionary; most words will not be found", env)
+	}
+	return embedded.Open(embeddedPath)
+}
+
+// Load 从 CC-CEDICT 与 ECDICT 格式的数据中构建词典，任一参数可以为 nil
+func Load(cedict, ecdict io.Reader) (*Dictionary, error) {
+	d := &Dictionary{
+		english: make(map[string]*Entry),
+		lemmas:  make(map[string]string),
+		chinese: make(map[string]*Entry),
+	}
+	if cedict != nil {
+		if err := d.loadCEDICT(cedict); err != nil {
+			return nil, fmt.Errorf("cedict: %w", err)
+		}
+	}
+	if ecdict != nil {
+		if err := d.loadECDICT(ecdict); err != nil {
+			return nil, fmt.Errorf("ecdict: %w", err)
+		}
+	}
+	return d, nil
+}
+
+// loadCEDICT 解析形如 “繁體 简体 [pin1 yin1] /释义1/释义2/” 的行，# 开头的行为注释。
+// 同一个词有多个读音时合并为一个词条。
+func (d *Dictionary) loadCEDICT(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lb, rb := strings.Index(line, "["), strings.Index(line, "]")
+		if lb < 0 || rb < lb {
+			continue
+		}
+		heads := strings.Fields(line[:lb])
+		if len(heads) < 2 {
+			continue
+		}
+		traditional, simplified := heads[0], heads[1]
+		reading := line[lb+1 : rb]
+		var defs []string
+		for _, def := range strings.Split(line[rb+1:], "/") {
+			if def = strings.TrimSpace(def); def != "" {
+				defs = append(defs, def)
+			}
+		}
+
+		entry, ok := d.chinese[simplified]
+		if !ok {
+			entry = &Entry{Word: simplified, Reading: reading}
+			if traditional != simplified {
+				entry.Traditional = traditional
+			}
+			d.chinese[simplified] = entry
+			d.chinese[traditional] = entry
+		} else if !strings.Contains(entry.Reading, reading) {
+			entry.Reading += "; " + reading
+		}
+		entry.Definitions = append(entry.Definitions, defs...)
+		if n := len([]rune(simplified)); n > d.maxWordLen {
+			d.maxWordLen = min(n, maxSegmentWordLen)
+		}
+	}
+	return scanner.Err()
+}
+
+// loadECDICT 解析 ECDICT 的 CSV 文件，按表头定位 word、phonetic、translation、definition、exchange 列。
+// 中文释义优先，没有时使用英文释义；exchange 中的屈折形式用于词元还原。
+func (d *Dictionary) loadECDICT(r io.Reader) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	header, err := reader.Read()
+	if err != nil {
+		return err
+	}
+	col := make(map[string]int, len(header))
+	for i, name := range header {
+		col[strings.TrimSpace(name)] = i
+	}
+	if _, ok := col["word"]; !ok {
+		return errors.New("missing word column")
+	}
+	field := func(record []string, name string) string {
+		if i, ok := col[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		word := strings.ToLower(field(record, "word"))
+		if word == "" {
+			continue
+		}
+		text := field(record, "translation")
+		if text == "" {
+			text = field(record, "definition")
+		}
+		var defs []string
+		for _, def := range strings.Split(strings.ReplaceAll(text, `\n`, "\n"), "\n") {
+			if def = strings.TrimSpace(def); def != "" {
+				defs = append(defs, def)
+			}
+		}
+		d.english[word] = &Entry{Word: word, Reading: field(record, "phonetic"), Definitions: defs}
+
+		// exchange 形如 “p:went/d:gone/i:going/3:goes/0:go”，其中 0 为原形，其余为屈折形式
+		for _, part := range strings.Split(field(record, "exchange"), "/") {
+			kind, form, ok := strings.Cut(part, ":")
+			if !ok || form == "" {
+				continue
+			}
+			form = strings.ToLower(form)
+			switch kind {
+			case "0":
+				if form != word {
+					d.lemmas[word] = form
+				}
+			case "p", "d", "i", "3", "r", "t", "s":
+				if _, exists := d.lemmas[form]; !exists && form != word {
+					d.lemmas[form] = word
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Lookup 按语言查询词条；英文先还原为原形
+func (d *Dictionary) Lookup(language, word string) (*Entry, bool) {
+	switch language {
+	case English:
+		e, ok := d.english[d.Lemma(word)]
+		return e, ok
+	case Chinese:
+		e, ok := d.chinese[word]
+		return e, ok
+	}
+	return nil, false
+}
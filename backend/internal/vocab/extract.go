@@ -0,0 +1,151 @@
+package vocab
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/shuind/language-learner/backend/internal/lang"
+)
+
+// ErrUnsupportedLanguage 表示内容的语言暂不支持提取生词
+var ErrUnsupportedLanguage = errors.New("vocabulary extraction supports English and Chinese content only")
+
+// Candidate 是从内容中提取出的一个生词
+type Candidate struct {
+	Word     string   `json:"word"`     // 英文为原形，中文为词语
+	Language string   `json:"language"` // en 或 zh
+	Forms    []string `json:"forms"`    // 在文中出现过的形式
+	Count    int      `json:"count"`
+	Offset   int      `json:"offset"` // 首次出现的位置，按 rune 计
+	Entry    *Entry   `json:"entry,omitempty"`
+}
+
+// englishStopwords 是不作为生词提取的常见功能词
+var englishStopwords = toSet(strings.Fields(`
+	a an the and or but if of to in on at by for with from as into about than then so
+	is am are was were be been being do does did have has had will would shall should can could may might must
+	i me my you your he him his she her it its we us our they them their this that these those which who whom what
+	when where why how not no nor all any some one very too also just there here up out over
+`))
+
+// chineseStopwords 是不作为生词提取的常见虚词
+var chineseStopwords = toSet(strings.Split("的了是在和也就都而之乎者也矣焉哉不有我你他她它这那其以于与", ""))
+
+func toSet(words []string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	return set
+}
+
+// Extract 从文本中提取生词，按首次出现的顺序返回。
+// 未指定语言时英文与中文都会提取；英文内容只提取英文单词，中文（含文言文、粤语）内容只提取中文词语。
+func (d *Dictionary) Extract(language, text string) ([]Candidate, error) {
+	var english, chinese bool
+	switch lang.Base(language) {
+	case "":
+		english, chinese = true, true
+	case English:
+		english = true
+	case Chinese, "lzh", "yue", "wuu":
+		chinese = true
+	default:
+		return nil, ErrUnsupportedLanguage
+	}
+
+	byWord := make(map[string]*Candidate)
+	var order []*Candidate
+	add := func(word, language, form string, offset int, entry *Entry) {
+		key := language + ":" + word
+		c, ok := byWord[key]
+		if !ok {
+			c = &Candidate{Word: word, Language: language, Offset: offset, Entry: entry}
+			byWord[key] = c
+			order = append(order, c)
+		}
+		c.Count++
+		for _, f := range c.Forms {
+			if f == form {
+				return
+			}
+		}
+		c.Forms = append(c.Forms, form)
+	}
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.Is(unicode.Han, r):
+			j := i
+			for j < len(runes) && unicode.Is(unicode.Han, runes[j]) {
+				j++
+			}
+			if chinese {
+				offset := i
+				for _, word := range d.Segment(runes[i:j]) {
+					entry := d.chinese[word]
+					if entry != nil && !chineseStopwords[word] {
+						add(entry.Word, Chinese, word, offset, entry)
+					}
+					offset += len([]rune(word))
+				}
+			}
+			i = j
+		case unicode.In(r, unicode.Latin):
+			j := i
+			for j < len(runes) && inEnglishWord(runes, j) {
+				j++
+			}
+			if english {
+				form := strings.Trim(strings.ReplaceAll(string(runes[i:j]), "’", "'"), "'-")
+				lower := strings.ToLower(form)
+				if len(lower) >= 2 && !englishStopwords[lower] && !strings.Contains(lower, "'") {
+					lemma := d.Lemma(lower)
+					add(lemma, English, lower, i, d.english[lemma])
+				}
+			}
+			i = j
+		default:
+			i++
+		}
+	}
+
+	result := make([]Candidate, len(order))
+	for i, c := range order {
+		result[i] = *c
+	}
+	sort.SliceStable(result, func(a, b int) bool { return result[a].Offset < result[b].Offset })
+	return result, nil
+}
+
+// Segment 用正向最大匹配法把一段连续的汉字切分为词语，词典中没有的字单独成词
+func (d *Dictionary) Segment(run []rune) []string {
+	words := make([]string, 0, len(run))
+	for i := 0; i < len(run); {
+		n := 1
+		for l := min(d.maxWordLen, len(run)-i); l >= 2; l-- {
+			if _, ok := d.chinese[string(run[i:i+l])]; ok {
+				n = l
+				break
+			}
+		}
+		words = append(words, string(run[i:i+n]))
+		i += n
+	}
+	return words
+}
+
+// inEnglishWord 判断 runes[i] 是否仍属于当前英文单词：字母、撇号，以及后面紧跟字母的连字符
+func inEnglishWord(runes []rune, i int) bool {
+	switch r := runes[i]; {
+	case unicode.In(r, unicode.Latin), r == '\'', r == '’':
+		return true
+	case r == '-':
+		return i+1 < len(runes) && unicode.In(runes[i+1], unicode.Latin)
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package vocab
+
+import "strings"
+
+// suffixRules 是词典中找不到屈折形式时使用的后缀还原规则，按顺序尝试
+var suffixRules = []struct {
+	suffix, replace string
+}{
+	{"iest", "y"}, {"ier", "y"}, {"ies", "y"}, {"ied", "y"},
+	{"ves", "f"}, {"ves", "fe"},
+	{"es", ""}, {"s", ""},
+	{"ed", ""}, {"ed", "e"},
+	{"ing", ""}, {"ing", "e"},
+	{"est", ""}, {"est", "e"}, {"er", ""}, {"er", "e"},
+}
+
+// Lemma 把英文单词还原为原形：先查 ECDICT 的屈折形式表，再按后缀规则尝试，
+// 得到的词必须在词典中存在；都不成立时返回小写的原词
+func (d *Dictionary) Lemma(word string) string {
+	w := strings.ToLower(word)
+	if lemma, ok := d.lemmas[w]; ok {
+		return lemma
+	}
+	if _, ok := d.english[w]; ok {
+		return w
+	}
+	for _, rule := range suffixRules {
+		stem, ok := strings.CutSuffix(w, rule.suffix)
+		if !ok || len(stem) < 2 {
+			continue
+		}
+		if _, ok := d.english[stem+rule.replace]; ok {
+			return stem + rule.replace
+		}
+		// hopped -> hop、bigger -> big：去掉重复的末尾辅音
+		if n := len(stem); rule.replace == "" && n >= 3 && stem[n-1] == stem[n-2] {
+			if _, ok := d.english[stem[:n-1]]; ok {
+				return stem[:n-1]
+			}
+		}
+	}
+	return w
+}